@@ -7,6 +7,7 @@ import (
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	"github.com/syberalexis/linky-exporter/pkg/config"
 	"github.com/syberalexis/linky-exporter/pkg/core"
 	"github.com/syberalexis/linky-exporter/pkg/prom"
 	"gopkg.in/alecthomas/kingpin.v2"
@@ -21,19 +22,34 @@ var (
 	defaultFrameSize = 7
 	defaultParity    = "ParityNone"
 	defaultStopBits  = "Stop1"
+	defaultSchema    = "v1"
 
 	app        = kingpin.New(filepath.Base(os.Args[0]), "")
 	appVersion = app.Version(version)
 	help       = app.HelpFlag.Short('h')
 	debug      = app.Flag("debug", "Enable debug mode.").Bool()
+	configFile = app.Flag("config", "Configuration file, replacing all other flags, reloaded on SIGHUP or POST /-/reload").Short('c').String()
 
-	address = app.Flag("address", "Listen address").Default(fmt.Sprintf("%s", defaultAddress)).Short('a').String()
-	port    = app.Flag("port", "Listen port").Default(fmt.Sprintf("%d", defaultPort)).Short('p').Int()
+	address      = app.Flag("address", "Listen address").Default(fmt.Sprintf("%s", defaultAddress)).Short('a').String()
+	port         = app.Flag("port", "Listen port").Default(fmt.Sprintf("%d", defaultPort)).Short('p').Int()
+	webConfig    = app.Flag("web.config.file", "Web configuration file enabling TLS and basic auth, in exporter toolkit format").String()
+	bearerToken  = app.Flag("web.bearer-token-file", "File holding the bearer token required to query the exporter").String()
+	frameTimeout = app.Flag("frame-timeout", "Delay without frame after which a meter is down and the exporter not ready").Default(config.DefaultFrameTimeout.String()).Duration()
+	storePath    = app.Flag("store.path", "Embedded database file recording history, disabled when not set").String()
+	retention    = app.Flag("store.retention", "Age over which history records are deleted").Default(config.DefaultStoreRetention.String()).Duration()
+	powerWindow  = app.Flag("power-window", "Duration over which active power derived from energy indexes is averaged").Default(core.DefaultPowerWindow.String()).Duration()
+	tariffFile   = app.Flag("tariff-file", "Dated tariffs file to compute energy and subscription costs").String()
+	schema       = app.Flag("metrics-schema", "Metrics schema, v1 for original metrics or v2 for Prometheus naming conventions").Default(defaultSchema).Enum("v1", "v2")
+
+	labels     = app.Flag("label", "Constant label added to all metrics, as name=value").PlaceHolder("NAME=VALUE").StringMap()
+	aliases    = app.Flag("meter-alias", "Alias replacing the linky_id label of a meter, as id=alias").PlaceHolder("ID=ALIAS").StringMap()
+	hashSalt   = app.Flag("linky-id-hash-salt", "Publish a salted hash of linky_id and prm instead of the meter identifiers").String()
+	dropLabels = app.Flag("drop-label", "Label removed from a metric family, as family=label or *=label for all families").PlaceHolder("FAMILY=LABEL").Strings()
 
 	auto       = app.Flag("auto", "Automatique mode").Bool()
 	historical = app.Flag("historical", "Historical mode").Bool()
 	standard   = app.Flag("standard", "Standard mode").Bool()
-	device     = app.Flag("device", "Device to read").Required().Short('d').String()
+	device     = app.Flag("device", "Device to read, required without configuration file").Short('d').String()
 
 	baudrate = app.Flag("baud", "Baud rate").Short('b').Int()
 	size     = app.Flag("size", "Serial frame size").Int()
@@ -65,57 +81,88 @@ func run() {
 		log.Info("Debug mode enabled !")
 	}
 
-	// Checks before running
-	_, error := os.Stat(*device)
-	if error != nil {
-		log.Fatal(error)
+	// Parse parameters
+	var linkyConfig *config.Config
+	var err error
+	if configFile != nil && *configFile != "" {
+		linkyConfig, err = config.Load(*configFile)
+	} else {
+		linkyConfig, err = flagsConfig()
+	}
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	// Parse parameters
-	connector := core.LinkyConnector{Device: *device}
-	detect := auto != nil && *auto
-	if !detect {
-		if standard != nil && *standard {
-			connector.Mode = core.Standard
-			connector.BaudRate = core.Standard.BaudRate
-			connector.FrameSize = core.Standard.FrameSize
-			connector.Parity = core.Standard.Parity
-			connector.StopBits = core.Standard.StopBits
-		} else if historical != nil && *historical {
-			connector.Mode = core.Historical
-			connector.BaudRate = core.Historical.BaudRate
-			connector.FrameSize = core.Historical.FrameSize
-			connector.Parity = core.Historical.Parity
-			connector.StopBits = core.Historical.StopBits
-		} else {
-			detect = true
-		}
-		if baudrate != nil && *baudrate != 0 {
-			connector.BaudRate = *baudrate
-		}
-		if size != nil && *size != 0 {
-			connector.FrameSize = *size
+	// Checks before running, meters are read independently so only fail when none is available
+	available := 0
+	for _, device := range linkyConfig.Devices {
+		if connector, _ := device.Connector(); connector.IsNetwork() {
+			available++
+			continue
 		}
-		if parity != nil && *parity != "" {
-			log.Debug("Parse parity ", *parity)
-			connector.Parity = core.ParseParity(*parity)
-		}
-		if stopBits != nil && *stopBits != "" {
-			log.Debug("Parse Stop Bits ", *stopBits)
-			connector.StopBits = core.ParseStopBits(*stopBits)
+		_, error := os.Stat(device.Device)
+		if error != nil {
+			log.Warn(error)
+		} else {
+			available++
 		}
 	}
+	if len(linkyConfig.Devices) > 0 && available == 0 {
+		log.Fatal("No configured device is available")
+	}
 
-	// Auto detection mode
-	if detect {
-		err := connector.Detect()
-		log.Debug("device:", connector.Device, " mode:", connector.Mode, " baudrate:", connector.BaudRate, " framesize:", connector.FrameSize, " parity:", connector.Parity, " stopbits:", connector.StopBits)
-		if err != nil {
-			log.Fatal(err)
+	// Run exporter
+	exporter := prom.NewLinkyExporter(linkyConfig, *configFile)
+	if err := exporter.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Build configuration from command line flags
+func flagsConfig() (*config.Config, error) {
+	if device == nil || *device == "" {
+		return nil, fmt.Errorf("Required flag --device or --config not provided")
+	}
+
+	dropLabelsConfig, err := prom.ParseDropLabels(*dropLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceConfig := config.DeviceConfig{Name: config.DefaultDeviceName, Device: *device, Mode: "auto"}
+	if auto == nil || !*auto {
+		if standard != nil && *standard {
+			deviceConfig.Mode = "standard"
+		} else if historical != nil && *historical {
+			deviceConfig.Mode = "historical"
 		}
+		deviceConfig.BaudRate = *baudrate
+		deviceConfig.FrameSize = *size
+		deviceConfig.Parity = *parity
+		deviceConfig.StopBits = *stopBits
 	}
 
-	// Run exporter
-	exporter := prom.LinkyExporter{Address: *address, Port: *port}
-	exporter.Run(connector)
+	flagsConfig := &config.Config{
+		Web: config.WebConfig{
+			ListenAddress:   fmt.Sprintf("%s:%d", *address, *port),
+			ConfigFile:      *webConfig,
+			BearerTokenFile: *bearerToken,
+		},
+		Metrics: config.MetricsConfig{
+			Schema:       *schema,
+			Labels:       *labels,
+			MeterAliases: *aliases,
+			IdHashSalt:   *hashSalt,
+			DropLabels:   dropLabelsConfig,
+		},
+		Devices:      []config.DeviceConfig{deviceConfig},
+		FrameTimeout: *frameTimeout,
+		TariffFile:   *tariffFile,
+		PowerWindow:  *powerWindow,
+	}
+	if *storePath != "" {
+		flagsConfig.Store = &config.StoreConfig{Path: *storePath, Retention: *retention}
+	}
+	flagsConfig.SetDefaults()
+	return flagsConfig, flagsConfig.Validate()
 }
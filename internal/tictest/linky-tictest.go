@@ -0,0 +1,49 @@
+// Package tictest builds TIC frames with valid checksums for tests
+package tictest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Raw encodes data sets in a frame, from STX to ETX, with valid checksums. Data sets are written "LABEL VALUE" in
+// historical mode, "LABEL\tVALUE" or "LABEL\tHORODATE\tVALUE" in standard mode
+func Raw(mode core.LinkyMode, dataSets ...string) []byte {
+	content := []byte{0x02}
+	for _, dataSet := range dataSets {
+		// The checksum covers the separator before it in standard mode only
+		separator := " "
+		if mode == core.Standard {
+			dataSet, separator = dataSet+"\t", ""
+		}
+		content = append(content, '\n')
+		content = append(content, dataSet+separator...)
+		content = append(content, checksum(dataSet), '\r')
+	}
+	return append(content, 0x03)
+}
+
+// Frame decodes a frame of the data sets, received at the given time
+func Frame(t testing.TB, mode core.LinkyMode, at time.Time, dataSets ...string) core.Frame {
+	t.Helper()
+	frame, err := core.ParseFrame(mode, Raw(mode, dataSets...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := frame.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	frame.Time = at
+	return frame
+}
+
+// Compute the checksum of the data of a data set
+func checksum(data string) byte {
+	var sum byte
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+	return sum&0x3F + 0x20
+}
@@ -0,0 +1,169 @@
+package alert
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+const (
+	eventQueueSize = 16
+	Firing         = "firing"
+	Resolved       = "resolved"
+)
+
+// Event object sent to hooks when the headroom alert of a meter fires or resolves
+type Event struct {
+	Meter     string    `json:"meter"`
+	State     string    `json:"state"` // firing or resolved
+	Headroom  float64   `json:"headroom_va"`
+	Threshold float64   `json:"threshold_va"`
+	Overrun   bool      `json:"overrun"`
+	Since     time.Time `json:"since"` // Start of the low headroom
+	Time      time.Time `json:"time"`
+}
+
+// State of the alert of a meter
+type meterState struct {
+	since  time.Time // Start of the low headroom, zero when above the threshold
+	firing bool
+}
+
+// Watcher object to fire hooks when the headroom of a meter stays below the threshold
+type Watcher struct {
+	config   config.HeadroomConfig
+	hooks    []Hook
+	mutex    sync.Mutex
+	meters   map[string]*meterState
+	failures map[string]uint64 // Failed notifications by hook name
+	events   chan Event
+	done     chan struct{}
+	closed   bool
+}
+
+// NewWatcher method to construct Watcher, hooks are notified in background
+func NewWatcher(headroomConfig config.HeadroomConfig, hooks []Hook) *Watcher {
+	watcher := &Watcher{
+		config:   headroomConfig,
+		hooks:    hooks,
+		meters:   make(map[string]*meterState),
+		failures: make(map[string]uint64),
+		events:   make(chan Event, eventQueueSize),
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer close(watcher.done)
+		for event := range watcher.events {
+			watcher.notify(event)
+		}
+	}()
+	return watcher
+}
+
+// Configured returns whether the watcher was built with a configuration
+func (watcher *Watcher) Configured(headroomConfig config.HeadroomConfig) bool {
+	return reflect.DeepEqual(watcher.config, headroomConfig)
+}
+
+// Add a frame of a meter, firing the alert once the headroom stayed low for the duration and resolving it once above
+func (watcher *Watcher) Add(meter string, frame core.Frame) {
+	headroom, ok := core.HeadroomOf(frame)
+	if !ok {
+		return
+	}
+
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+	state, exists := watcher.meters[meter]
+	if !exists {
+		state = &meterState{}
+		watcher.meters[meter] = state
+	}
+
+	event := Event{Meter: meter, Headroom: headroom.Headroom, Threshold: watcher.config.Threshold, Overrun: headroom.Overrun, Time: frame.Time}
+	if headroom.Headroom < watcher.config.Threshold || headroom.Overrun {
+		if state.since.IsZero() {
+			state.since = frame.Time
+		}
+		if !state.firing && frame.Time.Sub(state.since) >= watcher.config.Duration {
+			state.firing = true
+			event.State, event.Since = Firing, state.since
+			log.Warnf("Headroom of %s below %.0f VA since %s", meter, watcher.config.Threshold, state.since.Format(time.RFC3339))
+			watcher.push(event)
+		}
+		return
+	}
+
+	if state.firing {
+		event.State, event.Since = Resolved, state.since
+		log.Infof("Headroom of %s back above %.0f VA", meter, watcher.config.Threshold)
+		watcher.push(event)
+	}
+	state.since, state.firing = time.Time{}, false
+}
+
+// Queue an event for hooks, it is dropped when hooks are late, the mutex must be held
+func (watcher *Watcher) push(event Event) {
+	if watcher.closed {
+		return
+	}
+	select {
+	case watcher.events <- event:
+	default:
+		log.Warnf("Alert hooks are late, %s event of %s dropped", event.State, event.Meter)
+	}
+}
+
+// Notify all hooks of an event
+func (watcher *Watcher) notify(event Event) {
+	for _, hook := range watcher.hooks {
+		if err := hook.Notify(event); err != nil {
+			log.Errorf("Failed to notify %s of %s alert : %s", hook.Name(), event.Meter, err)
+			watcher.mutex.Lock()
+			watcher.failures[hook.Name()]++
+			watcher.mutex.Unlock()
+		}
+	}
+}
+
+// Firing returns whether the alert of a meter is firing
+func (watcher *Watcher) Firing(meter string) bool {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+	state, exists := watcher.meters[meter]
+	return exists && state.firing
+}
+
+// Failures returns the number of failed notifications by hook name
+func (watcher *Watcher) Failures() map[string]uint64 {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+	failures := make(map[string]uint64)
+	for _, hook := range watcher.hooks {
+		failures[hook.Name()] = watcher.failures[hook.Name()]
+	}
+	return failures
+}
+
+// Close waits for queued events to be notified and releases hooks
+func (watcher *Watcher) Close() error {
+	watcher.mutex.Lock()
+	if !watcher.closed {
+		watcher.closed = true
+		close(watcher.events)
+	}
+	watcher.mutex.Unlock()
+	<-watcher.done
+
+	for _, hook := range watcher.hooks {
+		if err := hook.Close(); err != nil {
+			log.Errorf("Failed to close %s hook : %s", hook.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/internal/tictest"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Build a standard frame with a subscribed power of 6 kVA
+func headroomFrame(t *testing.T, at time.Time, sinsts int) core.Frame {
+	return tictest.Frame(t, core.Standard, at, "PREF\t06", fmt.Sprintf("SINSTS\t%05d", sinsts))
+}
+
+func TestWatcherTableDriven(t *testing.T) {
+	// Given
+	start := time.Date(2023, 6, 14, 18, 0, 0, 0, time.UTC)
+	var tests = []struct {
+		name     string
+		powers   []int
+		expected []string
+	}{
+		{"above threshold", []int{4000, 5000, 5400}, nil},
+		{"shorter than duration", []int{5800, 5800, 4000}, nil},
+		{"fired and resolved", []int{5800, 5800, 5900, 4000}, []string{Firing, Resolved}},
+		{"fired on overrun", []int{6100, 6200, 6300}, []string{Firing}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			var states []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var event Event
+				if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+					t.Error(err)
+				}
+				mutex.Lock()
+				states = append(states, event.State)
+				mutex.Unlock()
+			}))
+			defer server.Close()
+
+			headroomConfig := config.HeadroomConfig{
+				Threshold: 500,
+				Duration:  10 * time.Second,
+				Hooks:     config.HooksConfig{Webhook: &config.WebhookConfig{Url: server.URL, Timeout: time.Second}},
+			}
+			watcher := NewWatcher(headroomConfig, NewHooks(headroomConfig.Hooks))
+
+			// When
+			for i, power := range tt.powers {
+				watcher.Add("default", headroomFrame(t, start.Add(time.Duration(i)*5*time.Second), power))
+			}
+			watcher.Close()
+
+			// Then
+			mutex.Lock()
+			defer mutex.Unlock()
+			if !reflect.DeepEqual(states, tt.expected) {
+				t.Errorf("got %v, want %v", states, tt.expected)
+			}
+		})
+	}
+}
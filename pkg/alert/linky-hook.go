@@ -0,0 +1,172 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/syberalexis/linky-exporter/pkg/broker"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+)
+
+// Hook interface of destinations notified when an alert fires and resolves
+type Hook interface {
+	// Name of the hook, used in logs and metrics
+	Name() string
+	// Notify sends an event, blocking until it is delivered or failed
+	Notify(event Event) error
+	// Close releases the hook
+	Close() error
+}
+
+// NewHooks method to construct the enabled hooks
+func NewHooks(hooksConfig config.HooksConfig) []Hook {
+	var hooks []Hook
+	if hooksConfig.Webhook != nil {
+		hooks = append(hooks, NewWebhook(*hooksConfig.Webhook))
+	}
+	if hooksConfig.Mqtt != nil {
+		hooks = append(hooks, NewMqttHook(*hooksConfig.Mqtt))
+	}
+	if hooksConfig.Command != nil {
+		hooks = append(hooks, NewCommandHook(*hooksConfig.Command))
+	}
+	return hooks
+}
+
+// Webhook object to POST events as JSON
+type Webhook struct {
+	config config.WebhookConfig
+	client *http.Client
+}
+
+// NewWebhook method to construct Webhook
+func NewWebhook(webhookConfig config.WebhookConfig) *Webhook {
+	return &Webhook{config: webhookConfig, client: &http.Client{Timeout: webhookConfig.Timeout}}
+}
+
+// Name implements Hook
+func (hook *Webhook) Name() string {
+	return "webhook"
+}
+
+// Notify implements Hook
+func (hook *Webhook) Notify(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, hook.config.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range hook.config.Headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := hook.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("Webhook %s answered %s", hook.config.Url, response.Status)
+	}
+	return nil
+}
+
+// Close implements Hook
+func (hook *Webhook) Close() error {
+	hook.client.CloseIdleConnections()
+	return nil
+}
+
+// MqttHook object to publish events as JSON messages, the connection is retried in background
+type MqttHook struct {
+	config config.MqttHookConfig
+	client mqtt.Client
+}
+
+// NewMqttHook method to construct MqttHook
+func NewMqttHook(mqttConfig config.MqttHookConfig) *MqttHook {
+	return &MqttHook{config: mqttConfig, client: broker.NewClient(mqttConfig.MqttConnection, nil)}
+}
+
+// Name implements Hook
+func (hook *MqttHook) Name() string {
+	return "mqtt"
+}
+
+// Notify implements Hook
+func (hook *MqttHook) Notify(event Event) error {
+	if !hook.client.IsConnected() {
+		return fmt.Errorf("MQTT broker %s not connected", hook.config.Broker)
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	token := hook.client.Publish(hook.config.Topic, hook.config.Qos, hook.config.Retain, payload)
+	if !token.WaitTimeout(10 * time.Second) {
+		return fmt.Errorf("Timeout publishing to %s", hook.config.Topic)
+	}
+	return token.Error()
+}
+
+// Close implements Hook
+func (hook *MqttHook) Close() error {
+	broker.Disconnect(hook.client)
+	return nil
+}
+
+// CommandHook object to run a command on events, as a script switching a relay.
+// The event is given as JSON on the standard input and as LINKY_* environment variables.
+type CommandHook struct {
+	config config.CommandConfig
+}
+
+// NewCommandHook method to construct CommandHook
+func NewCommandHook(commandConfig config.CommandConfig) *CommandHook {
+	return &CommandHook{config: commandConfig}
+}
+
+// Name implements Hook
+func (hook *CommandHook) Name() string {
+	return "command"
+}
+
+// Notify implements Hook
+func (hook *CommandHook) Notify(event Event) error {
+	input, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hook.config.Timeout)
+	defer cancel()
+
+	command := exec.CommandContext(ctx, hook.config.Path, hook.config.Args...)
+	command.Stdin = bytes.NewReader(input)
+	command.Env = append(os.Environ(),
+		"LINKY_METER="+event.Meter,
+		"LINKY_STATE="+event.State,
+		fmt.Sprintf("LINKY_HEADROOM_VA=%.0f", event.Headroom),
+		fmt.Sprintf("LINKY_OVERRUN=%t", event.Overrun),
+	)
+	if output, err := command.CombinedOutput(); err != nil {
+		return fmt.Errorf("Command %s failed : %s %s", hook.config.Path, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Close implements Hook
+func (hook *CommandHook) Close() error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package broker
+
+import (
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	log "github.com/sirupsen/logrus"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+)
+
+// Time left to pending work when disconnecting, in milliseconds
+const disconnectQuiesce = 250
+
+// NewClient method to construct a MQTT client connecting in background and reconnecting after failures,
+// configure adds its own options, as a will or a connection handler, when not nil
+func NewClient(connection config.MqttConnection, configure func(options *mqtt.ClientOptions)) mqtt.Client {
+	options := mqtt.NewClientOptions().
+		AddBroker(connection.Broker).
+		SetClientID(connection.ClientId).
+		SetUsername(connection.Username).
+		SetPassword(connection.Password).
+		SetConnectRetry(true).
+		SetAutoReconnect(true).
+		SetConnectionLostHandler(func(client mqtt.Client, err error) {
+			log.Warnf("MQTT connection to %s lost : %s", connection.Broker, err)
+		})
+	if configure != nil {
+		configure(options)
+	}
+
+	client := mqtt.NewClient(options)
+	client.Connect()
+	return client
+}
+
+// Disconnect a client, leaving pending work a short time to complete
+func Disconnect(client mqtt.Client) {
+	client.Disconnect(disconnectQuiesce)
+}
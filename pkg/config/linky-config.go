@@ -0,0 +1,856 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/exporter-toolkit/web"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultListenAddress = "0.0.0.0:9901"
+	DefaultSchema        = "v1"
+	DefaultDeviceName    = "default"
+	DefaultFrameTimeout  = time.Minute
+
+	DefaultInfluxDBVersion        = 2
+	DefaultInfluxDBMeasurement    = "linky"
+	DefaultInfluxDBBatchSize      = 100
+	DefaultInfluxDBFlushInterval  = 10 * time.Second
+	DefaultInfluxDBTimeout        = 10 * time.Second
+	DefaultInfluxDBBufferMaxBytes = 100 * 1024 * 1024
+
+	DefaultRemoteWriteBatchSize     = 10
+	DefaultRemoteWriteFlushInterval = 15 * time.Second
+	DefaultRemoteWriteTimeout       = 30 * time.Second
+	DefaultRemoteWriteQueueMaxBytes = 100 * 1024 * 1024
+
+	DefaultStoreRetention = 90 * 24 * time.Hour
+	DefaultStoreInterval  = time.Minute
+
+	DefaultHeadroomDuration = 10 * time.Second
+	DefaultHookTimeout      = 10 * time.Second
+
+	DefaultEventsSize = 500
+
+	DefaultMqttClientId        = "linky-exporter"
+	DefaultMqttTopicPrefix     = "linky"
+	DefaultMqttDiscoveryPrefix = "homeassistant"
+
+	// Label holding the device name, added to metrics when several devices are configured
+	MeterLabel = "meter"
+)
+
+// Config object describing the whole exporter
+type Config struct {
+	Web          WebConfig       `yaml:"web"`
+	Metrics      MetricsConfig   `yaml:"metrics"`
+	Devices      []DeviceConfig  `yaml:"devices"`
+	ProbeOnly    bool            `yaml:"probe_only"`    // Only serve /probe, without configured devices
+	FrameTimeout time.Duration   `yaml:"frame_timeout"` // Delay without frame after which a meter is down and the exporter not ready
+	Outputs      OutputsConfig   `yaml:"outputs"`
+	Store        *StoreConfig    `yaml:"store"`        // Embedded history store, disabled when not set
+	TariffFile   string          `yaml:"tariff_file"`  // Dated tariffs to compute costs, disabled when not set
+	Periods      *PeriodsConfig  `yaml:"periods"`      // Calendar period totals, disabled when not set
+	PowerWindow  time.Duration   `yaml:"power_window"` // Duration over which active power derived from energy indexes is averaged
+	TanPhi       TanPhiConfig    `yaml:"tan_phi"`
+	Events       EventsConfig    `yaml:"events"`
+	Headroom     *HeadroomConfig `yaml:"headroom"`      // Alert on low headroom before the subscribed power, disabled when not set
+	LoadShedding *SheddingConfig `yaml:"load_shedding"` // Rules switching loads from frames, disabled when not set
+	Solar        *SolarConfig    `yaml:"solar"`         // Self-consumption of a photovoltaic production, disabled when not set
+}
+
+// SolarConfig object describing the grid meter and the photovoltaic production source
+type SolarConfig struct {
+	GridMeter  string           `yaml:"grid_meter"` // Device name of the meter between the installation and the grid, the only device when not set
+	Production ProductionConfig `yaml:"production"`
+}
+
+// ProductionConfig object describing the source of the photovoltaic production, exactly one must be set
+type ProductionConfig struct {
+	Meter string               `yaml:"meter"` // Device name of a production meter, its exported energy is the production
+	Http  bool                 `yaml:"http"`  // Accept production posted to /api/v1/solar/production
+	Mqtt  *MqttProductionInput `yaml:"mqtt"`
+}
+
+// MqttProductionInput object describing a MQTT topic receiving the production
+type MqttProductionInput struct {
+	MqttConnection `yaml:",inline"`
+	Topic          string `yaml:"topic"` // Messages as {"energy_wh": 1234.5} or {"power_w": 850}, or a number of Wh
+}
+
+// SheddingConfig object describing loads switched by rules evaluated on each frame
+type SheddingConfig struct {
+	Actuators []ActuatorConfig `yaml:"actuators"`
+	Rules     []RuleConfig     `yaml:"rules"` // Evaluated in order, the first active rule of an actuator sets its state
+}
+
+// ActuatorConfig object describing a switched load, exactly one of gpio, mqtt and http must be set
+type ActuatorConfig struct {
+	Name    string        `yaml:"name"`
+	Default string        `yaml:"default"` // on or off, state when no rule is active
+	MinOn   time.Duration `yaml:"min_on"`  // Minimum time on before switching off
+	MinOff  time.Duration `yaml:"min_off"` // Minimum time off before switching on
+	Gpio    *GpioConfig   `yaml:"gpio"`
+	Mqtt    *MqttSwitch   `yaml:"mqtt"`
+	Http    *HttpSwitch   `yaml:"http"`
+}
+
+// GpioConfig object describing a GPIO output line, either from a character device or a sysfs value file
+type GpioConfig struct {
+	Chip      string `yaml:"chip"`       // Character device, as /dev/gpiochip0, Linux only
+	Line      int    `yaml:"line"`       // Line offset on the chip
+	Sysfs     string `yaml:"sysfs"`      // Value file of an exported output, as /sys/class/gpio/gpio17/value
+	ActiveLow bool   `yaml:"active_low"` // Whether on drives the line low
+}
+
+// MqttSwitch object describing a load switched by MQTT messages
+type MqttSwitch struct {
+	MqttConnection `yaml:",inline"`
+	Topic          string `yaml:"topic"`
+	PayloadOn      string `yaml:"payload_on"`
+	PayloadOff     string `yaml:"payload_off"`
+	Retain         bool   `yaml:"retain"`
+}
+
+// HttpSwitch object describing a load switched by HTTP requests
+type HttpSwitch struct {
+	Method  string            `yaml:"method"`
+	OnUrl   string            `yaml:"on_url"`
+	OffUrl  string            `yaml:"off_url"`
+	Headers map[string]string `yaml:"headers"`
+	Timeout time.Duration     `yaml:"timeout"`
+}
+
+// RuleConfig object describing the state of an actuator while all conditions hold
+type RuleConfig struct {
+	Name       string            `yaml:"name"`
+	Meter      string            `yaml:"meter"` // Device name whose frames are evaluated, required with several devices
+	Actuator   string            `yaml:"actuator"`
+	State      string            `yaml:"state"` // on or off
+	Conditions []ConditionConfig `yaml:"conditions"`
+}
+
+// ConditionConfig object describing a test on a frame, on a data set as received or a sample
+type ConditionConfig struct {
+	Label      string   `yaml:"label"`      // Data set label, as PTEC or ADPS
+	Sample     string   `yaml:"sample"`     // Sample key, as apparent_power_exported or status_tempo_color
+	Present    *bool    `yaml:"present"`    // Whether the data set or sample is in the frame
+	Equals     *string  `yaml:"equals"`     // Value of the data set, or of the sample as a number
+	Above      *float64 `yaml:"above"`      // Sample strictly above
+	Below      *float64 `yaml:"below"`      // Sample strictly below
+	Hysteresis float64  `yaml:"hysteresis"` // Margin the sample must cross back before the condition stops holding
+}
+
+// HeadroomConfig object describing the alert fired when the headroom stays below a threshold
+type HeadroomConfig struct {
+	Threshold float64       `yaml:"threshold"` // Headroom in VA below which the alert is pending, 0 to only alert on overruns
+	Duration  time.Duration `yaml:"duration"`  // Delay the headroom must stay below the threshold before the alert fires
+	Hooks     HooksConfig   `yaml:"hooks"`
+}
+
+// HooksConfig object describing the hooks called when an alert fires and resolves, each one is disabled when not set
+type HooksConfig struct {
+	Webhook *WebhookConfig  `yaml:"webhook"`
+	Mqtt    *MqttHookConfig `yaml:"mqtt"`
+	Command *CommandConfig  `yaml:"command"`
+}
+
+// WebhookConfig object describing a webhook receiving alerts as JSON POST requests
+type WebhookConfig struct {
+	Url     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Timeout time.Duration     `yaml:"timeout"`
+}
+
+// MqttHookConfig object describing a MQTT topic receiving alerts as JSON messages
+type MqttHookConfig struct {
+	MqttConnection `yaml:",inline"`
+	Topic          string `yaml:"topic"`
+	Retain         bool   `yaml:"retain"`
+}
+
+// CommandConfig object describing a command run on alerts, as a script switching a relay
+type CommandConfig struct {
+	Path    string        `yaml:"path"`
+	Args    []string      `yaml:"args"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// TanPhiConfig object describing tan φ derived from reactive energy indexes
+type TanPhiConfig struct {
+	Windows   []time.Duration `yaml:"windows"`   // Durations over which tan φ is computed
+	Threshold float64         `yaml:"threshold"` // Value above which a warning is logged
+}
+
+// EventsConfig object describing the log of meter state transitions
+type EventsConfig struct {
+	Size    int  `yaml:"size"`    // Number of events kept for /api/v1/events
+	Publish bool `yaml:"publish"` // Whether events are published to outputs
+}
+
+// PeriodsConfig object describing calendar period totals
+type PeriodsConfig struct {
+	StateFile string         `yaml:"state_file"` // File keeping totals across restarts, in memory when not set
+	DayOffset *time.Duration `yaml:"day_offset"` // Start of the energy day, 06:00 for Tempo and EJP contracts and midnight otherwise when not set
+}
+
+// StoreConfig object describing the embedded history store
+type StoreConfig struct {
+	Path      string        `yaml:"path"`      // Database file, created when missing
+	Retention time.Duration `yaml:"retention"` // Age over which records are deleted
+	Interval  time.Duration `yaml:"interval"`  // Minimum delay between two records of a meter
+}
+
+// OutputsConfig object describing the destinations of decoded frames, each one is disabled when not set
+type OutputsConfig struct {
+	Mqtt        *MqttConfig        `yaml:"mqtt"`
+	InfluxDB    *InfluxDBConfig    `yaml:"influxdb"`
+	RemoteWrite *RemoteWriteConfig `yaml:"remote_write"`
+}
+
+// RemoteWriteConfig object describing the Prometheus remote write output
+type RemoteWriteConfig struct {
+	Url            string        `yaml:"url"`
+	Username       string        `yaml:"username"`
+	Password       string        `yaml:"password"`
+	BearerToken    string        `yaml:"bearer_token"`
+	BatchSize      int           `yaml:"batch_size"`      // Number of frames sent in one request
+	FlushInterval  time.Duration `yaml:"flush_interval"`  // Maximum delay before sending a frame
+	Timeout        time.Duration `yaml:"timeout"`         // Timeout of one request
+	QueueDirectory string        `yaml:"queue_directory"` // Directory keeping unsent requests, in memory when not set
+	QueueMaxBytes  int64         `yaml:"queue_max_bytes"` // Size over which oldest unsent requests are dropped
+}
+
+// InfluxDBConfig object describing the InfluxDB output
+type InfluxDBConfig struct {
+	Url             string        `yaml:"url"`
+	Version         int           `yaml:"version"` // 1 or 2, for the write API version
+	Database        string        `yaml:"database"`
+	RetentionPolicy string        `yaml:"retention_policy"`
+	Username        string        `yaml:"username"`
+	Password        string        `yaml:"password"`
+	Token           string        `yaml:"token"`
+	Org             string        `yaml:"org"`
+	Bucket          string        `yaml:"bucket"`
+	Measurement     string        `yaml:"measurement"`
+	BatchSize       int           `yaml:"batch_size"`       // Number of frames sent in one request
+	FlushInterval   time.Duration `yaml:"flush_interval"`   // Maximum delay before sending a frame
+	Timeout         time.Duration `yaml:"timeout"`          // Timeout of one request
+	BufferDirectory string        `yaml:"buffer_directory"` // Directory keeping unsent batches, in memory when not set
+	BufferMaxBytes  int64         `yaml:"buffer_max_bytes"` // Size over which oldest unsent batches are dropped
+}
+
+// MqttConnection object describing a MQTT broker connection, shared by the output, hooks, actuators and inputs
+type MqttConnection struct {
+	Broker   string `yaml:"broker"` // As tcp://host:1883, ssl://host:8883 or ws://host:80
+	ClientId string `yaml:"client_id"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Qos      byte   `yaml:"qos"` // QoS of published messages and subscriptions
+}
+
+// MqttConfig object describing the MQTT output
+type MqttConfig struct {
+	MqttConnection  `yaml:",inline"`
+	TopicPrefix     string `yaml:"topic_prefix"`
+	Retain          bool   `yaml:"retain"`
+	Discovery       bool   `yaml:"discovery"` // Publish Home Assistant discovery configurations
+	DiscoveryPrefix string `yaml:"discovery_prefix"`
+}
+
+// WebConfig object describing the HTTP server
+type WebConfig struct {
+	ListenAddress   string `yaml:"listen_address"`
+	ConfigFile      string `yaml:"config_file"`       // Exporter toolkit web configuration file, for TLS and basic auth
+	BearerTokenFile string `yaml:"bearer_token_file"` // File holding the token required in Authorization header
+}
+
+// MetricsConfig object describing the exposed metrics
+type MetricsConfig struct {
+	Schema       string              `yaml:"schema"`
+	Labels       map[string]string   `yaml:"labels"`
+	MeterAliases map[string]string   `yaml:"meter_aliases"`
+	IdHashSalt   string              `yaml:"linky_id_hash_salt"`
+	DropLabels   map[string][]string `yaml:"drop_labels"`
+}
+
+// MeterId returns the published value of a meter identifier, its alias or its salted hash when configured
+func (metrics MetricsConfig) MeterId(id string) string {
+	if alias, exists := metrics.MeterAliases[id]; exists {
+		return alias
+	}
+	if metrics.IdHashSalt != "" {
+		hash := sha256.Sum256([]byte(metrics.IdHashSalt + id))
+		return hex.EncodeToString(hash[:])[:16]
+	}
+	return id
+}
+
+// DeviceConfig object describing a TIC serial device
+type DeviceConfig struct {
+	Name      string `yaml:"name"`
+	Device    string `yaml:"device"`
+	Mode      string `yaml:"mode"` // auto, standard or historical
+	BaudRate  int    `yaml:"baud_rate"`
+	FrameSize int    `yaml:"frame_size"`
+	Parity    string `yaml:"parity"`
+	StopBits  string `yaml:"stop_bits"`
+}
+
+// Load configuration file, with defaults applied and validated
+func Load(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("Unable to parse configuration file %s : %s", path, err)
+	}
+
+	config.SetDefaults()
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("Invalid configuration file %s : %s", path, err)
+	}
+	return config, nil
+}
+
+// SetDefaults fills unset values with their defaults
+func (config *Config) SetDefaults() {
+	if config.Web.ListenAddress == "" {
+		config.Web.ListenAddress = DefaultListenAddress
+	}
+	if config.Metrics.Schema == "" {
+		config.Metrics.Schema = DefaultSchema
+	}
+	if config.FrameTimeout == 0 {
+		config.FrameTimeout = DefaultFrameTimeout
+	}
+	if config.PowerWindow == 0 {
+		config.PowerWindow = core.DefaultPowerWindow
+	}
+	if len(config.TanPhi.Windows) == 0 {
+		config.TanPhi.Windows = core.DefaultTanPhiWindows
+	}
+	if config.TanPhi.Threshold == 0 {
+		config.TanPhi.Threshold = core.DefaultTanPhiThreshold
+	}
+	if config.Events.Size == 0 {
+		config.Events.Size = DefaultEventsSize
+	}
+	if mqtt := config.Outputs.Mqtt; mqtt != nil {
+		if mqtt.ClientId == "" {
+			mqtt.ClientId = DefaultMqttClientId
+		}
+		if mqtt.TopicPrefix == "" {
+			mqtt.TopicPrefix = DefaultMqttTopicPrefix
+		}
+		if mqtt.DiscoveryPrefix == "" {
+			mqtt.DiscoveryPrefix = DefaultMqttDiscoveryPrefix
+		}
+	}
+	if influxDB := config.Outputs.InfluxDB; influxDB != nil {
+		if influxDB.Version == 0 {
+			influxDB.Version = DefaultInfluxDBVersion
+		}
+		if influxDB.Measurement == "" {
+			influxDB.Measurement = DefaultInfluxDBMeasurement
+		}
+		if influxDB.BatchSize == 0 {
+			influxDB.BatchSize = DefaultInfluxDBBatchSize
+		}
+		if influxDB.FlushInterval == 0 {
+			influxDB.FlushInterval = DefaultInfluxDBFlushInterval
+		}
+		if influxDB.Timeout == 0 {
+			influxDB.Timeout = DefaultInfluxDBTimeout
+		}
+		if influxDB.BufferMaxBytes == 0 {
+			influxDB.BufferMaxBytes = DefaultInfluxDBBufferMaxBytes
+		}
+	}
+	if remoteWrite := config.Outputs.RemoteWrite; remoteWrite != nil {
+		if remoteWrite.BatchSize == 0 {
+			remoteWrite.BatchSize = DefaultRemoteWriteBatchSize
+		}
+		if remoteWrite.FlushInterval == 0 {
+			remoteWrite.FlushInterval = DefaultRemoteWriteFlushInterval
+		}
+		if remoteWrite.Timeout == 0 {
+			remoteWrite.Timeout = DefaultRemoteWriteTimeout
+		}
+		if remoteWrite.QueueMaxBytes == 0 {
+			remoteWrite.QueueMaxBytes = DefaultRemoteWriteQueueMaxBytes
+		}
+	}
+	if headroom := config.Headroom; headroom != nil {
+		if headroom.Duration == 0 {
+			headroom.Duration = DefaultHeadroomDuration
+		}
+		if webhook := headroom.Hooks.Webhook; webhook != nil && webhook.Timeout == 0 {
+			webhook.Timeout = DefaultHookTimeout
+		}
+		if mqtt := headroom.Hooks.Mqtt; mqtt != nil && mqtt.ClientId == "" {
+			mqtt.ClientId = DefaultMqttClientId + "-alert"
+		}
+		if command := headroom.Hooks.Command; command != nil && command.Timeout == 0 {
+			command.Timeout = DefaultHookTimeout
+		}
+	}
+	if shedding := config.LoadShedding; shedding != nil {
+		for i := range shedding.Actuators {
+			actuator := &shedding.Actuators[i]
+			if actuator.Default == "" {
+				actuator.Default = "on"
+			}
+			if mqtt := actuator.Mqtt; mqtt != nil {
+				if mqtt.ClientId == "" {
+					mqtt.ClientId = DefaultMqttClientId + "-" + actuator.Name
+				}
+				if mqtt.PayloadOn == "" {
+					mqtt.PayloadOn = "ON"
+				}
+				if mqtt.PayloadOff == "" {
+					mqtt.PayloadOff = "OFF"
+				}
+			}
+			if http := actuator.Http; http != nil {
+				if http.Method == "" {
+					http.Method = "POST"
+				}
+				if http.Timeout == 0 {
+					http.Timeout = DefaultHookTimeout
+				}
+			}
+		}
+		for i := range shedding.Rules {
+			if shedding.Rules[i].State == "" {
+				shedding.Rules[i].State = "off"
+			}
+		}
+	}
+	// Devices are named first, as the only device is the default grid meter
+	for i := range config.Devices {
+		if config.Devices[i].Name == "" {
+			config.Devices[i].Name = DefaultDeviceName
+		}
+		if config.Devices[i].Mode == "" {
+			config.Devices[i].Mode = "auto"
+		}
+	}
+	if solar := config.Solar; solar != nil {
+		if solar.GridMeter == "" && len(config.Devices) == 1 {
+			solar.GridMeter = config.Devices[0].Name
+		}
+		if mqtt := solar.Production.Mqtt; mqtt != nil && mqtt.ClientId == "" {
+			mqtt.ClientId = DefaultMqttClientId + "-solar"
+		}
+	}
+	if store := config.Store; store != nil {
+		if store.Retention == 0 {
+			store.Retention = DefaultStoreRetention
+		}
+		if store.Interval == 0 {
+			store.Interval = DefaultStoreInterval
+		}
+	}
+}
+
+// Number of basic auth users of an exporter toolkit web configuration file
+func basicAuthUsers(path string) (int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var webConfig struct {
+		Users map[string]string `yaml:"basic_auth_users"`
+	}
+	if err := yaml.Unmarshal(content, &webConfig); err != nil {
+		return 0, err
+	}
+	return len(webConfig.Users), nil
+}
+
+// Validate the whole configuration
+func (config *Config) Validate() error {
+	if config.FrameTimeout < 0 {
+		return fmt.Errorf("Frame timeout must be positive : %s", config.FrameTimeout)
+	}
+	if config.PowerWindow < 0 {
+		return fmt.Errorf("Power window must be positive : %s", config.PowerWindow)
+	}
+	for _, window := range config.TanPhi.Windows {
+		if window <= 0 {
+			return fmt.Errorf("Tan φ window must be positive : %s", window)
+		}
+	}
+	if config.TanPhi.Threshold < 0 {
+		return fmt.Errorf("Tan φ threshold must be positive : %v", config.TanPhi.Threshold)
+	}
+	if config.Events.Size < 0 {
+		return fmt.Errorf("Events size must be positive : %d", config.Events.Size)
+	}
+	if err := web.Validate(config.Web.ConfigFile); err != nil {
+		return fmt.Errorf("Invalid web configuration file %s : %s", config.Web.ConfigFile, err)
+	}
+	if config.Web.BearerTokenFile != "" && config.Web.ConfigFile != "" {
+		// Both read the Authorization header, so that no request could satisfy both
+		users, err := basicAuthUsers(config.Web.ConfigFile)
+		if err != nil {
+			return fmt.Errorf("Invalid web configuration file %s : %s", config.Web.ConfigFile, err)
+		}
+		if users > 0 {
+			return fmt.Errorf("Bearer token file and basic auth users of %s can not be used together", config.Web.ConfigFile)
+		}
+	}
+	if config.Metrics.Schema != "v1" && config.Metrics.Schema != "v2" {
+		return fmt.Errorf("Unknown metrics schema : %s", config.Metrics.Schema)
+	}
+	if len(config.Devices) == 0 && !config.ProbeOnly {
+		return fmt.Errorf("At least one device must be configured")
+	}
+	if len(config.Devices) > 0 && config.ProbeOnly {
+		return fmt.Errorf("Devices can not be configured with probe_only")
+	}
+	if mqtt := config.Outputs.Mqtt; mqtt != nil {
+		if err := mqtt.MqttConnection.Validate(); err != nil {
+			return err
+		}
+		for _, device := range config.Devices {
+			if strings.ContainsAny(device.Name, "/+#") {
+				return fmt.Errorf("Device name %s can not be used in MQTT topics", device.Name)
+			}
+		}
+	}
+	if influxDB := config.Outputs.InfluxDB; influxDB != nil {
+		if err := influxDB.Validate(); err != nil {
+			return fmt.Errorf("InfluxDB output : %s", err)
+		}
+	}
+	if remoteWrite := config.Outputs.RemoteWrite; remoteWrite != nil {
+		if remoteWrite.Url == "" {
+			return fmt.Errorf("Remote write URL is required")
+		}
+		if remoteWrite.BatchSize < 0 || remoteWrite.FlushInterval < 0 || remoteWrite.Timeout < 0 || remoteWrite.QueueMaxBytes < 0 {
+			return fmt.Errorf("Remote write batch size, flush interval, timeout and queue size must be positive")
+		}
+	}
+	if store := config.Store; store != nil {
+		if store.Path == "" {
+			return fmt.Errorf("Store path is required")
+		}
+		if store.Retention < 0 || store.Interval < 0 {
+			return fmt.Errorf("Store retention and interval must be positive")
+		}
+	}
+	if headroom := config.Headroom; headroom != nil {
+		if err := headroom.Validate(); err != nil {
+			return fmt.Errorf("Headroom : %s", err)
+		}
+	}
+	if shedding := config.LoadShedding; shedding != nil {
+		if err := shedding.Validate(config.Devices); err != nil {
+			return fmt.Errorf("Load shedding : %s", err)
+		}
+	}
+	if solar := config.Solar; solar != nil {
+		if err := solar.Validate(config.Devices); err != nil {
+			return fmt.Errorf("Solar : %s", err)
+		}
+	}
+	if periods := config.Periods; periods != nil && periods.DayOffset != nil && (*periods.DayOffset < 0 || *periods.DayOffset >= 24*time.Hour) {
+		return fmt.Errorf("Day offset must be between 0 and 24h : %s", *periods.DayOffset)
+	}
+	if _, exists := config.Metrics.Labels[MeterLabel]; exists {
+		return fmt.Errorf("Label %s is reserved", MeterLabel)
+	}
+
+	names := make(map[string]bool)
+	paths := make(map[string]bool)
+	for _, device := range config.Devices {
+		if names[device.Name] {
+			return fmt.Errorf("Device name %s is used more than once", device.Name)
+		}
+		if paths[device.Device] {
+			return fmt.Errorf("Device %s is used more than once", device.Device)
+		}
+		names[device.Name] = true
+		paths[device.Device] = true
+
+		if _, err := device.Connector(); err != nil {
+			return fmt.Errorf("Device %s : %s", device.Name, err)
+		}
+	}
+	return nil
+}
+
+// Validate the InfluxDB output configuration
+func (influxDB InfluxDBConfig) Validate() error {
+	if influxDB.Url == "" {
+		return fmt.Errorf("URL is required")
+	}
+	switch influxDB.Version {
+	case 1:
+		if influxDB.Database == "" {
+			return fmt.Errorf("Database is required with version 1")
+		}
+	case 2:
+		if influxDB.Org == "" || influxDB.Bucket == "" {
+			return fmt.Errorf("Org and bucket are required with version 2")
+		}
+	default:
+		return fmt.Errorf("Unknown version : %d", influxDB.Version)
+	}
+	if influxDB.BatchSize < 0 || influxDB.FlushInterval < 0 || influxDB.Timeout < 0 || influxDB.BufferMaxBytes < 0 {
+		return fmt.Errorf("Batch size, flush interval, timeout and buffer size must be positive")
+	}
+	return nil
+}
+
+// Validate the broker and QoS of a MQTT connection
+func (connection MqttConnection) Validate() error {
+	if connection.Broker == "" {
+		return fmt.Errorf("MQTT broker is required")
+	}
+	if connection.Qos > 2 {
+		return fmt.Errorf("Unknown MQTT QoS : %d", connection.Qos)
+	}
+	return nil
+}
+
+// ValidateTopic validates a MQTT connection and the topic used on it
+func (connection MqttConnection) ValidateTopic(topic string) error {
+	if topic == "" {
+		return fmt.Errorf("MQTT topic is required")
+	}
+	return connection.Validate()
+}
+
+// Validate the headroom alert and its hooks
+func (headroom HeadroomConfig) Validate() error {
+	if headroom.Threshold < 0 || headroom.Duration < 0 {
+		return fmt.Errorf("Threshold and duration must be positive")
+	}
+	hooks := headroom.Hooks
+	if hooks.Webhook == nil && hooks.Mqtt == nil && hooks.Command == nil {
+		return fmt.Errorf("At least one hook is required")
+	}
+	if webhook := hooks.Webhook; webhook != nil && (webhook.Url == "" || webhook.Timeout < 0) {
+		return fmt.Errorf("Webhook URL is required and timeout must be positive")
+	}
+	if mqtt := hooks.Mqtt; mqtt != nil {
+		if err := mqtt.MqttConnection.ValidateTopic(mqtt.Topic); err != nil {
+			return err
+		}
+	}
+	if command := hooks.Command; command != nil && (command.Path == "" || command.Timeout < 0) {
+		return fmt.Errorf("Command path is required and timeout must be positive")
+	}
+	return nil
+}
+
+// Validate actuators and rules, rules must refer to actuators
+func (shedding SheddingConfig) Validate(devices []DeviceConfig) error {
+	names := make(map[string]bool)
+	for _, device := range devices {
+		names[device.Name] = true
+	}
+	actuators := make(map[string]bool)
+	for _, actuator := range shedding.Actuators {
+		if actuator.Name == "" {
+			return fmt.Errorf("Actuator name is required")
+		}
+		if actuators[actuator.Name] {
+			return fmt.Errorf("Actuator name %s is used more than once", actuator.Name)
+		}
+		actuators[actuator.Name] = true
+		if err := actuator.Validate(); err != nil {
+			return fmt.Errorf("Actuator %s : %s", actuator.Name, err)
+		}
+	}
+
+	for i, rule := range shedding.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("%d", i+1)
+		}
+		if !actuators[rule.Actuator] {
+			return fmt.Errorf("Rule %s : Unknown actuator %s", name, rule.Actuator)
+		}
+		// Conditions of a rule evaluated on the frames of several meters would hold in turn
+		if rule.Meter == "" && len(devices) > 1 {
+			return fmt.Errorf("Rule %s : Meter is required with several devices", name)
+		}
+		if rule.Meter != "" && !names[rule.Meter] {
+			return fmt.Errorf("Rule %s : Unknown meter %s", name, rule.Meter)
+		}
+		if rule.State != "on" && rule.State != "off" {
+			return fmt.Errorf("Rule %s : State must be on or off : %s", name, rule.State)
+		}
+		if len(rule.Conditions) == 0 {
+			return fmt.Errorf("Rule %s : At least one condition is required", name)
+		}
+		for _, condition := range rule.Conditions {
+			if err := condition.Validate(); err != nil {
+				return fmt.Errorf("Rule %s : %s", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate an actuator, exactly one kind must be set
+func (actuator ActuatorConfig) Validate() error {
+	if actuator.Default != "on" && actuator.Default != "off" {
+		return fmt.Errorf("Default must be on or off : %s", actuator.Default)
+	}
+	if actuator.MinOn < 0 || actuator.MinOff < 0 {
+		return fmt.Errorf("Minimum on and off times must be positive")
+	}
+
+	kinds := 0
+	if gpio := actuator.Gpio; gpio != nil {
+		kinds++
+		if (gpio.Chip == "") == (gpio.Sysfs == "") {
+			return fmt.Errorf("GPIO requires either a chip or a sysfs value file")
+		}
+		if gpio.Line < 0 {
+			return fmt.Errorf("GPIO line must be positive : %d", gpio.Line)
+		}
+	}
+	if mqtt := actuator.Mqtt; mqtt != nil {
+		kinds++
+		if err := mqtt.MqttConnection.ValidateTopic(mqtt.Topic); err != nil {
+			return err
+		}
+	}
+	if http := actuator.Http; http != nil {
+		kinds++
+		if http.OnUrl == "" || http.OffUrl == "" {
+			return fmt.Errorf("HTTP on and off URLs are required")
+		}
+		if http.Timeout < 0 {
+			return fmt.Errorf("HTTP timeout must be positive")
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("Exactly one of gpio, mqtt and http is required")
+	}
+	return nil
+}
+
+// Validate a condition, it tests either a label or a sample
+func (condition ConditionConfig) Validate() error {
+	if (condition.Label == "") == (condition.Sample == "") {
+		return fmt.Errorf("Condition requires either a label or a sample")
+	}
+	tests := 0
+	for _, set := range []bool{condition.Present != nil, condition.Equals != nil, condition.Above != nil, condition.Below != nil} {
+		if set {
+			tests++
+		}
+	}
+	if tests != 1 {
+		return fmt.Errorf("Condition on %s%s requires exactly one of present, equals, above and below", condition.Label, condition.Sample)
+	}
+	if condition.Label != "" && (condition.Above != nil || condition.Below != nil) {
+		return fmt.Errorf("Condition on label %s can not compare numbers, use a sample", condition.Label)
+	}
+	if condition.Sample != "" && condition.Equals != nil {
+		if _, err := strconv.ParseFloat(*condition.Equals, 64); err != nil {
+			return fmt.Errorf("Condition on sample %s must equal a number : %s", condition.Sample, *condition.Equals)
+		}
+	}
+	if condition.Hysteresis < 0 {
+		return fmt.Errorf("Hysteresis must be positive : %v", condition.Hysteresis)
+	}
+	return nil
+}
+
+// Validate the grid meter and the production source, meters must be configured devices
+func (solar SolarConfig) Validate(devices []DeviceConfig) error {
+	names := make(map[string]bool)
+	for _, device := range devices {
+		names[device.Name] = true
+	}
+	if !names[solar.GridMeter] {
+		return fmt.Errorf("Unknown grid meter %s", solar.GridMeter)
+	}
+
+	production := solar.Production
+	sources := 0
+	if production.Meter != "" {
+		sources++
+		if !names[production.Meter] || production.Meter == solar.GridMeter {
+			return fmt.Errorf("Production meter %s must be another configured device", production.Meter)
+		}
+	}
+	if production.Http {
+		sources++
+	}
+	if mqtt := production.Mqtt; mqtt != nil {
+		sources++
+		if err := mqtt.MqttConnection.ValidateTopic(mqtt.Topic); err != nil {
+			return err
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("Exactly one production source of meter, http and mqtt is required")
+	}
+	return nil
+}
+
+// Connector builds the connector of the device, its mode is left unset for auto detection
+func (device DeviceConfig) Connector() (core.LinkyConnector, error) {
+	connector := core.LinkyConnector{Device: device.Device}
+	if device.Device == "" {
+		return connector, fmt.Errorf("Device path is required")
+	}
+
+	switch device.Mode {
+	case "auto":
+		return connector, nil
+	case "standard":
+		connector.Mode = core.Standard
+	case "historical":
+		connector.Mode = core.Historical
+	default:
+		return connector, fmt.Errorf("Unknown mode : %s", device.Mode)
+	}
+
+	connector.BaudRate = connector.Mode.BaudRate
+	connector.FrameSize = connector.Mode.FrameSize
+	connector.Parity = connector.Mode.Parity
+	connector.StopBits = connector.Mode.StopBits
+	if device.BaudRate != 0 {
+		connector.BaudRate = device.BaudRate
+	}
+	if device.FrameSize != 0 {
+		connector.FrameSize = device.FrameSize
+	}
+
+	var err error
+	if device.Parity != "" {
+		if connector.Parity, err = core.ParseParity(device.Parity); err != nil {
+			return connector, err
+		}
+	}
+	if device.StopBits != "" {
+		if connector.StopBits, err = core.ParseStopBits(device.StopBits); err != nil {
+			return connector, err
+		}
+	}
+	return connector, nil
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "linky.yml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	// Given
+	path := writeConfig(t, "devices:\n  - device: /dev/serial0\n    mode: standard\n    baud_rate: 1200\n")
+
+	// When
+	config, err := Load(path)
+
+	// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Web.ListenAddress != DefaultListenAddress || config.Metrics.Schema != DefaultSchema || config.Devices[0].Name != DefaultDeviceName {
+		t.Errorf("defaults not applied : %+v", config)
+	}
+	connector, err := config.Devices[0].Connector()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if connector.Mode != core.Standard || connector.BaudRate != 1200 || connector.FrameSize != core.Standard.FrameSize {
+		t.Errorf("unexpected connector : %+v", connector)
+	}
+}
+
+func TestLoadProbeOnly(t *testing.T) {
+	// Given
+	path := writeConfig(t, "probe_only: true\n")
+
+	// When
+	config, err := Load(path)
+
+	// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.ProbeOnly || len(config.Devices) != 0 {
+		t.Errorf("unexpected config : %+v", config)
+	}
+}
+
+func TestLoadMqttConnections(t *testing.T) {
+	// Given
+	path := writeConfig(t, "devices:\n  - device: /dev/serial0\noutputs:\n  mqtt:\n    broker: tcp://mqtt:1883\n    qos: 1\nsolar:\n  production:\n    mqtt:\n      broker: tcp://mqtt:1883\n      username: pv\n      topic: solar/energy\n")
+
+	// When
+	config, err := Load(path)
+
+	// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output := config.Outputs.Mqtt; output.Broker != "tcp://mqtt:1883" || output.Qos != 1 || output.ClientId != DefaultMqttClientId {
+		t.Errorf("unexpected output connection : %+v", output.MqttConnection)
+	}
+	if config.Solar.GridMeter != DefaultDeviceName {
+		t.Errorf("got grid meter %s, want %s", config.Solar.GridMeter, DefaultDeviceName)
+	}
+	if input := config.Solar.Production.Mqtt; input.Username != "pv" || input.Topic != "solar/energy" || input.ClientId != DefaultMqttClientId+"-solar" {
+		t.Errorf("unexpected input : %+v", input)
+	}
+}
+
+func TestLoadInvalidTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name    string
+		content string
+	}{
+		{"unknown field", "devices:\n  - device: /dev/serial0\n    speed: 1200\n"},
+		{"unknown mode", "devices:\n  - device: /dev/serial0\n    mode: fast\n"},
+		{"unknown schema", "metrics:\n  schema: v3\ndevices:\n  - device: /dev/serial0\n"},
+		{"unknown parity", "devices:\n  - device: /dev/serial0\n    mode: historical\n    parity: X\n"},
+		{"missing web config", "web:\n  config_file: /nonexistent/web.yml\ndevices:\n  - device: /dev/serial0\n"},
+		{"influxdb without bucket", "outputs:\n  influxdb:\n    url: http://influxdb:8086\n    org: home\n"},
+		{"mqtt without broker", "outputs:\n  mqtt:\n    qos: 1\n"},
+		{"mqtt input without topic", "devices:\n  - device: /dev/serial0\nsolar:\n  production:\n    mqtt:\n      broker: tcp://mqtt:1883\n"},
+		{"remote write without url", "outputs:\n  remote_write:\n    batch_size: 5\n"},
+		{"store without path", "store:\n  retention: 720h\n"},
+		{"headroom without hook", "headroom:\n  threshold: 500\n"},
+		{"shedding rule with unknown actuator", "load_shedding:\n  rules:\n    - actuator: heater\n      conditions:\n        - label: ADPS\n          present: true\n"},
+		{"shedding rule without meter", "devices:\n  - name: a\n    device: /dev/ttyUSB0\n  - name: b\n    device: /dev/ttyUSB1\nload_shedding:\n  actuators:\n    - name: heater\n      http:\n        on_url: http://relay/on\n        off_url: http://relay/off\n  rules:\n    - actuator: heater\n      conditions:\n        - label: ADPS\n          present: true\n"},
+		{"shedding rule with unknown meter", "devices:\n  - device: /dev/ttyUSB0\nload_shedding:\n  actuators:\n    - name: heater\n      http:\n        on_url: http://relay/on\n        off_url: http://relay/off\n  rules:\n    - actuator: heater\n      meter: other\n      conditions:\n        - label: ADPS\n          present: true\n"},
+		{"solar without production", "devices:\n  - device: /dev/ttyUSB0\nsolar:\n  grid_meter: default\n"},
+		{"negative events size", "events:\n  size: -1\n"},
+		{"no device", "web:\n  listen_address: :9901\n"},
+		{"devices with probe only", "probe_only: true\ndevices:\n  - device: /dev/serial0\n"},
+		{"same name", "devices:\n  - device: /dev/ttyUSB0\n  - device: /dev/ttyUSB1\n"},
+		{"same device", "devices:\n  - name: a\n    device: /dev/ttyUSB0\n  - name: b\n    device: /dev/ttyUSB0\n"},
+		{"reserved label", "metrics:\n  labels:\n    meter: a\ndevices:\n  - name: a\n    device: /dev/ttyUSB0\n  - name: b\n    device: /dev/ttyUSB1\n"},
+		{"reserved label with one device", "metrics:\n  labels:\n    meter: home\ndevices:\n  - device: /dev/ttyUSB0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			_, err := Load(writeConfig(t, tt.content))
+
+			// Then
+			if err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestLoadBearerTokenWithBasicAuthUsers(t *testing.T) {
+	// Given
+	directory := t.TempDir()
+	webConfig := filepath.Join(directory, "web.yml")
+	if err := os.WriteFile(webConfig, []byte("basic_auth_users:\n  prometheus: $2a$04$e1.3QexmUYgZS5CuiSBRP..u6sSVJbmaZvsXey8zGJOyu4v1oZglW\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	token := filepath.Join(directory, "token")
+	if err := os.WriteFile(token, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// When
+	_, err := Load(writeConfig(t, "web:\n  config_file: "+webConfig+"\n  bearer_token_file: "+token+"\ndevices:\n  - device: /dev/serial0\n"))
+
+	// Then
+	if err == nil || !strings.Contains(err.Error(), "can not be used together") {
+		t.Errorf("got %v, want bearer token and basic auth rejected", err)
+	}
+}
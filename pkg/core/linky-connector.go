@@ -2,15 +2,26 @@ package core
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
-	"os"
+	"io"
+	"net"
 	"regexp"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"go.bug.st/serial"
 )
 
+const (
+	networkScheme        = "tcp://"
+	networkDialTimeout   = 5 * time.Second
+	networkDetectTimeout = 10 * time.Second
+	serialDetectTimeout  = 5 * time.Second
+)
+
 type LinkyConnector struct {
 	Mode      LinkyMode
 	Device    string
@@ -20,51 +31,101 @@ type LinkyConnector struct {
 	StopBits  serial.StopBits
 }
 
-// Detect serial connection mode
-func (connector *LinkyConnector) Detect() error {
+// Detect serial connection mode, giving up when the context is done
+func (connector *LinkyConnector) Detect(ctx context.Context) error {
 	log.Info("Trying to auto detect TIC mode...")
 
-	if connector.trySerial(Standard) {
+	if connector.IsNetwork() {
+		return connector.detectNetwork(ctx)
+	}
+
+	if connector.trySerial(ctx, Standard) {
 		log.Info("Standard Mode detected !")
-		connector.Mode = Standard
-		connector.BaudRate = Standard.BaudRate
-		connector.FrameSize = Standard.FrameSize
-		connector.Parity = Standard.Parity
-		connector.StopBits = Standard.StopBits
+		connector.setMode(Standard)
 		return nil
 	} else {
 		log.Debug("It's not standard mode !")
 	}
 
-	if connector.trySerial(Historical) {
+	if connector.trySerial(ctx, Historical) {
 		log.Info("Historical Mode detected !")
-		connector.Mode = Historical
-		connector.BaudRate = Historical.BaudRate
-		connector.FrameSize = Historical.FrameSize
-		connector.Parity = Historical.Parity
-		connector.StopBits = Historical.StopBits
+		connector.setMode(Historical)
 		return nil
 	} else {
 		log.Debug("It's not historical mode !")
 	}
 
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 	return fmt.Errorf("Impossible to auto detect TIC mode !")
 }
 
+// Detect mode of a network stream from data sets separator, the serial settings being handled by the gateway
+func (connector *LinkyConnector) detectNetwork(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, networkDetectTimeout)
+	defer cancel()
+
+	dialer := net.Dialer{Timeout: networkDialTimeout}
+	stream, err := dialer.DialContext(ctx, "tcp", strings.TrimPrefix(connector.Device, networkScheme))
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	deadline, _ := ctx.Deadline()
+	stream.SetReadDeadline(deadline)
+	stop := closeOnDone(ctx, stream)
+	defer stop()
+	raw, _, err := newFrameScanner(stream).next()
+	if err != nil {
+		return fmt.Errorf("Impossible to auto detect TIC mode : %s", err)
+	}
+
+	if bytes.IndexByte(raw, '\t') >= 0 {
+		log.Info("Standard Mode detected !")
+		connector.setMode(Standard)
+	} else {
+		log.Info("Historical Mode detected !")
+		connector.setMode(Historical)
+	}
+	return nil
+}
+
+// Set mode and its serial settings
+func (connector *LinkyConnector) setMode(mode LinkyMode) {
+	connector.Mode = mode
+	connector.BaudRate = mode.BaudRate
+	connector.FrameSize = mode.FrameSize
+	connector.Parity = mode.Parity
+	connector.StopBits = mode.StopBits
+}
+
 // Try serial connection and reading
-func (connector LinkyConnector) trySerial(mode LinkyMode) bool {
+func (connector LinkyConnector) trySerial(ctx context.Context, mode LinkyMode) bool {
+	if ctx.Err() != nil {
+		return false
+	}
 	m := &serial.Mode{BaudRate: mode.BaudRate, DataBits: mode.FrameSize, Parity: mode.Parity, StopBits: mode.StopBits}
 	stream, err := serial.Open(connector.Device, m)
 	if err != nil {
 		return false
 	}
+	defer stream.Close()
+	stop := closeOnDone(ctx, stream)
+	defer stop()
 
-	reader := bufio.NewReader(stream)
+	stream.SetReadTimeout(serialDetectTimeout)
+	reader := bufio.NewReader(timeoutReader{stream})
 	regex, _ := regexp.Compile(`^[A-Z0-9\-+]+ +[a-zA-Z0-9 \.\-]+ +.$`)
 
 	log.Debug("Read serial data...")
 	for i := 1; i <= 5; i++ {
-		bytes, _, _ := reader.ReadLine()
+		bytes, _, err := reader.ReadLine()
+		if err != nil {
+			log.Debug("Read failed : ", err)
+			return false
+		}
 		line := string(bytes)
 		log.Debug("Try line ", i, "/5 : ", line)
 		if regex.MatchString(line) {
@@ -76,44 +137,62 @@ func (connector LinkyConnector) trySerial(mode LinkyMode) bool {
 	return false
 }
 
+// Close the stream to unblock a pending read when the context is done, until stop is called
+func closeOnDone(ctx context.Context, stream io.Closer) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			stream.Close()
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+// Reader returning an error instead of an empty read when the serial read timeout expires
+type timeoutReader struct {
+	port serial.Port
+}
+
+func (reader timeoutReader) Read(p []byte) (int, error) {
+	n, err := reader.port.Read(p)
+	if n == 0 && err == nil {
+		return 0, fmt.Errorf("No data received")
+	}
+	return n, err
+}
+
+// IsNetwork returns whether the device is a TCP gateway address, as tcp://host:port
+func (connector LinkyConnector) IsNetwork() bool {
+	return strings.HasPrefix(connector.Device, networkScheme)
+}
+
+// Open serial or network stream with connector configuration
+func (connector LinkyConnector) open() (io.ReadCloser, error) {
+	if connector.IsNetwork() {
+		log.Debug("Open network stream with config device:", connector.Device)
+		return net.DialTimeout("tcp", strings.TrimPrefix(connector.Device, networkScheme), networkDialTimeout)
+	}
+
+	log.Debug("Open serial with config device:", connector.Device, " baudrate:", connector.BaudRate, " framesize:", connector.FrameSize, " parity:", connector.Parity, " stopbits:", connector.StopBits)
+	m := &serial.Mode{BaudRate: connector.BaudRate, DataBits: connector.FrameSize, Parity: connector.Parity, StopBits: connector.StopBits}
+	return serial.Open(connector.Device, m)
+}
+
 // Read serial values
 func (connector LinkyConnector) readSerial() ([][]string, error) {
-	log.Debug("Read serial with config device:", connector.Device, " baudrate:", connector.BaudRate, " framesize:", connector.FrameSize, " parity:", connector.Parity, " stopbits:", connector.StopBits)
-	m := &serial.Mode{BaudRate: connector.BaudRate, DataBits: connector.FrameSize, Parity: connector.Parity, StopBits: connector.StopBits}
-	stream, err := serial.Open(connector.Device, m)
+	stream, err := connector.open()
 	if err != nil {
 		return nil, err
 	}
-
-	reader := bufio.NewReader(stream)
-	started := false
-	var values [][]string
+	defer stream.Close()
 
 	log.Debug("Read serial data...")
-	for {
-		bytes, _, err := reader.ReadLine()
-		if err != nil {
-			return nil, err
-		}
-
-		line := string(bytes)
-
-		// End loop when block ended
-		if started && strings.ContainsRune(line, 0x03) {
-			stream.Close()
-			break
-		}
-
-		// Collect data line by line
-		if started {
-			log.Debug(line)
-			values = append(values, strings.FieldsFunc(line, func(r rune) bool { return r == 0x09 || r == ' ' }))
-		}
-
-		// Start reading data when block started
-		if strings.ContainsRune(line, 0x02) {
-			started = true
-		}
+	scanner := newFrameScanner(stream)
+	_, values, err := scanner.next()
+	if err != nil {
+		return nil, err
 	}
 	log.Debug("Read serial data ended !")
 
@@ -129,12 +208,7 @@ func (connector LinkyConnector) GetLastHistoricalTicValue() (*HistoricalTicValue
 		return nil, err
 	}
 
-	values := HistoricalTicValue{}
-	for _, line := range lines {
-		values.ParseParam(line[0], line[1:])
-	}
-
-	return &values, nil
+	return parseHistoricalTicValue(lines), nil
 }
 
 // Return last serial Standard TIC
@@ -146,54 +220,39 @@ func (connector LinkyConnector) GetLastStandardTicValue() (*StandardTicValue, er
 		return nil, err
 	}
 
-	values := StandardTicValue{}
-	for _, line := range lines {
-		values.ParseParam(line[0], line[1:])
-	}
-
-	return &values, nil
+	return parseStandardTicValue(lines), nil
 }
 
 // Parse parity from string to serial object
-func ParseParity(value string) (parity serial.Parity) {
+func ParseParity(value string) (parity serial.Parity, err error) {
 	switch value {
 	case "ParityNone", "N":
 		parity = serial.NoParity
-		break
 	case "ParityOdd", "O":
 		parity = serial.OddParity
-		break
 	case "ParityEven", "E":
 		parity = serial.EvenParity
-		break
 	case "ParityMark", "M":
 		parity = serial.MarkParity
-		break
 	case "ParitySpace", "S":
 		parity = serial.SpaceParity
-		break
 	default:
-		log.Error(fmt.Errorf("Impossible to parse Parity named : %s", value))
-		os.Exit(3)
+		err = fmt.Errorf("Impossible to parse Parity named : %s", value)
 	}
 	return
 }
 
 // Parse stop bits from string to serial object
-func ParseStopBits(value string) (stopBits serial.StopBits) {
+func ParseStopBits(value string) (stopBits serial.StopBits, err error) {
 	switch value {
 	case "Stop1", "1":
 		stopBits = serial.OneStopBit
-		break
 	case "Stop1Half", "15":
 		stopBits = serial.OnePointFiveStopBits
-		break
 	case "Stop2", "2":
 		stopBits = serial.TwoStopBits
-		break
 	default:
-		log.Error(fmt.Errorf("Impossible to parse StopBits named : %s", value))
-		os.Exit(3)
+		err = fmt.Errorf("Impossible to parse StopBits named : %s", value)
 	}
 	return
 }
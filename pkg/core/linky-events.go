@@ -0,0 +1,142 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventType of a meter state transition
+type EventType string
+
+// Event types
+const (
+	TariffChanged            EventType = "tariff_changed"               // PTEC or LTARF
+	TariffIndexChanged       EventType = "tariff_index_changed"         // NTARF
+	RelayToggled             EventType = "relay_toggled"                // RELAIS
+	CutOffDeviceChanged      EventType = "cut_off_device_changed"       // STGE cut-off device
+	SurgeStarted             EventType = "surge_started"                // STGE surge
+	SurgeEnded               EventType = "surge_ended"                  //
+	TempoColorChanged        EventType = "tempo_color_changed"          // STGE Tempo color of the day
+	TempoNextDayColorChanged EventType = "tempo_next_day_color_changed" // STGE Tempo color of the next day
+	NextDayColorChanged      EventType = "next_day_color_changed"       // DEMAIN
+	OvervoltageStarted       EventType = "overvoltage_started"          // RMS voltage above +10%
+	OvervoltageEnded         EventType = "overvoltage_ended"            //
+	UndervoltageStarted      EventType = "undervoltage_started"         // RMS voltage below -10%
+	UndervoltageEnded        EventType = "undervoltage_ended"           //
+	OverrunStarted           EventType = "overrun_started"              // Subscribed power exceeded
+	OverrunEnded             EventType = "overrun_ended"                //
+)
+
+// Names of the cut-off device states of the STGE status register
+var cutOffDeviceStates = []string{"closed", "open_overpower", "open_overvoltage", "open_load_shedding", "open_remote_order", "open_overheating_above_max_current", "open_overheating_below_max_current"}
+
+// Names of the Tempo colors of the STGE status register
+var tempoColors = []string{"none", "blue", "white", "red"}
+
+// Event object describing a meter state transition between two consecutive frames
+type Event struct {
+	Time     time.Time `json:"time"` // Reception time of the frame
+	Meter    string    `json:"meter"`
+	Type     EventType `json:"type"`
+	Field    string    `json:"field"`                      // Changed value, as price_label, relay_2, status_surge or voltage_phase1
+	From     string    `json:"from,omitempty"`             // Previous value, empty when an episode starts
+	To       string    `json:"to,omitempty"`               // New value
+	Duration float64   `json:"duration_seconds,omitempty"` // Duration of an ended episode
+}
+
+// EventDetector object to diff consecutive frames of a meter into events
+type EventDetector struct {
+	previous *LinkyMeasurement
+}
+
+// Add a frame, returning the events of the transitions from the previous frame, none for the first frame
+func (detector *EventDetector) Add(frame Frame) []Event {
+	current := frame.Measurement()
+	previous := detector.previous
+	detector.previous = current
+	if previous == nil {
+		return nil
+	}
+
+	var events []Event
+	add := func(eventType EventType, field string, from string, to string) {
+		events = append(events, Event{Time: frame.Time, Type: eventType, Field: field, From: from, To: to})
+	}
+
+	for _, label := range []struct {
+		eventType EventType
+		field     string
+		from, to  string
+	}{
+		{TariffChanged, "price_label", previous.PriceLabel, current.PriceLabel},
+		{TariffIndexChanged, "tariff_index", previous.TariffIndex, current.TariffIndex},
+		{NextDayColorChanged, "next_day_color", previous.NextDayColor, current.NextDayColor},
+	} {
+		from, to := strings.TrimSpace(label.from), strings.TrimSpace(label.to)
+		if from != "" && to != "" && from != to {
+			add(label.eventType, label.field, from, to)
+		}
+	}
+
+	for _, sample := range current.Samples {
+		if sample.Quantity != Relay && sample.Quantity != Status {
+			continue
+		}
+		before, ok := previous.Get(sample.Quantity, sample.Direction, sample.Phase, sample.Index)
+		if !ok || before == sample.Value {
+			continue
+		}
+		from, to := int(before), int(sample.Value)
+		switch {
+		case sample.Quantity == Relay:
+			add(RelayToggled, sample.Key(), onOff(from), onOff(to))
+		case sample.Index == StatusCutOffDevice:
+			add(CutOffDeviceChanged, sample.Key(), stateName(cutOffDeviceStates, from), stateName(cutOffDeviceStates, to))
+		case sample.Index == StatusSurge && to != 0:
+			add(SurgeStarted, sample.Key(), "", "")
+		case sample.Index == StatusSurge:
+			add(SurgeEnded, sample.Key(), "", "")
+		case sample.Index == StatusTempoContractColor:
+			add(TempoColorChanged, sample.Key(), stateName(tempoColors, from), stateName(tempoColors, to))
+		case sample.Index == StatusTempoContractNextDayColor:
+			add(TempoNextDayColorChanged, sample.Key(), stateName(tempoColors, from), stateName(tempoColors, to))
+		}
+	}
+	return events
+}
+
+// Event of the start or end of a voltage episode
+func (episode VoltageEpisode) Event(at time.Time) Event {
+	event := Event{Time: at, Field: Sample{Quantity: Voltage, Phase: episode.Phase}.Key(), To: strconv.FormatFloat(episode.Voltage, 'f', -1, 64)}
+	switch {
+	case episode.Kind == Overvoltage && episode.Started:
+		event.Type = OvervoltageStarted
+	case episode.Kind == Overvoltage:
+		event.Type = OvervoltageEnded
+	case episode.Started:
+		event.Type = UndervoltageStarted
+	default:
+		event.Type = UndervoltageEnded
+	}
+	if !episode.Started {
+		event.Duration = episode.Duration.Seconds()
+	}
+	return event
+}
+
+// Name of a relay state
+func onOff(value int) string {
+	if value != 0 {
+		return "on"
+	}
+	return "off"
+}
+
+// Name of a status register state, its number when unknown
+func stateName(names []string, value int) string {
+	if value >= 0 && value < len(names) {
+		return names[value]
+	}
+	return strconv.Itoa(value)
+}
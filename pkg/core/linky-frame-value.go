@@ -0,0 +1,131 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Units of numeric historical labels, other labels are kept as text
+var historicalUnits = map[string]Unit{
+	"isousc":  Ampere,
+	"base":    WattHour,
+	"hchc":    WattHour,
+	"hchp":    WattHour,
+	"ejphn":   WattHour,
+	"ejphpm":  WattHour,
+	"bbrhcjb": WattHour,
+	"bbrhpjb": WattHour,
+	"bbrhcjw": WattHour,
+	"bbrhpjw": WattHour,
+	"bbrhcjr": WattHour,
+	"bbrhpjr": WattHour,
+	"pejp":    Minute,
+	"iinst":   Ampere,
+	"iinst1":  Ampere,
+	"iinst2":  Ampere,
+	"iinst3":  Ampere,
+	"adps":    Ampere,
+	"imax":    Ampere,
+	"imax1":   Ampere,
+	"imax2":   Ampere,
+	"imax3":   Ampere,
+	"pmax":    Watt,
+	"papp":    VoltAmpere,
+}
+
+// Units of numeric standard labels, other labels are kept as text
+var standardUnits = map[string]Unit{
+	"east":      WattHour,
+	"easf01":    WattHour,
+	"easf02":    WattHour,
+	"easf03":    WattHour,
+	"easf04":    WattHour,
+	"easf05":    WattHour,
+	"easf06":    WattHour,
+	"easf07":    WattHour,
+	"easf08":    WattHour,
+	"easf09":    WattHour,
+	"easf10":    WattHour,
+	"easd01":    WattHour,
+	"easd02":    WattHour,
+	"easd03":    WattHour,
+	"easd04":    WattHour,
+	"eait":      WattHour,
+	"erq1":      VarHour,
+	"erq2":      VarHour,
+	"erq3":      VarHour,
+	"erq4":      VarHour,
+	"irms1":     Ampere,
+	"irms2":     Ampere,
+	"irms3":     Ampere,
+	"urms1":     Volt,
+	"urms2":     Volt,
+	"urms3":     Volt,
+	"pref":      KiloVoltAmpere,
+	"pcoup":     KiloVoltAmpere,
+	"sinsts":    VoltAmpere,
+	"sinsts1":   VoltAmpere,
+	"sinsts2":   VoltAmpere,
+	"sinsts3":   VoltAmpere,
+	"smaxsn":    VoltAmpere,
+	"smaxsn1":   VoltAmpere,
+	"smaxsn2":   VoltAmpere,
+	"smaxsn3":   VoltAmpere,
+	"smaxsn-1":  VoltAmpere,
+	"smaxsn1-1": VoltAmpere,
+	"smaxsn2-1": VoltAmpere,
+	"smaxsn3-1": VoltAmpere,
+	"sinsti":    VoltAmpere,
+	"smaxin":    VoltAmpere,
+	"smaxin-1":  VoltAmpere,
+	"ccasn":     Watt,
+	"ccasn-1":   Watt,
+	"ccain":     Watt,
+	"ccain-1":   Watt,
+	"umoy1":     Volt,
+	"umoy2":     Volt,
+	"umoy3":     Volt,
+	"dpm1":      NoUnit,
+	"fpm1":      NoUnit,
+	"dpm2":      NoUnit,
+	"fpm2":      NoUnit,
+	"dpm3":      NoUnit,
+	"fpm3":      NoUnit,
+	"relais":    NoUnit,
+	"ntarf":     NoUnit,
+	"njourf":    NoUnit,
+	"njourf+1":  NoUnit,
+}
+
+// Value object to hold one typed data set of a frame
+type Value struct {
+	Label string
+	Value interface{} // int64 for numeric labels, string otherwise
+	Unit  Unit
+	Time  time.Time // Horodate of the value, zero when not provided
+}
+
+// Values returns the data sets of the frame in reception order, numeric labels converted with their unit
+func (frame Frame) Values() []Value {
+	units := historicalUnits
+	if frame.Mode == Standard {
+		units = standardUnits
+	}
+
+	var values []Value
+	for _, dataSet := range frame.DataSets {
+		value := Value{Label: dataSet.Label, Value: dataSet.Value}
+		if unit, numeric := units[strings.ToLower(dataSet.Label)]; numeric {
+			if number, err := strconv.ParseInt(dataSet.Value, 10, 64); err == nil {
+				value.Value = number
+				value.Unit = unit
+			}
+		}
+		if dataSet.Timestamp != "" {
+			value.Time, _ = parseHorodate(dataSet.Timestamp)
+		}
+		values = append(values, value)
+	}
+	return values
+}
@@ -0,0 +1,183 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	startOfText = 0x02
+	endOfText   = 0x03
+)
+
+// Frame object to hold one decoded TIC frame, whatever the mode
+type Frame struct {
+	Mode       LinkyMode           // Mode used to decode the frame
+	Time       time.Time           // Reception time of the frame
+	Raw        []byte              // Raw data sets received between STX and ETX
+	DataSets   []DataSet           // Data sets in reception order
+	Historical *HistoricalTicValue // Decoded values, only set in historical mode
+	Standard   *StandardTicValue   // Decoded values, only set in standard mode
+}
+
+// ParseFrame decodes the first complete frame, from STX to ETX, of the content
+func ParseFrame(mode LinkyMode, content []byte) (Frame, error) {
+	raw, lines, err := newFrameScanner(bytes.NewReader(content)).next()
+	if err != nil {
+		return Frame{}, err
+	}
+	return newFrame(mode, raw, lines), nil
+}
+
+// DataSet object to hold one label of a frame as received
+type DataSet struct {
+	Label     string
+	Timestamp string // Horodate, only set for timestamped labels in standard mode
+	Value     string
+	Checksum  string
+}
+
+// Valid returns whether the checksum of the data set matches its fields, computed on the label, horodate and value
+// with their separators in standard mode, including the one before the checksum, and on the label and value in historical mode
+func (dataSet DataSet) Valid(mode LinkyMode) bool {
+	return len(dataSet.Checksum) == 1 && dataSet.Checksum[0] == dataSet.checksum(mode)
+}
+
+// IsIdentifier returns whether a TIC label holds a meter identifier, ADCO, ADSC or PRM
+func IsIdentifier(label string) bool {
+	switch strings.ToUpper(label) {
+	case "ADCO", "ADSC", "PRM":
+		return true
+	}
+	return false
+}
+
+// Compute the checksum of the data set fields
+func (dataSet DataSet) checksum(mode LinkyMode) byte {
+	data := dataSet.Label + " " + dataSet.Value
+	if mode == Standard {
+		data = dataSet.Label + "\t"
+		if dataSet.Timestamp != "" {
+			data += dataSet.Timestamp + "\t"
+		}
+		data += dataSet.Value + "\t"
+	}
+
+	var sum byte
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+	return sum&0x3F + 0x20
+}
+
+// Verify returns an error for the first data set of the frame with an invalid checksum
+func (frame Frame) Verify() error {
+	for _, dataSet := range frame.DataSets {
+		if !dataSet.Valid(frame.Mode) {
+			return fmt.Errorf("Invalid checksum of data set %s", dataSet.Label)
+		}
+	}
+	return nil
+}
+
+// Build a frame decoded with the given mode
+func newFrame(mode LinkyMode, raw []byte, lines [][]string) Frame {
+	frame := Frame{Mode: mode, Time: time.Now(), Raw: raw, DataSets: parseDataSets(mode, raw)}
+	switch mode {
+	case Standard:
+		frame.Standard = parseStandardTicValue(lines)
+	default:
+		frame.Historical = parseHistoricalTicValue(lines)
+	}
+	return frame
+}
+
+// Split raw data in data sets, fields are separated by tabulations in standard mode and spaces in historical mode,
+// so that values holding spaces and space checksums are kept
+func parseDataSets(mode LinkyMode, raw []byte) []DataSet {
+	var dataSets []DataSet
+	for _, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		var fields []string
+		if mode == Standard {
+			fields = strings.Split(line, "\t")
+		} else {
+			fields = strings.SplitN(line, " ", 3)
+		}
+
+		switch len(fields) {
+		case 1:
+			dataSets = append(dataSets, DataSet{Label: fields[0]})
+		case 2:
+			dataSets = append(dataSets, DataSet{Label: fields[0], Value: fields[1]})
+		case 3:
+			dataSets = append(dataSets, DataSet{Label: fields[0], Value: fields[1], Checksum: fields[2]})
+		default:
+			dataSets = append(dataSets, DataSet{Label: fields[0], Timestamp: fields[1], Value: fields[2], Checksum: fields[3]})
+		}
+	}
+	return dataSets
+}
+
+// Scanner object to split a TIC byte stream into frames
+type frameScanner struct {
+	reader  *bufio.Reader
+	started bool
+}
+
+// Construct a frame scanner on a stream
+func newFrameScanner(stream io.Reader) *frameScanner {
+	return &frameScanner{reader: bufio.NewReader(stream)}
+}
+
+// Read the next complete frame and return its raw data and data sets split in fields
+func (scanner *frameScanner) next() ([]byte, [][]string, error) {
+	var raw []byte
+	var values [][]string
+
+	for {
+		// A stream ending right after ETX still holds a complete frame
+		line, err := scanner.reader.ReadBytes('\n')
+		if err != nil && bytes.IndexByte(line, endOfText) < 0 {
+			return nil, nil, err
+		}
+
+		// Skip data until the beginning of a frame
+		if !scanner.started {
+			if start := bytes.IndexByte(line, startOfText); start >= 0 {
+				scanner.started = true
+				line = line[start+1:]
+			} else {
+				continue
+			}
+		}
+
+		// End of frame, the next one can start on the same line
+		if end := bytes.IndexByte(line, endOfText); end >= 0 {
+			raw = append(raw, line[:end]...)
+			values = appendDataSet(values, line[:end])
+			scanner.started = bytes.IndexByte(line[end:], startOfText) >= 0
+			return raw, values, nil
+		}
+
+		raw = append(raw, line...)
+		values = appendDataSet(values, line)
+	}
+}
+
+// Split a data set line in fields and append it when not empty
+func appendDataSet(values [][]string, line []byte) [][]string {
+	fields := strings.FieldsFunc(strings.TrimRight(string(line), "\r\n"), func(r rune) bool { return r == 0x09 || r == ' ' })
+	if len(fields) == 0 {
+		return values
+	}
+	return append(values, fields)
+}
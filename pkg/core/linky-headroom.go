@@ -0,0 +1,93 @@
+package core
+
+import "time"
+
+// Headroom object to hold the apparent power left before the subscribed power and before the breaking power
+type Headroom struct {
+	Headroom    float64 // Subscribed power minus apparent power in VA, negative above the subscribed power
+	Breaking    float64 // Breaking power minus apparent power in VA, negative above the breaking power
+	HasBreaking bool    // Whether the frame holds the breaking power (PCOUP), only in standard mode
+	Overrun     bool    // Whether the meter signals an overrun (ADPS or STGE), or apparent power is above the subscribed power
+}
+
+// HeadroomOf returns the headroom of a frame, false without apparent and subscribed powers, the breaking power is optional
+func HeadroomOf(frame Frame) (Headroom, bool) {
+	measurement := frame.Measurement()
+	used, ok := measurement.Get(ApparentPower, Imported, 0, "")
+	if !ok {
+		return Headroom{}, false
+	}
+	reference, ok := measurement.Get(ReferencePower, NoDirection, 0, "")
+	if !ok || reference <= 0 {
+		return Headroom{}, false
+	}
+
+	headroom := Headroom{Headroom: reference*1000 - used}
+	// The breaker trips at the breaking power (PCOUP), which may be set above the subscribed power.
+	// Historical frames derive it from ADPS, an overrun current rather than a breaker setting.
+	if breaking, ok := measurement.Get(BreakingPower, NoDirection, 0, ""); ok && breaking > 0 && frame.Standard != nil {
+		headroom.Breaking, headroom.HasBreaking = breaking*1000-used, true
+	}
+	// Historical frames only hold ADPS while the current is above the subscribed current
+	headroom.Overrun = headroom.Headroom < 0 || (frame.Historical != nil && frame.Historical.Has("adps"))
+	if status, ok := measurement.Get(Status, NoDirection, 0, StatusReferencePowerExceeded); ok && status > 0 {
+		headroom.Overrun = true
+	}
+	return headroom, true
+}
+
+// Overruns object to hold the overrun events of a meter since the exporter started
+type Overruns struct {
+	Headroom     Headroom
+	Count        uint64        // Number of overrun events, including the current one
+	Duration     time.Duration // Total duration of overrun events, including the current one
+	Current      time.Duration // Duration of the current overrun event, 0 when none
+	LastDuration time.Duration // Duration of the last ended overrun event
+}
+
+// OverrunTracker object to track headroom and overrun events from successive frames
+type OverrunTracker struct {
+	overruns Overruns
+	known    bool
+	since    time.Time // Start of the current overrun event, zero when none
+	last     time.Time // Time of the last frame
+}
+
+// Add a frame, returning the overrun event started or ended by this frame, if any
+func (tracker *OverrunTracker) Add(frame Frame) (started bool, ended bool) {
+	headroom, ok := HeadroomOf(frame)
+	if !ok {
+		return false, false
+	}
+	tracker.overruns.Headroom = headroom
+	tracker.known = true
+
+	switch {
+	case headroom.Overrun && tracker.since.IsZero():
+		tracker.since, tracker.last = frame.Time, frame.Time
+		tracker.overruns.Count++
+		tracker.overruns.Current = 0
+		return true, false
+	case headroom.Overrun:
+		if frame.Time.After(tracker.last) {
+			tracker.overruns.Duration += frame.Time.Sub(tracker.last)
+			tracker.last = frame.Time
+		}
+		tracker.overruns.Current = tracker.last.Sub(tracker.since)
+	case !tracker.since.IsZero():
+		// The event lasts until the first frame without overrun
+		if frame.Time.After(tracker.last) {
+			tracker.overruns.Duration += frame.Time.Sub(tracker.last)
+		}
+		tracker.overruns.LastDuration = frame.Time.Sub(tracker.since)
+		tracker.overruns.Current = 0
+		tracker.since = time.Time{}
+		return false, true
+	}
+	return false, false
+}
+
+// Overruns returns headroom and overrun events, false until a frame provides apparent and subscribed powers
+func (tracker *OverrunTracker) Overruns() (Overruns, bool) {
+	return tracker.overruns, tracker.known
+}
@@ -14,7 +14,7 @@ type HistoricalTicValue struct {
 	Hchc     int32  // Index option Heures creuses : Heures Creuses en Wh
 	Hchp     int32  // Index option Heures pleines : Heures Pleines en Wh
 	Ejphn    int32  // Index option EJP : Heures Normales en Wh
-	Ejphpn   int32  // Index option EJP : Heures de Pointe Mobile en Wh
+	Ejphpm   int32  // Index option EJP : Heures de Pointe Mobile en Wh
 	Bbrhcjb  int32  // Index option Tempo : Heures Creuses Jours Bleus en Wh
 	Bbrhpjb  int32  // Index option Tempo : Heures Pleines Jours Bleus en Wh
 	Bbrhcjw  int32  // Index option Tempo : Heures Creuses Jours Blancs en Wh
@@ -38,6 +38,8 @@ type HistoricalTicValue struct {
 	Hhphc    string // Horaire Heures Pleines Heures Creuses
 	Motdetat string // Mot d'état du compteur
 	Ppot     string // Présence des potentiels
+
+	labels map[string]bool // Labels of received data sets
 }
 
 // Parse parameter with name and value
@@ -45,6 +47,10 @@ func (tic *HistoricalTicValue) ParseParam(name string, values []string) {
 	if len(values) == 0 {
 		return
 	}
+	if tic.labels == nil {
+		tic.labels = make(map[string]bool)
+	}
+	tic.labels[strings.ToLower(name)] = true
 
 	switch strings.ToLower(name) {
 	case "adco":
@@ -73,9 +79,9 @@ func (tic *HistoricalTicValue) ParseParam(name string, values []string) {
 		val, _ := strconv.ParseInt(values[0], 10, 32)
 		tic.Ejphn = int32(val)
 		break
-	case "ejphpn":
+	case "ejphpm":
 		val, _ := strconv.ParseInt(values[0], 10, 32)
-		tic.Ejphpn = int32(val)
+		tic.Ejphpm = int32(val)
 		break
 	case "bbrhcjb":
 		val, _ := strconv.ParseInt(values[0], 10, 32)
@@ -166,3 +172,17 @@ func (tic *HistoricalTicValue) ParseParam(name string, values []string) {
 		break
 	}
 }
+
+// Has returns true when the data set label was received
+func (tic HistoricalTicValue) Has(name string) bool {
+	return tic.labels[strings.ToLower(name)]
+}
+
+// Parse all data lines of a frame into Historical TIC
+func parseHistoricalTicValue(lines [][]string) *HistoricalTicValue {
+	values := HistoricalTicValue{}
+	for _, line := range lines {
+		values.ParseParam(line[0], line[1:])
+	}
+	return &values
+}
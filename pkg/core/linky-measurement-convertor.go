@@ -0,0 +1,211 @@
+package core
+
+import (
+	"strconv"
+)
+
+// Labels of historical energy indexes for each tariff option, in index order
+var historicalEnergyIndexes = [][]string{
+	{"base"},
+	{"hchc", "hchp"},
+	{"ejphn", "ejphpm"},
+	{"bbrhcjb", "bbrhpjb", "bbrhcjw", "bbrhpjw", "bbrhcjr", "bbrhpjr"},
+}
+
+// Convert Historical Tic Value to measurement
+func ConvertHistoricalTicValue(historicalValues HistoricalTicValue) *LinkyMeasurement {
+	measurement := &LinkyMeasurement{
+		Mode:           Historical,
+		MeterId:        historicalValues.Adco,
+		Version:        "1",
+		Contract:       historicalValues.Optarif,
+		PriceLabel:     historicalValues.Ptec,
+		NextDayColor:   historicalValues.Demain,
+		HourlySchedule: historicalValues.Hhphc,
+	}
+
+	if historicalValues.Has("papp") {
+		measurement.add(ApparentPower, Imported, 0, "", VoltAmpere, float64(historicalValues.Papp))
+	}
+
+	isTriplePhase := historicalValues.Has("iinst2") || historicalValues.Has("iinst3")
+	if isTriplePhase {
+		if historicalValues.Has("isousc") {
+			measurement.add(ReferencePower, NoDirection, 0, "", KiloVoltAmpere, float64(historicalValues.Isousc)*3*200/1000)
+		}
+		currents := []int16{historicalValues.Iinst1, historicalValues.Iinst2, historicalValues.Iinst3}
+		for phase, current := range currents {
+			if historicalValues.Has("iinst" + strconv.Itoa(phase+1)) {
+				measurement.add(Current, NoDirection, phase+1, "", Ampere, float64(current))
+			}
+		}
+	} else {
+		if historicalValues.Has("isousc") {
+			measurement.add(ReferencePower, NoDirection, 0, "", KiloVoltAmpere, float64(historicalValues.Isousc)*200/1000)
+		}
+		if historicalValues.Has("iinst") {
+			measurement.add(Current, NoDirection, 1, "", Ampere, float64(historicalValues.Iinst))
+		}
+		if historicalValues.Has("adps") {
+			measurement.add(BreakingPower, NoDirection, 0, "", KiloVoltAmpere, float64(historicalValues.Adps)*200/1000)
+		}
+	}
+
+	indexes := map[string]int32{
+		"base":    historicalValues.Base,
+		"hchc":    historicalValues.Hchc,
+		"hchp":    historicalValues.Hchp,
+		"ejphn":   historicalValues.Ejphn,
+		"ejphpm":  historicalValues.Ejphpm,
+		"bbrhcjb": historicalValues.Bbrhcjb,
+		"bbrhpjb": historicalValues.Bbrhpjb,
+		"bbrhcjw": historicalValues.Bbrhcjw,
+		"bbrhpjw": historicalValues.Bbrhpjw,
+		"bbrhcjr": historicalValues.Bbrhcjr,
+		"bbrhpjr": historicalValues.Bbrhpjr,
+	}
+	for _, labels := range historicalEnergyIndexes {
+		if !historicalValues.Has(labels[0]) {
+			continue
+		}
+		var total float64
+		for i, label := range labels {
+			if historicalValues.Has(label) {
+				total += float64(indexes[label])
+				measurement.add(ActiveEnergy, Imported, 0, "F"+strconv.Itoa(i+1), WattHour, float64(indexes[label]))
+			}
+		}
+		measurement.add(ActiveEnergy, Imported, 0, "", WattHour, total)
+		break
+	}
+
+	if historicalValues.Has("pejp") {
+		measurement.add(PeakNotice, NoDirection, 0, "", Minute, float64(historicalValues.Pejp))
+	}
+
+	return measurement
+}
+
+// Convert Standard Tic Value to measurement
+func ConvertStandardTicValue(standardValues StandardTicValue) *LinkyMeasurement {
+	measurement := &LinkyMeasurement{
+		Mode:               Standard,
+		MeterTime:          standardValues.Date,
+		MeterId:            standardValues.Adsc,
+		Version:            standardValues.Vtic,
+		Prm:                standardValues.Prm,
+		Contract:           standardValues.Ngtf,
+		PriceLabel:         standardValues.Ltarf,
+		NextDayProfile:     standardValues.Pjourfnd,
+		PeakNextDayProfile: standardValues.Ppointe,
+	}
+	if standardValues.Has("ntarf") {
+		measurement.TariffIndex = strconv.FormatInt(int64(standardValues.Ntarf), 10)
+	}
+	if standardValues.Has("njourf") {
+		measurement.DayNumber = strconv.FormatInt(int64(standardValues.Njourf), 10)
+	}
+	if standardValues.Has("njourf+1") {
+		measurement.NextDayNumber = strconv.FormatInt(int64(standardValues.Njourfnd), 10)
+	}
+
+	var samples = []struct {
+		label     string
+		quantity  Quantity
+		direction Direction
+		phase     int
+		index     string
+		unit      Unit
+		value     float64
+	}{
+		{"east", ActiveEnergy, Imported, 0, "", WattHour, float64(standardValues.East)},
+		{"easf01", ActiveEnergy, Imported, 0, "F1", WattHour, float64(standardValues.Easf01)},
+		{"easf02", ActiveEnergy, Imported, 0, "F2", WattHour, float64(standardValues.Easf02)},
+		{"easf03", ActiveEnergy, Imported, 0, "F3", WattHour, float64(standardValues.Easf03)},
+		{"easf04", ActiveEnergy, Imported, 0, "F4", WattHour, float64(standardValues.Easf04)},
+		{"easf05", ActiveEnergy, Imported, 0, "F5", WattHour, float64(standardValues.Easf05)},
+		{"easf06", ActiveEnergy, Imported, 0, "F6", WattHour, float64(standardValues.Easf06)},
+		{"easf07", ActiveEnergy, Imported, 0, "F7", WattHour, float64(standardValues.Easf07)},
+		{"easf08", ActiveEnergy, Imported, 0, "F8", WattHour, float64(standardValues.Easf08)},
+		{"easf09", ActiveEnergy, Imported, 0, "F9", WattHour, float64(standardValues.Easf09)},
+		{"easf10", ActiveEnergy, Imported, 0, "F10", WattHour, float64(standardValues.Easf10)},
+		{"easd01", ActiveEnergy, Imported, 0, "D1", WattHour, float64(standardValues.Easd01)},
+		{"easd02", ActiveEnergy, Imported, 0, "D2", WattHour, float64(standardValues.Easd02)},
+		{"easd03", ActiveEnergy, Imported, 0, "D3", WattHour, float64(standardValues.Easd03)},
+		{"easd04", ActiveEnergy, Imported, 0, "D4", WattHour, float64(standardValues.Easd04)},
+		{"eait", ActiveEnergy, Exported, 0, "", WattHour, float64(standardValues.Eait)},
+		{"erq1", ReactiveEnergy, NoDirection, 0, "Q1", VarHour, float64(standardValues.Erq1)},
+		{"erq2", ReactiveEnergy, NoDirection, 0, "Q2", VarHour, float64(standardValues.Erq2)},
+		{"erq3", ReactiveEnergy, NoDirection, 0, "Q3", VarHour, float64(standardValues.Erq3)},
+		{"erq4", ReactiveEnergy, NoDirection, 0, "Q4", VarHour, float64(standardValues.Erq4)},
+		{"irms1", Current, NoDirection, 1, "", Ampere, float64(standardValues.Irms1)},
+		{"irms2", Current, NoDirection, 2, "", Ampere, float64(standardValues.Irms2)},
+		{"irms3", Current, NoDirection, 3, "", Ampere, float64(standardValues.Irms3)},
+		{"urms1", Voltage, NoDirection, 1, "", Volt, float64(standardValues.Urms1)},
+		{"urms2", Voltage, NoDirection, 2, "", Volt, float64(standardValues.Urms2)},
+		{"urms3", Voltage, NoDirection, 3, "", Volt, float64(standardValues.Urms3)},
+		{"pref", ReferencePower, NoDirection, 0, "", KiloVoltAmpere, float64(standardValues.Pref)},
+		{"pcoup", BreakingPower, NoDirection, 0, "", KiloVoltAmpere, float64(standardValues.Pcoup)},
+		{"sinsts", ApparentPower, Imported, 0, "", VoltAmpere, float64(standardValues.Sinsts)},
+		{"sinsts1", ApparentPower, Imported, 1, "", VoltAmpere, float64(standardValues.Sinsts1)},
+		{"sinsts2", ApparentPower, Imported, 2, "", VoltAmpere, float64(standardValues.Sinsts2)},
+		{"sinsts3", ApparentPower, Imported, 3, "", VoltAmpere, float64(standardValues.Sinsts3)},
+		{"smaxsn", ApparentPowerMax, Imported, 0, "", VoltAmpere, float64(standardValues.Smaxsn)},
+		{"smaxsn1", ApparentPowerMax, Imported, 1, "", VoltAmpere, float64(standardValues.Smaxsn1)},
+		{"smaxsn2", ApparentPowerMax, Imported, 2, "", VoltAmpere, float64(standardValues.Smaxsn2)},
+		{"smaxsn3", ApparentPowerMax, Imported, 3, "", VoltAmpere, float64(standardValues.Smaxsn3)},
+		{"smaxsn-1", ApparentPowerMaxLastYear, Imported, 0, "", VoltAmpere, float64(standardValues.Smaxsnly)},
+		{"smaxsn1-1", ApparentPowerMaxLastYear, Imported, 1, "", VoltAmpere, float64(standardValues.Smaxsn1ly)},
+		{"smaxsn2-1", ApparentPowerMaxLastYear, Imported, 2, "", VoltAmpere, float64(standardValues.Smaxsn2ly)},
+		{"smaxsn3-1", ApparentPowerMaxLastYear, Imported, 3, "", VoltAmpere, float64(standardValues.Smaxsn3ly)},
+		{"sinsti", ApparentPower, Exported, 0, "", VoltAmpere, float64(standardValues.Sinsti)},
+		{"smaxin", ApparentPowerMax, Exported, 0, "", VoltAmpere, float64(standardValues.Smaxin)},
+		{"smaxin-1", ApparentPowerMaxLastYear, Exported, 0, "", VoltAmpere, float64(standardValues.Smaxinly)},
+		{"ccasn", LoadCurvePoint, Imported, 0, "", Watt, float64(standardValues.Ccasn)},
+		{"ccasn-1", LoadCurvePointLastYear, Imported, 0, "", Watt, float64(standardValues.Ccasnly)},
+		{"ccain", LoadCurvePoint, Exported, 0, "", Watt, float64(standardValues.Ccain)},
+		{"ccain-1", LoadCurvePointLastYear, Exported, 0, "", Watt, float64(standardValues.Ccainly)},
+		{"umoy1", AverageVoltage, NoDirection, 1, "", Volt, float64(standardValues.Umoy1)},
+		{"umoy2", AverageVoltage, NoDirection, 2, "", Volt, float64(standardValues.Umoy2)},
+		{"umoy3", AverageVoltage, NoDirection, 3, "", Volt, float64(standardValues.Umoy3)},
+		{"stge", Status, NoDirection, 0, StatusDryContact, NoUnit, float64(standardValues.DryContactStatus)},
+		{"stge", Status, NoDirection, 0, StatusCutOffDevice, NoUnit, float64(standardValues.CutOffDeviceStatus)},
+		{"stge", Status, NoDirection, 0, StatusLinkyTerminalShield, NoUnit, float64(standardValues.LinkyTerminalShieldStatus)},
+		{"stge", Status, NoDirection, 0, StatusSurge, NoUnit, float64(standardValues.SurgeStatus)},
+		{"stge", Status, NoDirection, 0, StatusReferencePowerExceeded, NoUnit, float64(standardValues.ReferencePowerExceededStatus)},
+		{"stge", Status, NoDirection, 0, StatusConsumption, NoUnit, float64(standardValues.ConsumptionStatus)},
+		{"stge", Status, NoDirection, 0, StatusEnergyDirection, NoUnit, float64(standardValues.EnergyDirectionStatus)},
+		{"stge", Status, NoDirection, 0, StatusContractTypePrice, NoUnit, float64(standardValues.ContractTypePriceStatus)},
+		{"stge", Status, NoDirection, 0, StatusContractTypePriceDistributor, NoUnit, float64(standardValues.ContractTypePriceDistributorStatus)},
+		{"stge", Status, NoDirection, 0, StatusClock, NoUnit, float64(standardValues.ClockStatus)},
+		{"stge", Status, NoDirection, 0, StatusTic, NoUnit, float64(standardValues.TicStatus)},
+		{"stge", Status, NoDirection, 0, StatusEuridisLink, NoUnit, float64(standardValues.EuridisLinkStatus)},
+		{"stge", Status, NoDirection, 0, StatusCPL, NoUnit, float64(standardValues.CPLStatus)},
+		{"stge", Status, NoDirection, 0, StatusCPLSync, NoUnit, float64(standardValues.CPLSyncStatus)},
+		{"stge", Status, NoDirection, 0, StatusTempoContractColor, NoUnit, float64(standardValues.TempoContractColorStatus)},
+		{"stge", Status, NoDirection, 0, StatusTempoContractNextDayColor, NoUnit, float64(standardValues.TempoContractNextDayColorStatus)},
+		{"stge", Status, NoDirection, 0, StatusMovingPeakNotice, NoUnit, float64(standardValues.MovingPeakNoticeStatus)},
+		{"stge", Status, NoDirection, 0, StatusMovingPeak, NoUnit, float64(standardValues.MovingPeakStatus)},
+		{"dpm1", MovablePeakStart, NoDirection, 0, "1", NoUnit, float64(standardValues.Dpm1)},
+		{"fpm1", MovablePeakEnd, NoDirection, 0, "1", NoUnit, float64(standardValues.Fpm1)},
+		{"dpm2", MovablePeakStart, NoDirection, 0, "2", NoUnit, float64(standardValues.Dpm2)},
+		{"fpm2", MovablePeakEnd, NoDirection, 0, "2", NoUnit, float64(standardValues.Fpm2)},
+		{"dpm3", MovablePeakStart, NoDirection, 0, "3", NoUnit, float64(standardValues.Dpm3)},
+		{"fpm3", MovablePeakEnd, NoDirection, 0, "3", NoUnit, float64(standardValues.Fpm3)},
+		{"relais", Relay, NoDirection, 0, "1", NoUnit, float64(standardValues.Relai1)},
+		{"relais", Relay, NoDirection, 0, "2", NoUnit, float64(standardValues.Relai2)},
+		{"relais", Relay, NoDirection, 0, "3", NoUnit, float64(standardValues.Relai3)},
+		{"relais", Relay, NoDirection, 0, "4", NoUnit, float64(standardValues.Relai4)},
+		{"relais", Relay, NoDirection, 0, "5", NoUnit, float64(standardValues.Relai5)},
+		{"relais", Relay, NoDirection, 0, "6", NoUnit, float64(standardValues.Relai6)},
+		{"relais", Relay, NoDirection, 0, "7", NoUnit, float64(standardValues.Relai7)},
+		{"relais", Relay, NoDirection, 0, "8", NoUnit, float64(standardValues.Relai8)},
+	}
+	for _, sample := range samples {
+		if standardValues.Has(sample.label) {
+			measurement.add(sample.quantity, sample.direction, sample.phase, sample.index, sample.unit, sample.value)
+		}
+	}
+
+	return measurement
+}
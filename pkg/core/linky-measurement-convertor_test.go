@@ -0,0 +1,105 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestConvertStandardTicValueKeepsZeroValues(t *testing.T) {
+	// Given
+	tic := StandardTicValue{}
+	tic.ParseParam("SINSTS", []string{"01700", "N"})
+	tic.ParseParam("SINSTI", []string{"00000", "F"})
+	tic.ParseParam("IRMS1", []string{"000", "0"})
+
+	// When
+	measurement := ConvertStandardTicValue(tic)
+
+	// Then
+	var tests = []struct {
+		quantity  Quantity
+		direction Direction
+		phase     int
+		want      float64
+		present   bool
+	}{
+		{ApparentPower, Imported, 0, 1700, true},
+		{ApparentPower, Exported, 0, 0, true},
+		{Current, NoDirection, 1, 0, true},
+		{Current, NoDirection, 2, 0, false},
+		{Voltage, NoDirection, 1, 0, false},
+	}
+	for _, tt := range tests {
+		testname := fmt.Sprintf("%s %s %d", tt.quantity, tt.direction, tt.phase)
+		t.Run(testname, func(t *testing.T) {
+			got, present := measurement.Get(tt.quantity, tt.direction, tt.phase, "")
+			if present != tt.present || got != tt.want {
+				t.Errorf("got %f (present %t), want %f (present %t)", got, present, tt.want, tt.present)
+			}
+		})
+	}
+}
+
+func TestConvertStandardTicValueStatus(t *testing.T) {
+	// Given
+	tic := StandardTicValue{}
+	tic.ParseParam("STGE", []string{"00DA0001", "K"})
+
+	// When
+	measurement := ConvertStandardTicValue(tic)
+
+	// Then
+	if samples := measurement.Filter(Status); len(samples) != 18 {
+		t.Errorf("got %d status samples, want %d", len(samples), 18)
+	}
+	var tests = []struct {
+		name string
+		want float64
+	}{
+		{StatusDryContact, 1},
+		{StatusCutOffDevice, 0},
+		{StatusTic, 1},
+		{StatusEuridisLink, 3},
+		{StatusCPL, 2},
+		{StatusCPLSync, 1},
+		{StatusTempoContractColor, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := measurement.Get(Status, NoDirection, 0, tt.name)
+			if got != tt.want {
+				t.Errorf("got %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertHistoricalTicValueIndexes(t *testing.T) {
+	// Given
+	tic := HistoricalTicValue{}
+	tic.ParseParam("ADCO", []string{"031762120345", "@"})
+	tic.ParseParam("HCHC", []string{"002345675", "'"})
+	tic.ParseParam("HCHP", []string{"006662251", "@"})
+	tic.ParseParam("IINST", []string{"000", "Y"})
+	tic.ParseParam("ISOUSC", []string{"30", "9"})
+
+	// When
+	measurement := ConvertHistoricalTicValue(tic)
+
+	// Then
+	if got, _ := measurement.Get(ActiveEnergy, Imported, 0, "F2"); got != 6662251 {
+		t.Errorf("got F2 %f, want %d", got, 6662251)
+	}
+	if got, _ := measurement.Get(ActiveEnergy, Imported, 0, ""); got != 9007926 {
+		t.Errorf("got total %f, want %d", got, 9007926)
+	}
+	if _, present := measurement.Get(Current, NoDirection, 1, ""); !present {
+		t.Error("idle current must be present")
+	}
+	if got, _ := measurement.Get(ReferencePower, NoDirection, 0, ""); got != 6 {
+		t.Errorf("got reference power %f, want %d", got, 6)
+	}
+	if _, present := measurement.Get(ApparentPower, Exported, 0, ""); present {
+		t.Error("produced power must be absent in historical mode")
+	}
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Quantity measured by the meter
+type Quantity string
+
+const (
+	ActiveEnergy             Quantity = "active_energy"                // Energy index counter, Index is empty for the total
+	ReactiveEnergy           Quantity = "reactive_energy"              // Reactive energy counter, Index is the quadrant
+	Current                  Quantity = "current"                      // RMS current
+	Voltage                  Quantity = "voltage"                      // RMS voltage
+	AverageVoltage           Quantity = "average_voltage"              // Average voltage over the last period
+	ApparentPower            Quantity = "apparent_power"               // Instantaneous apparent power
+	ApparentPowerMax         Quantity = "apparent_power_max"           // Maximum apparent power of the day
+	ApparentPowerMaxLastYear Quantity = "apparent_power_max_last_year" // Maximum apparent power of the same day last year
+	ReferencePower           Quantity = "reference_power"              // Subscribed apparent power
+	BreakingPower            Quantity = "breaking_power"               // Apparent power cutting the breaker
+	LoadCurvePoint           Quantity = "load_curve_point"             // Active load curve point
+	LoadCurvePointLastYear   Quantity = "load_curve_point_last_year"   // Previous active load curve point
+	PeakNotice               Quantity = "peak_notice"                  // EJP peak period notice
+	MovablePeakStart         Quantity = "movable_peak_start"           // Movable peak start, Index is the peak number
+	MovablePeakEnd           Quantity = "movable_peak_end"             // Movable peak end, Index is the peak number
+	Status                   Quantity = "status"                       // Status register field, Index is the field name
+	Relay                    Quantity = "relay"                        // Relay state, Index is the relay number
+)
+
+// Unit of a measured quantity
+type Unit string
+
+const (
+	NoUnit         Unit = ""
+	WattHour       Unit = "Wh"
+	VarHour        Unit = "VArh"
+	Ampere         Unit = "A"
+	Volt           Unit = "V"
+	VoltAmpere     Unit = "VA"
+	KiloVoltAmpere Unit = "kVA"
+	Watt           Unit = "W"
+	Minute         Unit = "min"
+)
+
+// Direction of the energy flow
+type Direction string
+
+const (
+	NoDirection Direction = ""
+	Imported    Direction = "imported" // Drawn from the grid (soutirée)
+	Exported    Direction = "exported" // Injected into the grid (injectée)
+)
+
+// Status register field names
+const (
+	StatusDryContact                   = "dry_contact"
+	StatusCutOffDevice                 = "cut_off_device"
+	StatusLinkyTerminalShield          = "terminal_shield"
+	StatusSurge                        = "surge"
+	StatusReferencePowerExceeded       = "reference_power_exceeded"
+	StatusConsumption                  = "producer"
+	StatusEnergyDirection              = "energy_direction"
+	StatusContractTypePrice            = "supplier_tariff_index"
+	StatusContractTypePriceDistributor = "distributor_tariff_index"
+	StatusClock                        = "clock_degraded"
+	StatusTic                          = "tic_mode"
+	StatusEuridisLink                  = "euridis_link"
+	StatusCPL                          = "cpl"
+	StatusCPLSync                      = "cpl_sync"
+	StatusTempoContractColor           = "tempo_color"
+	StatusTempoContractNextDayColor    = "tempo_next_day_color"
+	StatusMovingPeakNotice             = "movable_peak_notice"
+	StatusMovingPeak                   = "movable_peak"
+)
+
+// Sample object to hold one measured value with its dimensions
+type Sample struct {
+	Quantity  Quantity
+	Direction Direction // Energy flow, empty when not relevant
+	Phase     int       // Phase number, 0 when not phase specific
+	Index     string    // Tariff index (F1..F10, D1..D4), quadrant (Q1..Q4), status name or relay number
+	Unit      Unit
+	Value     float64
+}
+
+// Key of a sample, unique in a measurement, e.g. active_energy_imported_f1 or voltage_phase1
+func (sample Sample) Key() string {
+	parts := []string{string(sample.Quantity)}
+	if sample.Direction != NoDirection {
+		parts = append(parts, string(sample.Direction))
+	}
+	if sample.Index != "" {
+		parts = append(parts, strings.ToLower(sample.Index))
+	}
+	if sample.Phase > 0 {
+		parts = append(parts, fmt.Sprintf("phase%d", sample.Phase))
+	}
+	return strings.Join(parts, "_")
+}
+
+// LinkyMeasurement object to hold all values of one frame, whatever the mode.
+// Values absent from the frame have no sample, so a zero value is always a real reading.
+type LinkyMeasurement struct {
+	Mode               LinkyMode
+	Time               time.Time // Reception time of the frame
+	MeterTime          time.Time // Meter clock, zero when not provided
+	MeterId            string    // ADCO or ADSC
+	Version            string    // TIC version
+	Prm                string    // Point Reference Mesure
+	Contract           string    // Tariff option or supplier calendar name
+	PriceLabel         string    // Current tariff period label
+	TariffIndex        string    // Current supplier tariff index number
+	DayNumber          string    // Current day number of the supplier calendar
+	NextDayNumber      string    // Next day number of the supplier calendar
+	NextDayProfile     string    // Next day profile of the supplier calendar
+	PeakNextDayProfile string    // Next peak day profile
+	NextDayColor       string    // Tempo next day color
+	HourlySchedule     string    // Peak and off-peak hours schedule group
+	Samples            []Sample
+}
+
+// Add a sample to measurement
+func (measurement *LinkyMeasurement) add(quantity Quantity, direction Direction, phase int, index string, unit Unit, value float64) {
+	measurement.Samples = append(measurement.Samples, Sample{quantity, direction, phase, index, unit, value})
+}
+
+// Get returns the value of a sample and whether it was present in the frame
+func (measurement *LinkyMeasurement) Get(quantity Quantity, direction Direction, phase int, index string) (float64, bool) {
+	for _, sample := range measurement.Samples {
+		if sample.Quantity == quantity && sample.Direction == direction && sample.Phase == phase && sample.Index == index {
+			return sample.Value, true
+		}
+	}
+	return 0, false
+}
+
+// Filter returns all samples of a quantity
+func (measurement *LinkyMeasurement) Filter(quantity Quantity) []Sample {
+	var samples []Sample
+	for _, sample := range measurement.Samples {
+		if sample.Quantity == quantity {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+// Measurement converts the frame into the mode agnostic measurement model
+func (frame Frame) Measurement() *LinkyMeasurement {
+	var measurement *LinkyMeasurement
+	if frame.Standard != nil {
+		measurement = ConvertStandardTicValue(*frame.Standard)
+	} else if frame.Historical != nil {
+		measurement = ConvertHistoricalTicValue(*frame.Historical)
+	} else {
+		measurement = &LinkyMeasurement{Mode: frame.Mode}
+	}
+	measurement.Time = frame.Time
+	return measurement
+}
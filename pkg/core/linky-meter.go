@@ -0,0 +1,254 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Meter object to keep reading a connector in background and hold its last decoded frame
+type Meter struct {
+	Name      string
+	connector LinkyConnector
+	reader    *Reader
+	mutex     sync.RWMutex
+	last      *Frame
+	frames    uint64
+	errors    uint64
+	lastError error
+	listeners []func(Frame)
+	observers []func(Event)
+	power     *PowerEstimator
+	reactive  *ReactiveEstimator
+	exceeded  map[time.Duration]bool // tan φ windows above the threshold
+	overruns  OverrunTracker
+	quality   QualityTracker
+	detector  EventDetector
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// EstimationConfig object describing the windows of values derived from energy indexes
+type EstimationConfig struct {
+	PowerWindow     time.Duration   // Window of active and reactive power
+	TanPhiWindows   []time.Duration // Windows of tan φ
+	TanPhiThreshold float64         // tan φ above which a warning is logged
+}
+
+// MeterHealth object describing the reading state of a meter
+type MeterHealth struct {
+	Name      string
+	Device    string
+	Mode      LinkyMode // Mode of the last frame, unset before the first one
+	LastFrame time.Time // Reception time of the last frame, zero before the first one
+	Frames    uint64    // Number of decoded frames
+	Errors    uint64    // Number of reading errors
+	LastError error
+}
+
+// NewMeter method to construct Meter
+func NewMeter(name string, connector LinkyConnector) *Meter {
+	return &Meter{
+		Name:      name,
+		connector: connector,
+		reader:    NewReader(connector),
+		power:     NewPowerEstimator(DefaultPowerWindow),
+		reactive:  NewReactiveEstimator(DefaultPowerWindow, DefaultTanPhiWindows, DefaultTanPhiThreshold),
+		exceeded:  make(map[time.Duration]bool),
+	}
+}
+
+// Start reading frames in background until Stop is called or the context is done
+func (meter *Meter) Start(ctx context.Context) {
+	ctx, meter.cancel = context.WithCancel(ctx)
+	meter.done = make(chan struct{})
+	frames := meter.reader.Frames(ctx)
+
+	go func() {
+		for {
+			select {
+			case err := <-meter.reader.Errors():
+				meter.mutex.Lock()
+				meter.errors++
+				meter.lastError = err
+				meter.mutex.Unlock()
+			case <-meter.done:
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer close(meter.done)
+		for frame := range frames {
+			log.Debugf("Frame received from %s", meter.Name)
+			frame := frame
+			meter.mutex.Lock()
+			meter.last = &frame
+			meter.frames++
+			meter.power.Add(frame)
+			meter.reactive.Add(frame)
+			meter.checkTanPhi()
+			events := meter.detector.Add(frame)
+			events = append(events, meter.checkOverrun(frame)...)
+			events = append(events, meter.checkVoltage(frame)...)
+			listeners := meter.listeners
+			observers := meter.observers
+			meter.mutex.Unlock()
+
+			for _, listener := range listeners {
+				listener(frame)
+			}
+			for _, event := range events {
+				event.Meter = meter.Name
+				for _, observer := range observers {
+					observer(event)
+				}
+			}
+		}
+	}()
+}
+
+// Listen registers a function called with each decoded frame, it must not block the reading
+func (meter *Meter) Listen(listener func(Frame)) {
+	meter.mutex.Lock()
+	defer meter.mutex.Unlock()
+	meter.listeners = append(meter.listeners, listener)
+}
+
+// Observe registers a function called with each event of the state transitions between frames, it must not block the reading
+func (meter *Meter) Observe(observer func(Event)) {
+	meter.mutex.Lock()
+	defer meter.mutex.Unlock()
+	meter.observers = append(meter.observers, observer)
+}
+
+// Stop reading and wait for the connection to be closed
+func (meter *Meter) Stop() {
+	if meter.cancel == nil {
+		return
+	}
+	meter.cancel()
+	<-meter.done
+}
+
+// Last returns the last decoded frame, false when no frame was decoded yet
+func (meter *Meter) Last() (Frame, bool) {
+	meter.mutex.RLock()
+	defer meter.mutex.RUnlock()
+
+	if meter.last == nil {
+		return Frame{}, false
+	}
+	return *meter.last, true
+}
+
+// Configure changes the windows of values derived from energy indexes
+func (meter *Meter) Configure(estimation EstimationConfig) {
+	meter.mutex.Lock()
+	defer meter.mutex.Unlock()
+	meter.power.Window = estimation.PowerWindow
+	meter.reactive.PowerWindow = estimation.PowerWindow
+	meter.reactive.TanPhiWindows = estimation.TanPhiWindows
+	meter.reactive.Threshold = estimation.TanPhiThreshold
+}
+
+// Power returns the active power derived from energy indexes, false until enough frames are received
+func (meter *Meter) Power() (PowerEstimate, bool) {
+	meter.mutex.RLock()
+	defer meter.mutex.RUnlock()
+	return meter.power.Estimate()
+}
+
+// Reactive returns reactive power and tan φ derived from energy indexes, false until enough frames are received
+func (meter *Meter) Reactive() (ReactiveEstimate, bool) {
+	meter.mutex.RLock()
+	defer meter.mutex.RUnlock()
+	return meter.reactive.Estimate()
+}
+
+// Log when tan φ of a window goes above or back below the threshold, the mutex must be held
+func (meter *Meter) checkTanPhi() {
+	estimate, _ := meter.reactive.Estimate()
+	for _, tanPhi := range estimate.TanPhi {
+		if tanPhi.Exceeded == meter.exceeded[tanPhi.Window] {
+			continue
+		}
+		meter.exceeded[tanPhi.Window] = tanPhi.Exceeded
+		if tanPhi.Exceeded {
+			log.Warnf("tan φ of %s over %s is %.2f, above %.2f", meter.Name, tanPhi.Window, tanPhi.Value, meter.reactive.Threshold)
+		} else {
+			log.Infof("tan φ of %s over %s is %.2f, back below %.2f", meter.Name, tanPhi.Window, tanPhi.Value, meter.reactive.Threshold)
+		}
+	}
+}
+
+// Overruns returns headroom and overrun events, false until a frame provides apparent and subscribed powers
+func (meter *Meter) Overruns() (Overruns, bool) {
+	meter.mutex.RLock()
+	defer meter.mutex.RUnlock()
+	return meter.overruns.Overruns()
+}
+
+// Track overrun events of a frame and log their start and end, the mutex must be held
+func (meter *Meter) checkOverrun(frame Frame) []Event {
+	started, ended := meter.overruns.Add(frame)
+	overruns, _ := meter.overruns.Overruns()
+	var events []Event
+	if started {
+		log.Warnf("Subscribed power of %s exceeded, headroom is %.0f VA", meter.Name, overruns.Headroom.Headroom)
+		events = append(events, Event{Time: frame.Time, Type: OverrunStarted, Field: string(ReferencePower)})
+	}
+	if ended {
+		log.Infof("Subscribed power overrun of %s ended after %s", meter.Name, overruns.LastDuration)
+		events = append(events, Event{Time: frame.Time, Type: OverrunEnded, Field: string(ReferencePower), Duration: overruns.LastDuration.Seconds()})
+	}
+	return events
+}
+
+// Quality returns phase imbalance and voltage quality, false until a frame provides voltages or phase values
+func (meter *Meter) Quality() (Quality, bool) {
+	meter.mutex.RLock()
+	defer meter.mutex.RUnlock()
+	return meter.quality.Quality()
+}
+
+// Track voltage episodes of a frame and log their start and end, the mutex must be held
+func (meter *Meter) checkVoltage(frame Frame) []Event {
+	var events []Event
+	for _, episode := range meter.quality.Add(frame) {
+		events = append(events, episode.Event(frame.Time))
+		if episode.Started {
+			log.Warnf("Phase %d of %s in %svoltage, %.0f V", episode.Phase, meter.Name, episode.Kind, episode.Voltage)
+		} else {
+			log.Infof("Phase %d of %s back from %svoltage after %s", episode.Phase, meter.Name, episode.Kind, episode.Duration)
+		}
+	}
+	return events
+}
+
+// Health returns the reading state of the meter
+func (meter *Meter) Health() MeterHealth {
+	meter.mutex.RLock()
+	defer meter.mutex.RUnlock()
+
+	health := MeterHealth{
+		Name:      meter.Name,
+		Device:    meter.connector.Device,
+		Frames:    meter.frames,
+		Errors:    meter.errors,
+		LastError: meter.lastError,
+	}
+	if meter.last != nil {
+		health.Mode = meter.last.Mode
+		health.LastFrame = meter.last.Time
+	}
+	return health
+}
+
+// Up returns whether a frame was received within the timeout
+func (health MeterHealth) Up(timeout time.Duration) bool {
+	return !health.LastFrame.IsZero() && time.Since(health.LastFrame) <= timeout
+}
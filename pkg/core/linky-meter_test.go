@@ -0,0 +1,298 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestMeterHealth(t *testing.T) {
+	// Given
+	meter := NewMeter("main", LinkyConnector{Mode: Historical, Device: "/dev/null"})
+	meter.reader.open = func(LinkyConnector) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewBufferString(historicalFrames)), nil
+	}
+	meter.reader.retryDelay = time.Hour
+	var listened []Frame
+	meter.Listen(func(frame Frame) { listened = append(listened, frame) })
+
+	// When
+	meter.Start(context.Background())
+	deadline := time.Now().Add(5 * time.Second)
+	for meter.Health().Errors == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	meter.Stop()
+	health := meter.Health()
+
+	// Then
+	if health.Name != "main" || health.Device != "/dev/null" || health.Mode != Historical {
+		t.Errorf("unexpected health %+v", health)
+	}
+	if health.Frames != 2 || health.Errors != 1 || health.LastError != io.EOF {
+		t.Errorf("got %d frames and %d errors (%v)", health.Frames, health.Errors, health.LastError)
+	}
+	if last, ok := meter.Last(); !ok || last.Historical.Papp != 2540 {
+		t.Error("last frame not kept")
+	}
+	if len(listened) != 2 {
+		t.Errorf("listener got %d frames, want 2", len(listened))
+	}
+}
+
+// Decode a standard frame of the data sets, with valid checksums
+func standardFrame(t *testing.T, dataSets ...DataSet) Frame {
+	frame, err := ParseFrame(Standard, encodeFrame(Standard, dataSets))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return frame
+}
+
+// Meter clock data set at a time
+func dateDataSet(at time.Time) DataSet {
+	return DataSet{Label: "DATE", Timestamp: "E" + at.In(time.FixedZone("", 2*3600)).Format("060102150405")}
+}
+
+// Standard frame with the meter clock, total energy index and apparent power
+func powerFrame(t *testing.T, at time.Time, east int, sinsts int) Frame {
+	return standardFrame(t, dateDataSet(at), DataSet{Label: "EAST", Value: fmt.Sprintf("%09d", east)}, DataSet{Label: "SINSTS", Value: fmt.Sprintf("%05d", sinsts)})
+}
+
+func TestPowerEstimatorTableDriven(t *testing.T) {
+	// Given
+	start := time.Date(2023, 6, 14, 12, 0, 0, 0, time.UTC)
+	var tests = []struct {
+		name     string
+		energies []int
+		expected PowerEstimate
+		ok       bool
+	}{
+		{"steady", []int{10000, 10010, 10020, 10030}, PowerEstimate{ActivePower: 1200, PowerFactor: 0.8, HasPowerFactor: true}, true},
+		{"single frame", []int{10000}, PowerEstimate{}, false},
+		{"replaced meter", []int{10000, 10010, 10}, PowerEstimate{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimator := NewPowerEstimator(time.Minute)
+
+			// When
+			for i, energy := range tt.energies {
+				estimator.Add(powerFrame(t, start.Add(time.Duration(i)*30*time.Second), energy, 1500))
+			}
+			estimate, ok := estimator.Estimate()
+
+			// Then
+			if ok != tt.ok || estimate != tt.expected {
+				t.Errorf("got %+v %v, want %+v %v", estimate, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+// Standard frame with the meter clock, total energy index and reactive energy indexes
+func reactiveFrame(t *testing.T, at time.Time, east int, erq1 int) Frame {
+	return standardFrame(t, dateDataSet(at), DataSet{Label: "EAST", Value: fmt.Sprintf("%09d", east)}, DataSet{Label: "ERQ1", Value: fmt.Sprintf("%09d", erq1)},
+		DataSet{Label: "ERQ2", Value: "000000000"}, DataSet{Label: "ERQ3", Value: "000000000"}, DataSet{Label: "ERQ4", Value: "000000100"})
+}
+
+func TestReactiveEstimatorTableDriven(t *testing.T) {
+	// Given
+	start := time.Date(2023, 6, 14, 12, 0, 0, 0, time.UTC)
+	var tests = []struct {
+		name      string
+		reactives []int
+		power     [4]float64
+		tanPhi    []TanPhi
+		ok        bool
+	}{
+		{"below threshold", []int{5000, 5001, 5002, 5003}, [4]float64{120, 0, 0, 0}, []TanPhi{{Window: 2 * time.Minute, Value: 0.1}}, true},
+		{"above threshold", []int{5000, 5005, 5010, 5015}, [4]float64{600, 0, 0, 0}, []TanPhi{{Window: 2 * time.Minute, Value: 0.5, Exceeded: true}}, true},
+		{"single frame", []int{5000}, [4]float64{}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimator := NewReactiveEstimator(time.Minute, []time.Duration{2 * time.Minute, time.Hour}, DefaultTanPhiThreshold)
+
+			// When
+			for i, reactive := range tt.reactives {
+				estimator.Add(reactiveFrame(t, start.Add(time.Duration(i)*30*time.Second), 10000+i*10, reactive))
+			}
+			estimate, ok := estimator.Estimate()
+
+			// Then
+			if ok != tt.ok || estimate.Power != tt.power || fmt.Sprint(estimate.TanPhi) != fmt.Sprint(tt.tanPhi) {
+				t.Errorf("got %+v %v, want %+v %+v %v", estimate, ok, tt.power, tt.tanPhi, tt.ok)
+			}
+		})
+	}
+}
+
+func TestHeadroomOfTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name     string
+		mode     LinkyMode
+		dataSets []DataSet
+		expected Headroom
+	}{
+		{"breaking above reference", Standard, []DataSet{{Label: "PREF", Value: "06"}, {Label: "PCOUP", Value: "09"}, {Label: "SINSTS", Value: "05000"}}, Headroom{Headroom: 1000, Breaking: 4000, HasBreaking: true}},
+		{"above reference below breaking", Standard, []DataSet{{Label: "PREF", Value: "06"}, {Label: "PCOUP", Value: "09"}, {Label: "SINSTS", Value: "07000"}}, Headroom{Headroom: -1000, Breaking: 2000, HasBreaking: true, Overrun: true}},
+		{"no breaking", Standard, []DataSet{{Label: "PREF", Value: "06"}, {Label: "SINSTS", Value: "05000"}}, Headroom{Headroom: 1000}},
+		{"historical overrun", Historical, []DataSet{{Label: "ISOUSC", Value: "30"}, {Label: "PAPP", Value: "06500"}, {Label: "ADPS", Value: "032"}}, Headroom{Headroom: -500, Overrun: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := ParseFrame(tt.mode, encodeFrame(tt.mode, tt.dataSets))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// When
+			headroom, ok := HeadroomOf(frame)
+
+			// Then
+			if !ok || headroom != tt.expected {
+				t.Errorf("got %+v %v, want %+v", headroom, ok, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOverrunTrackerTableDriven(t *testing.T) {
+	// Given
+	start := time.Date(2023, 6, 14, 12, 0, 0, 0, time.UTC)
+	var tests = []struct {
+		name     string
+		powers   []int
+		expected Overruns
+	}{
+		{"no overrun", []int{3000, 5500}, Overruns{Headroom: Headroom{Headroom: 500}}},
+		{"ongoing overrun", []int{3000, 6200, 6500}, Overruns{Headroom: Headroom{Headroom: -500, Overrun: true}, Count: 1, Duration: 10 * time.Second, Current: 10 * time.Second}},
+		{"ended overrun", []int{6200, 6500, 5000}, Overruns{Headroom: Headroom{Headroom: 1000}, Count: 1, Duration: 20 * time.Second, LastDuration: 20 * time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tracker OverrunTracker
+
+			// When
+			for i, power := range tt.powers {
+				frame := standardFrame(t, DataSet{Label: "PREF", Value: "06"}, DataSet{Label: "SINSTS", Value: fmt.Sprintf("%05d", power)})
+				frame.Time = start.Add(time.Duration(i) * 10 * time.Second)
+				tracker.Add(frame)
+			}
+			overruns, ok := tracker.Overruns()
+
+			// Then
+			if !ok || overruns != tt.expected {
+				t.Errorf("got %+v %v, want %+v", overruns, ok, tt.expected)
+			}
+		})
+	}
+}
+
+func TestQualityTrackerTableDriven(t *testing.T) {
+	// Given
+	start := time.Date(2023, 6, 14, 12, 0, 0, 0, time.UTC)
+	var tests = []struct {
+		name      string
+		voltages  []int
+		average   []DataSet
+		imbalance float64
+		band      string
+		over      Episodes
+		under     Episodes
+		episodes  int
+	}{
+		{"normal voltage", []int{230, 235}, nil, 50, BandNormal, Episodes{}, Episodes{}, 0},
+		{"ongoing overvoltage", []int{230, 255, 256}, nil, 50, BandOvervoltage, Episodes{Count: 1, Duration: 10 * time.Second}, Episodes{}, 1},
+		{"ended undervoltage", []int{200, 195, 230}, nil, 50, BandNormal, Episodes{}, Episodes{Count: 1, Duration: 20 * time.Second}, 2},
+		{"average voltage band", []int{230}, []DataSet{{Label: "UMOY1", Timestamp: "E230614120000", Value: "190"}}, 50, BandSevereUndervoltage, Episodes{}, Episodes{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tracker QualityTracker
+			var episodes int
+
+			// When
+			for i, voltage := range tt.voltages {
+				dataSets := []DataSet{{Label: "IRMS1", Value: "010"}, {Label: "IRMS2", Value: "020"}, {Label: "IRMS3", Value: "030"}, {Label: "URMS1", Value: fmt.Sprintf("%03d", voltage)}}
+				frame := standardFrame(t, append(dataSets, tt.average...)...)
+				frame.Time = start.Add(time.Duration(i) * 10 * time.Second)
+				episodes += len(tracker.Add(frame))
+			}
+			quality, ok := tracker.Quality()
+
+			// Then
+			if !ok || !quality.HasCurrent || quality.CurrentImbalance != tt.imbalance || quality.HasPower || len(quality.Phases) != 1 {
+				t.Fatalf("got %+v %v", quality, ok)
+			}
+			phase := quality.Phases[0]
+			if phase.Band != tt.band || phase.Overvoltage.Count != tt.over.Count || phase.Overvoltage.Duration != tt.over.Duration ||
+				phase.Undervoltage.Count != tt.under.Count || phase.Undervoltage.Duration != tt.under.Duration {
+				t.Errorf("got %+v, want band %s, over %+v and under %+v", phase, tt.band, tt.over, tt.under)
+			}
+			if episodes != tt.episodes {
+				t.Errorf("got %d episode changes, want %d", episodes, tt.episodes)
+			}
+		})
+	}
+}
+
+func TestEventDetectorTableDriven(t *testing.T) {
+	// Given
+	start := time.Date(2023, 6, 14, 12, 0, 0, 0, time.UTC)
+	first := []DataSet{{Label: "LTARF", Value: "HC"}, {Label: "NTARF", Value: "01"}, {Label: "RELAIS", Value: "000"}, {Label: "STGE", Value: "00000000"}}
+	var tests = []struct {
+		name     string
+		frames   [][]DataSet
+		expected []Event
+	}{
+		{"first frame", [][]DataSet{first}, nil},
+		{"unchanged", [][]DataSet{first, first}, nil},
+		{"transitions", [][]DataSet{first, {{Label: "LTARF", Value: "HP"}, {Label: "NTARF", Value: "02"}, {Label: "RELAIS", Value: "001"}, {Label: "STGE", Value: "01000040"}}}, []Event{
+			{Type: TariffChanged, Field: "price_label", From: "HC", To: "HP"},
+			{Type: TariffIndexChanged, Field: "tariff_index", From: "1", To: "2"},
+			{Type: SurgeStarted, Field: "status_surge"},
+			{Type: TempoColorChanged, Field: "status_tempo_color", From: "none", To: "blue"},
+			{Type: RelayToggled, Field: "relay_1", From: "off", To: "on"},
+		}},
+		{"cut-off device", [][]DataSet{first, {{Label: "LTARF", Value: "HC"}, {Label: "NTARF", Value: "01"}, {Label: "RELAIS", Value: "000"}, {Label: "STGE", Value: "00000002"}}}, []Event{
+			{Type: CutOffDeviceChanged, Field: "status_cut_off_device", From: "closed", To: "open_overpower"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var detector EventDetector
+			var events []Event
+
+			// When
+			for i, dataSets := range tt.frames {
+				frame := standardFrame(t, dataSets...)
+				frame.Time = start.Add(time.Duration(i) * time.Second)
+				events = detector.Add(frame)
+			}
+
+			// Then
+			if len(events) != len(tt.expected) {
+				t.Fatalf("got %+v, want %+v", events, tt.expected)
+			}
+			for i, event := range events {
+				expected := tt.expected[i]
+				expected.Time = start.Add(time.Duration(len(tt.frames)-1) * time.Second)
+				if event != expected {
+					t.Errorf("got %+v, want %+v", event, expected)
+				}
+			}
+		})
+	}
+}
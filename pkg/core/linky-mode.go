@@ -3,6 +3,7 @@ package core
 import "go.bug.st/serial"
 
 type LinkyMode struct {
+	Name      string
 	BaudRate  int
 	FrameSize int
 	Parity    serial.Parity
@@ -10,6 +11,11 @@ type LinkyMode struct {
 }
 
 var (
-	Standard   = LinkyMode{9600, 7, serial.NoParity, serial.OneStopBit}
-	Historical = LinkyMode{1200, 7, serial.NoParity, serial.OneStopBit}
+	Standard   = LinkyMode{"standard", 9600, 7, serial.NoParity, serial.OneStopBit}
+	Historical = LinkyMode{"historical", 1200, 7, serial.NoParity, serial.OneStopBit}
 )
+
+// String returns the mode name
+func (mode LinkyMode) String() string {
+	return mode.Name
+}
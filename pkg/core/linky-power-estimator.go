@@ -0,0 +1,98 @@
+package core
+
+import "time"
+
+// Default duration over which active power is averaged
+const DefaultPowerWindow = time.Minute
+
+// Point of the power estimator
+type powerPoint struct {
+	time          time.Time
+	energy        float64 // Total imported active energy in Wh
+	apparentPower float64 // Apparent power in VA, negative when not provided
+}
+
+// PowerEstimate object to hold the active power derived from energy indexes
+type PowerEstimate struct {
+	ActivePower    float64 // Average active power over the window in W
+	PowerFactor    float64 // Active power over average apparent power, between 0 and 1
+	HasPowerFactor bool    // Whether apparent power was provided to compute the power factor
+}
+
+// PowerEstimator object to derive active power from successive total energy indexes.
+// Indexes have a 1 Wh resolution, so power is averaged over a window to smooth the steps.
+type PowerEstimator struct {
+	Window time.Duration
+	points []powerPoint
+}
+
+// NewPowerEstimator method to construct PowerEstimator
+func NewPowerEstimator(window time.Duration) *PowerEstimator {
+	return &PowerEstimator{Window: window}
+}
+
+// Add the total energy index of a frame, timed by the meter clock when provided
+func (estimator *PowerEstimator) Add(frame Frame) {
+	measurement := frame.Measurement()
+	energy, ok := measurement.Get(ActiveEnergy, Imported, 0, "")
+	if !ok {
+		return
+	}
+	point := powerPoint{time: frame.Time, energy: energy, apparentPower: -1}
+	if !measurement.MeterTime.IsZero() {
+		point.time = measurement.MeterTime
+	}
+	if apparentPower, ok := measurement.Get(ApparentPower, Imported, 0, ""); ok {
+		point.apparentPower = apparentPower
+	}
+
+	if last := len(estimator.points) - 1; last >= 0 {
+		previous := estimator.points[last]
+		switch {
+		case energy < previous.energy || point.time.Before(previous.time):
+			// A lower index or time comes from a replaced meter or a clock change, previous points are meaningless
+			estimator.points = nil
+		case point.time.Equal(previous.time):
+			// Frames within the same second of the meter clock
+			return
+		}
+	}
+	estimator.points = append(estimator.points, point)
+
+	// Keep the last point before the window, so that the window is fully covered
+	for len(estimator.points) > 2 && !estimator.points[1].time.After(point.time.Add(-estimator.Window)) {
+		estimator.points = estimator.points[1:]
+	}
+}
+
+// Estimate returns the active power and power factor, false until points cover half of the window
+func (estimator *PowerEstimator) Estimate() (PowerEstimate, bool) {
+	if len(estimator.points) < 2 {
+		return PowerEstimate{}, false
+	}
+	first, last := estimator.points[0], estimator.points[len(estimator.points)-1]
+	elapsed := last.time.Sub(first.time)
+	if elapsed < estimator.Window/2 {
+		return PowerEstimate{}, false
+	}
+
+	estimate := PowerEstimate{ActivePower: (last.energy - first.energy) * float64(time.Hour) / float64(elapsed)}
+
+	// Apparent power of the first point was measured before the window
+	var apparentPower float64
+	for _, point := range estimator.points[1:] {
+		if point.apparentPower < 0 {
+			return estimate, true
+		}
+		apparentPower += point.apparentPower
+	}
+	apparentPower /= float64(len(estimator.points) - 1)
+	if apparentPower > 0 {
+		estimate.PowerFactor = estimate.ActivePower / apparentPower
+		if estimate.PowerFactor > 1 {
+			estimate.PowerFactor = 1
+		}
+		estimate.HasPowerFactor = true
+	}
+	return estimate, true
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"sort"
+	"time"
+)
+
+// Reactive energy quadrants, in index order of ReactiveEstimate.Power
+var Quadrants = []string{"Q1", "Q2", "Q3", "Q4"}
+
+// Default windows and threshold of tan φ, above 0.4 reactive energy is billed on C4 contracts
+var (
+	DefaultTanPhiWindows   = []time.Duration{10 * time.Minute, time.Hour, 24 * time.Hour}
+	DefaultTanPhiThreshold = 0.4
+)
+
+// Point of the reactive estimator
+type reactivePoint struct {
+	time     time.Time
+	active   float64    // Total imported active energy in Wh
+	reactive [4]float64 // Reactive energy by quadrant in VArh
+}
+
+// TanPhi object to hold the ratio of inductive reactive energy over active energy consumed within a window
+type TanPhi struct {
+	Window   time.Duration
+	Value    float64
+	Exceeded bool // Whether the value is above the threshold
+}
+
+// ReactiveEstimate object to hold values derived from reactive energy indexes
+type ReactiveEstimate struct {
+	Power     [4]float64 // Reactive power by quadrant in var, averaged over the power window
+	HasPower  bool       // Whether frames cover the power window
+	TanPhi    []TanPhi   // Windows covered by frames, with active energy consumed
+	Threshold float64    // Threshold of tan φ
+}
+
+// ReactiveEstimator object to derive reactive power and tan φ from the reactive energy indexes of standard mode
+type ReactiveEstimator struct {
+	PowerWindow   time.Duration
+	TanPhiWindows []time.Duration
+	Threshold     float64
+	recent        []reactivePoint // Every frame of the power window
+	history       []reactivePoint // Sampled frames of the longest tan φ window
+}
+
+// NewReactiveEstimator method to construct ReactiveEstimator
+func NewReactiveEstimator(powerWindow time.Duration, tanPhiWindows []time.Duration, threshold float64) *ReactiveEstimator {
+	return &ReactiveEstimator{PowerWindow: powerWindow, TanPhiWindows: tanPhiWindows, Threshold: threshold}
+}
+
+// Add the energy indexes of a frame, timed by the meter clock when provided
+func (estimator *ReactiveEstimator) Add(frame Frame) {
+	measurement := frame.Measurement()
+	active, ok := measurement.Get(ActiveEnergy, Imported, 0, "")
+	if !ok {
+		return
+	}
+	point := reactivePoint{time: frame.Time, active: active}
+	if !measurement.MeterTime.IsZero() {
+		point.time = measurement.MeterTime
+	}
+	for i, quadrant := range Quadrants {
+		if point.reactive[i], ok = measurement.Get(ReactiveEnergy, NoDirection, 0, quadrant); !ok {
+			return
+		}
+	}
+
+	if last := len(estimator.recent) - 1; last >= 0 {
+		previous := estimator.recent[last]
+		switch {
+		case !point.after(previous):
+			// A lower index or time comes from a replaced meter or a clock change, previous points are meaningless
+			estimator.recent, estimator.history = nil, nil
+		case point.time.Equal(previous.time):
+			// Frames within the same second of the meter clock
+			return
+		}
+	}
+
+	estimator.recent = append(estimator.recent, point)
+	for len(estimator.recent) > 2 && !estimator.recent[1].time.After(point.time.Add(-estimator.PowerWindow)) {
+		estimator.recent = estimator.recent[1:]
+	}
+
+	longest, spacing := estimator.historyRange()
+	if len(estimator.history) == 0 || point.time.Sub(estimator.history[len(estimator.history)-1].time) >= spacing {
+		estimator.history = append(estimator.history, point)
+	}
+	for len(estimator.history) > 2 && !estimator.history[1].time.After(point.time.Add(-longest)) {
+		estimator.history = estimator.history[1:]
+	}
+}
+
+// Whether a point can follow another one, with greater or equal indexes and time
+func (point reactivePoint) after(previous reactivePoint) bool {
+	if point.time.Before(previous.time) || point.active < previous.active {
+		return false
+	}
+	for i := range point.reactive {
+		if point.reactive[i] < previous.reactive[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Longest tan φ window, and spacing of sampled frames keeping 60 points in the shortest window
+func (estimator *ReactiveEstimator) historyRange() (time.Duration, time.Duration) {
+	if len(estimator.TanPhiWindows) == 0 {
+		return 0, 0
+	}
+	windows := append([]time.Duration{}, estimator.TanPhiWindows...)
+	sort.Slice(windows, func(i, j int) bool { return windows[i] < windows[j] })
+	return windows[len(windows)-1], windows[0] / 60
+}
+
+// Estimate returns reactive power and tan φ of covered windows, false when none is covered
+func (estimator *ReactiveEstimator) Estimate() (ReactiveEstimate, bool) {
+	estimate := ReactiveEstimate{Threshold: estimator.Threshold}
+	if len(estimator.recent) == 0 {
+		return estimate, false
+	}
+	last := estimator.recent[len(estimator.recent)-1]
+
+	if first, ok := windowStart(estimator.recent, last, estimator.PowerWindow); ok {
+		elapsed := float64(last.time.Sub(first.time))
+		for i := range Quadrants {
+			estimate.Power[i] = (last.reactive[i] - first.reactive[i]) * float64(time.Hour) / elapsed
+		}
+		estimate.HasPower = true
+	}
+
+	for _, window := range estimator.TanPhiWindows {
+		first, ok := windowStart(estimator.history, last, window)
+		if !ok || last.active <= first.active {
+			continue
+		}
+		value := (last.reactive[0] - first.reactive[0]) / (last.active - first.active)
+		estimate.TanPhi = append(estimate.TanPhi, TanPhi{Window: window, Value: value, Exceeded: value > estimator.Threshold})
+	}
+	return estimate, estimate.HasPower || len(estimate.TanPhi) > 0
+}
+
+// Return the last point at or before the window start, or the oldest one, false when points do not cover half of the window
+func windowStart(points []reactivePoint, last reactivePoint, window time.Duration) (reactivePoint, bool) {
+	if len(points) == 0 {
+		return reactivePoint{}, false
+	}
+	start := last.time.Add(-window)
+	first := points[0]
+	for _, point := range points[1:] {
+		if point.time.After(start) {
+			break
+		}
+		first = point
+	}
+	elapsed := last.time.Sub(first.time)
+	return first, elapsed > 0 && elapsed >= window/2
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	readerRetryDelay = 5 * time.Second
+	readerErrorsSize = 16
+)
+
+// Reader object to continuously read and decode frames from a connector
+type Reader struct {
+	connector  LinkyConnector
+	open       func(connector LinkyConnector) (io.ReadCloser, error)
+	retryDelay time.Duration
+	frames     chan Frame
+	errors     chan error
+	once       sync.Once
+}
+
+// NewReader method to construct Reader, the connector mode is auto detected when not set
+func NewReader(connector LinkyConnector) *Reader {
+	return &Reader{
+		connector:  connector,
+		open:       LinkyConnector.open,
+		retryDelay: readerRetryDelay,
+		frames:     make(chan Frame),
+		errors:     make(chan error, readerErrorsSize),
+	}
+}
+
+// Frames starts reading on first call and returns the channel of decoded frames, closed when the context is done
+func (reader *Reader) Frames(ctx context.Context) <-chan Frame {
+	reader.once.Do(func() { go reader.run(ctx) })
+	return reader.frames
+}
+
+// Errors returns the channel of reading errors, errors are dropped when nobody listens
+func (reader *Reader) Errors() <-chan error {
+	return reader.errors
+}
+
+// ReadFrame reads a single frame from the connector, its mode is auto detected when not set
+func ReadFrame(ctx context.Context, connector LinkyConnector) (Frame, error) {
+	return NewReader(connector).first(ctx)
+}
+
+// Return the first frame or the first error
+func (reader *Reader) first(ctx context.Context) (Frame, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	select {
+	case frame, ok := <-reader.Frames(ctx):
+		if !ok {
+			return Frame{}, ctx.Err()
+		}
+		return frame, nil
+	case err := <-reader.errors:
+		return Frame{}, err
+	case <-ctx.Done():
+		return Frame{}, ctx.Err()
+	}
+}
+
+// Read frames until the context is done, reopening the stream after each failure
+func (reader *Reader) run(ctx context.Context) {
+	defer close(reader.frames)
+
+	for ctx.Err() == nil {
+		if err := reader.read(ctx); err != nil && ctx.Err() == nil {
+			log.Errorf("Failed to read %s : %s", reader.connector.Device, err)
+			reader.report(err)
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(reader.retryDelay):
+			}
+		}
+	}
+}
+
+// Open the stream and send decoded frames until an error occurs
+func (reader *Reader) read(ctx context.Context) error {
+	if reader.connector.Mode == (LinkyMode{}) {
+		if err := reader.connector.Detect(ctx); err != nil {
+			return err
+		}
+	}
+
+	stream, err := reader.open(reader.connector)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	// Close the stream to unblock the pending read when the context is done
+	stop := closeOnDone(ctx, stream)
+	defer stop()
+
+	scanner := newFrameScanner(stream)
+	for {
+		raw, lines, err := scanner.next()
+		if err != nil {
+			return err
+		}
+
+		// A corrupted data set could switch loads or record wrong indexes, the whole frame is dropped
+		frame := newFrame(reader.connector.Mode, raw, lines)
+		if err := frame.Verify(); err != nil {
+			log.Warnf("Frame of %s dropped : %s", reader.connector.Device, err)
+			reader.report(err)
+			continue
+		}
+
+		select {
+		case reader.frames <- frame:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Send an error without blocking the reading
+func (reader *Reader) report(err error) {
+	select {
+	case reader.errors <- err:
+	default:
+	}
+}
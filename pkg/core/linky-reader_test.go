@@ -0,0 +1,308 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Encode data sets in a frame, from STX to ETX, with their computed checksums
+func encodeFrame(mode LinkyMode, dataSets []DataSet) []byte {
+	separator := " "
+	if mode == Standard {
+		separator = "\t"
+	}
+
+	content := []byte{startOfText}
+	for _, dataSet := range dataSets {
+		content = append(content, '\n')
+		content = append(content, dataSet.Label...)
+		if mode == Standard && dataSet.Timestamp != "" {
+			content = append(content, separator+dataSet.Timestamp...)
+		}
+		content = append(content, separator+dataSet.Value+separator...)
+		content = append(content, dataSet.checksum(mode), '\r')
+	}
+	return append(content, endOfText)
+}
+
+const historicalFrames = "PAPP 00750 ,\r\n\x03\x02\nADCO 031762120345 9\r\nOPTARIF HC.. <\r\nISOUSC 30 9\r\nHCHC 002345675 &\r\nHCHP 006662251 /\r\nPTEC HP..  \r\nIINST 011 Y\r\nPAPP 02530 +\r\nMOTDETAT 000000 B\r\x03\x02\nADCO 031762120345 9\r\nPAPP 02540 ,\r\x03"
+
+func TestFrameScannerSkipsPartialFrame(t *testing.T) {
+	// Given
+	scanner := newFrameScanner(strings.NewReader(historicalFrames))
+
+	// When
+	_, values, err := scanner.next()
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if len(values) != 9 {
+		t.Fatalf("got %d data sets, want %d", len(values), 9)
+	}
+	if values[0][0] != "ADCO" || values[8][0] != "MOTDETAT" {
+		t.Errorf("got first %s and last %s data sets", values[0][0], values[8][0])
+	}
+}
+
+func TestFrameScannerChainsFrames(t *testing.T) {
+	// Given
+	scanner := newFrameScanner(strings.NewReader(historicalFrames))
+
+	// When
+	scanner.next()
+	raw, values, err := scanner.next()
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if len(values) != 2 || values[1][1] != "02540" {
+		t.Errorf("got data sets %v", values)
+	}
+	if string(raw) != "ADCO 031762120345 9\r\nPAPP 02540 ,\r" {
+		t.Errorf("got raw %q", raw)
+	}
+	if _, _, err := scanner.next(); err != io.EOF {
+		t.Errorf("got error %v, want EOF", err)
+	}
+}
+
+func TestReaderFrames(t *testing.T) {
+	// Given
+	reader := NewReader(LinkyConnector{Mode: Historical})
+	reader.open = func(LinkyConnector) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewBufferString(historicalFrames)), nil
+	}
+	reader.retryDelay = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// When
+	frames := reader.Frames(ctx)
+	first := <-frames
+	second := <-frames
+	err := <-reader.Errors()
+	cancel()
+
+	// Then
+	if first.Historical == nil || first.Standard != nil {
+		t.Fatal("frame not decoded in historical mode")
+	}
+	if first.Historical.Hchp != 6662251 || first.Historical.Papp != 2530 {
+		t.Errorf("got HCHP %d and PAPP %d", first.Historical.Hchp, first.Historical.Papp)
+	}
+	if second.Historical.Papp != 2540 {
+		t.Errorf("got PAPP %d, want %d", second.Historical.Papp, 2540)
+	}
+	if err != io.EOF {
+		t.Errorf("got error %v, want EOF", err)
+	}
+	for range frames {
+	}
+}
+
+func TestReaderFirstTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name    string
+		content string
+		papp    int32
+		err     error
+	}{
+		{"frame", historicalFrames, 2530, nil},
+		{"no frame", "\nPAPP 02530 *\r", 0, io.EOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := NewReader(LinkyConnector{Mode: Historical})
+			reader.open = func(LinkyConnector) (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewBufferString(tt.content)), nil
+			}
+
+			// When
+			frame, err := reader.first(context.Background())
+
+			// Then
+			if err != tt.err {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if err == nil && frame.Historical.Papp != tt.papp {
+				t.Errorf("got PAPP %d, want %d", frame.Historical.Papp, tt.papp)
+			}
+		})
+	}
+}
+
+func TestReadFrameNetwork(t *testing.T) {
+	// Given
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("\x02\nADSC\t031762120345\t/\r\nSINSTS\t00250\tM\r\x03\x02\nADSC\t031762120345\t/\r\nSINSTS\t00260\tN\r\x03"))
+			conn.Close()
+		}
+	}()
+	connector := LinkyConnector{Device: "tcp://" + listener.Addr().String()}
+
+	// When
+	frame, err := ReadFrame(context.Background(), connector)
+
+	// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !connector.IsNetwork() || frame.Mode != Standard || frame.Standard.Sinsts != 250 {
+		t.Errorf("got mode %s and frame %+v", frame.Mode, frame.Standard)
+	}
+}
+
+func TestParseDataSetsTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name     string
+		mode     LinkyMode
+		raw      string
+		expected DataSet
+	}{
+		{"historical", Historical, "\nPAPP 02530 +\r\n", DataSet{Label: "PAPP", Value: "02530", Checksum: "+"}},
+		{"historical space checksum", Historical, "\nPTEC HP..  \r\n", DataSet{Label: "PTEC", Value: "HP..", Checksum: " "}},
+		{"standard spaces", Standard, "\nLTARF\t  HEURE  PLEINE  \t9\r\n", DataSet{Label: "LTARF", Value: "  HEURE  PLEINE  ", Checksum: "9"}},
+		{"standard timestamp", Standard, "\nSMAXSN\tE220101123000\t05020\t7\r\n", DataSet{Label: "SMAXSN", Timestamp: "E220101123000", Value: "05020", Checksum: "7"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			dataSets := parseDataSets(tt.mode, []byte(tt.raw))
+
+			// Then
+			if len(dataSets) != 1 || dataSets[0] != tt.expected {
+				t.Errorf("got %+v, want %+v", dataSets, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFrameValuesTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name     string
+		mode     LinkyMode
+		raw      string
+		expected Value
+	}{
+		{"historical numeric", Historical, "\nPAPP 02530 +\r\n", Value{Label: "PAPP", Value: int64(2530), Unit: VoltAmpere}},
+		{"historical text", Historical, "\nADCO 031762120345 9\r\n", Value{Label: "ADCO", Value: "031762120345"}},
+		{"standard text", Standard, "\nPRM\t01234567890123\t9\r\n", Value{Label: "PRM", Value: "01234567890123"}},
+		{"standard horodate", Standard, "\nSMAXSN\tE220101123000\t05020\t7\r\n", Value{Label: "SMAXSN", Value: int64(5020), Unit: VoltAmpere, Time: time.Date(2022, 1, 1, 12, 30, 0, 0, time.FixedZone("", 2*3600))}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			values := Frame{Mode: tt.mode, DataSets: parseDataSets(tt.mode, []byte(tt.raw))}.Values()
+
+			// Then
+			if len(values) != 1 || values[0].Label != tt.expected.Label || values[0].Value != tt.expected.Value || values[0].Unit != tt.expected.Unit || !values[0].Time.Equal(tt.expected.Time) {
+				t.Errorf("got %+v, want %+v", values, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDataSetValidTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name     string
+		mode     LinkyMode
+		raw      string
+		expected bool
+	}{
+		{"historical", Historical, "\nPAPP 02530 +\r\n", true},
+		{"historical space checksum", Historical, "\nPTEC HP..  \r\n", true},
+		{"historical corrupted", Historical, "\nPTEC HC..  \r\n", false},
+		{"standard spaces", Standard, "\nLTARF\t  HEURE  PLEINE  \t!\r\n", true},
+		{"standard timestamp", Standard, "\nSMAXSN\tE220101123000\t05020\t]\r\n", true},
+		{"standard corrupted", Standard, "\nSINSTS\t00350\tM\r\n", false},
+		{"no checksum", Standard, "\nSINSTS\t00250\r\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			dataSets := parseDataSets(tt.mode, []byte(tt.raw))
+
+			// Then
+			if len(dataSets) != 1 || dataSets[0].Valid(tt.mode) != tt.expected {
+				t.Errorf("got %+v valid %t, want %t", dataSets, !tt.expected, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReaderDropsCorruptedFrames(t *testing.T) {
+	// Given
+	reader := NewReader(LinkyConnector{Mode: Standard})
+	reader.open = func(LinkyConnector) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewBufferString("\x02\nSINSTS\t00350\tM\r\x03\x02\nSINSTS\t00250\tM\r\x03")), nil
+	}
+	reader.retryDelay = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// When
+	frame := <-reader.Frames(ctx)
+	err := <-reader.Errors()
+
+	// Then
+	if frame.Standard.Sinsts != 250 {
+		t.Errorf("got SINSTS %d, want %d", frame.Standard.Sinsts, 250)
+	}
+	if err == nil || err.Error() != "Invalid checksum of data set SINSTS" {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestDetectHonoursContext(t *testing.T) {
+	// Given
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		// Accept without ever sending a frame
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+	connector := LinkyConnector{Device: "tcp://" + listener.Addr().String()}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// When
+	start := time.Now()
+	err = connector.Detect(ctx)
+
+	// Then
+	if err == nil || time.Since(start) > time.Second {
+		t.Errorf("got error %v after %s", err, time.Since(start))
+	}
+}
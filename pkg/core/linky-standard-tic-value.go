@@ -1,6 +1,7 @@
 package core
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -102,6 +103,8 @@ type StandardTicValue struct {
 	Njourfnd                           int8      // Numéro du prochain jour calendrier fournisseur
 	Pjourfnd                           string    // Profil du prochain jour calendrier fournisseur
 	Ppointe                            string    // Profil du prochain jour de pointe
+
+	labels map[string]bool // Labels of received data sets
 }
 
 // Parse parameter with name and value
@@ -109,6 +112,10 @@ func (tic *StandardTicValue) ParseParam(name string, values []string) {
 	if len(values) == 0 {
 		return
 	}
+	if tic.labels == nil {
+		tic.labels = make(map[string]bool)
+	}
+	tic.labels[strings.ToLower(name)] = true
 
 	switch strings.ToLower(name) {
 	case "adsc":
@@ -118,7 +125,8 @@ func (tic *StandardTicValue) ParseParam(name string, values []string) {
 		tic.Vtic = values[0]
 		break
 	case "date":
-		tic.parseDate(values[1])
+		// The horodate is followed by an empty value
+		tic.parseDate(values[0])
 		break
 	case "ngtf":
 		tic.Ngtf = values[0]
@@ -326,8 +334,8 @@ func (tic *StandardTicValue) ParseParam(name string, values []string) {
 		val, _ := strconv.ParseUint(values[1], 10, 16)
 		tic.Umoy3 = int16(val)
 		break
-	case "status":
-		val, _ := strconv.ParseInt(values[0], 10, 64)
+	case "stge":
+		val, _ := strconv.ParseUint(values[0], 16, 32)
 		tic.parseStatus(int64(val))
 		break
 	case "dpm1":
@@ -390,6 +398,14 @@ func (tic *StandardTicValue) ParseParam(name string, values []string) {
 
 // Parse date from Tic value
 func (values *StandardTicValue) parseDate(value string) {
+	values.Date, _ = parseHorodate(value)
+}
+
+// Parse an horodate, a season letter (H for winter, E for summer, lower case when degraded) and YYMMDDhhmmss
+func parseHorodate(value string) (time.Time, error) {
+	if len(value) < 13 {
+		return time.Time{}, fmt.Errorf("Invalid horodate : %s", value)
+	}
 	season := strings.ToLower(value[0:1])
 	if season == "h" {
 		value = value + "+01"
@@ -397,8 +413,7 @@ func (values *StandardTicValue) parseDate(value string) {
 		value = value + "+02"
 	}
 
-	val, _ := time.Parse("060102150405-07", value[1:])
-	values.Date = val
+	return time.Parse("060102150405-07", value[1:])
 }
 
 // Parse TIC Status information into real status representation
@@ -408,7 +423,7 @@ func (values *StandardTicValue) parseStatus(value int64) {
 	// Bit 0
 	values.DryContactStatus = convertStatusToUint(string(binaries[31]))
 	// Bit 1 to 3
-	values.CutOffDeviceStatus = convertStatusToUint(binaries[28:30])
+	values.CutOffDeviceStatus = convertStatusToUint(binaries[28:31])
 	// Bit 4
 	values.LinkyTerminalShieldStatus = convertStatusToUint(string(binaries[27]))
 	// Bit 5 unused
@@ -421,28 +436,28 @@ func (values *StandardTicValue) parseStatus(value int64) {
 	// Bit 9
 	values.EnergyDirectionStatus = convertStatusToUint(string(binaries[22]))
 	// Bit 10 to 13
-	values.ContractTypePriceStatus = convertStatusToUint(binaries[18:21])
+	values.ContractTypePriceStatus = convertStatusToUint(binaries[18:22])
 	// Bit 14 to 15
-	values.ContractTypePriceDistributorStatus = convertStatusToUint(binaries[16:17])
+	values.ContractTypePriceDistributorStatus = convertStatusToUint(binaries[16:18])
 	// Bit 16
 	values.ClockStatus = convertStatusToUint(string(binaries[15]))
 	// Bit 17
 	values.TicStatus = convertStatusToUint(string(binaries[14]))
 	// Bit 18 unused
 	// Bit 19 to 20
-	values.EuridisLinkStatus = convertStatusToUint(binaries[11:12])
+	values.EuridisLinkStatus = convertStatusToUint(binaries[11:13])
 	// Bit 21 to 22
-	values.CPLStatus = convertStatusToUint(binaries[9:10])
+	values.CPLStatus = convertStatusToUint(binaries[9:11])
 	// Bit 23
 	values.CPLSyncStatus = convertStatusToUint(string(binaries[8]))
 	// Bit 24 to 25
-	values.TempoContractColorStatus = convertStatusToUint(binaries[6:7])
+	values.TempoContractColorStatus = convertStatusToUint(binaries[6:8])
 	// Bit 26 to 27
-	values.TempoContractNextDayColorStatus = convertStatusToUint(binaries[4:5])
+	values.TempoContractNextDayColorStatus = convertStatusToUint(binaries[4:6])
 	// Bit 28 to 29
-	values.MovingPeakNoticeStatus = convertStatusToUint(binaries[2:3])
+	values.MovingPeakNoticeStatus = convertStatusToUint(binaries[2:4])
 	// Bit 30 to 31
-	values.MovingPeakStatus = convertStatusToUint(binaries[0:1])
+	values.MovingPeakStatus = convertStatusToUint(binaries[0:2])
 }
 
 // Parse TIC Relais information into real representation
@@ -490,3 +505,17 @@ func convertRelayValue(relay byte) int8 {
 	}
 	return 1
 }
+
+// Has returns true when the data set label was received
+func (tic StandardTicValue) Has(name string) bool {
+	return tic.labels[strings.ToLower(name)]
+}
+
+// Parse all data lines of a frame into Standard TIC
+func parseStandardTicValue(lines [][]string) *StandardTicValue {
+	values := StandardTicValue{}
+	for _, line := range lines {
+		values.ParseParam(line[0], line[1:])
+	}
+	return &values
+}
@@ -87,6 +87,38 @@ func TestParseParamTableDrivenRelais(t *testing.T) {
 	}
 }
 
+func TestParseParamTableDrivenStatus(t *testing.T) {
+	// Given, STGE is hexadecimal and its fields span several bits
+	var tests = []struct {
+		value string
+		want  [9]uint8 // Cut-off device, contract price, distributor price, Euridis, CPL, Tempo color, next day color, moving peak notice, moving peak
+	}{
+		{"00000000", [9]uint8{}},
+		{"0000000A", [9]uint8{5, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"00002400", [9]uint8{0, 9, 0, 0, 0, 0, 0, 0, 0}},
+		{"0000C000", [9]uint8{0, 0, 3, 0, 0, 0, 0, 0, 0}},
+		{"00580000", [9]uint8{0, 0, 0, 3, 2, 0, 0, 0, 0}},
+		{"0B000000", [9]uint8{0, 0, 0, 0, 0, 3, 2, 0, 0}},
+		{"90000000", [9]uint8{0, 0, 0, 0, 0, 0, 0, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			tic := StandardTicValue{}
+
+			// When
+			tic.ParseParam("STGE", []string{tt.value, "!"})
+
+			// Then
+			got := [9]uint8{tic.CutOffDeviceStatus, tic.ContractTypePriceStatus, tic.ContractTypePriceDistributorStatus, tic.EuridisLinkStatus, tic.CPLStatus,
+				tic.TempoContractColorStatus, tic.TempoContractNextDayColorStatus, tic.MovingPeakNoticeStatus, tic.MovingPeakStatus}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestParseDateTableDrivenRelais(t *testing.T) {
 	// Given
 	tic := StandardTicValue{}
@@ -114,6 +146,34 @@ func TestParseDateTableDrivenRelais(t *testing.T) {
 	}
 }
 
+func TestParseFrameDateTableDriven(t *testing.T) {
+	// Given, DATE has an horodate and an empty value, so the horodate is the first field before the checksum
+	var tests = []struct {
+		horodate string
+		want     int64
+	}{
+		{"H221113153547", 1668350147},
+		{"E230614120000", 1686736800},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.horodate, func(t *testing.T) {
+			content := encodeFrame(Standard, []DataSet{{Label: "DATE", Timestamp: tt.horodate}, {Label: "SINSTS", Value: "01700"}})
+
+			// When
+			frame, err := ParseFrame(Standard, content)
+
+			// Then
+			if err != nil {
+				t.Fatal(err)
+			}
+			if frame.Standard.Date.Unix() != tt.want {
+				t.Errorf("got %d, want %d", frame.Standard.Date.Unix(), tt.want)
+			}
+		})
+	}
+}
+
 func TestParseParam(t *testing.T) {
 	// Given
 	var values = []struct {
@@ -166,9 +226,9 @@ func TestParseParam(t *testing.T) {
 	}
 
 	// Then
-	// if tic.Date.UnixMilli() != 1668350147 {
-	// 	t.Errorf("Expected date millis %d but got %d", 1668350147, tic.Date.UnixMilli())
-	// }
+	if tic.Date.Unix() != 1668350147 {
+		t.Errorf("Expected date %d but got %d", 1668350147, tic.Date.Unix())
+	}
 	if tic.Relai1 != 1 {
 		t.Error("Relais 1 not good")
 	}
@@ -0,0 +1,183 @@
+package core
+
+import (
+	"math"
+	"time"
+)
+
+// Nominal voltage and EN 50160 limits of low voltage supply, in ratio of the nominal voltage
+const (
+	NominalVoltage        = 230.0
+	voltageTolerance      = 0.10 // 10 minutes averages must stay within ±10% for 95% of a week
+	voltageUnderTolerance = 0.15 // and all of them within +10% / -15%
+)
+
+// EN 50160 bands of a voltage
+const (
+	BandNormal             = "normal"              // Within ±10%
+	BandUndervoltage       = "undervoltage"        // Between -15% and -10%
+	BandSevereUndervoltage = "severe_undervoltage" // Below -15%
+	BandOvervoltage        = "overvoltage"         // Above +10%
+)
+
+// Voltage bands in export order
+var VoltageBands = []string{BandNormal, BandUndervoltage, BandSevereUndervoltage, BandOvervoltage}
+
+// Kinds of voltage episodes
+const (
+	Overvoltage  = "over"
+	Undervoltage = "under"
+)
+
+// Episodes object to hold the voltage episodes of a kind on a phase since the exporter started
+type Episodes struct {
+	Count    uint64        // Number of episodes, including the current one
+	Duration time.Duration // Total duration of episodes, including the current one
+	since    time.Time     // Start of the current episode, zero when none
+	last     time.Time     // Time of the last frame of the current episode
+}
+
+// Ongoing returns whether an episode is in progress
+func (episodes Episodes) Ongoing() bool {
+	return !episodes.since.IsZero()
+}
+
+// PhaseQuality object to hold the voltage quality of a phase
+type PhaseQuality struct {
+	Phase        int
+	Voltage      float64 // RMS voltage
+	Deviation    float64 // Deviation of the RMS voltage from the nominal voltage, in percent
+	Band         string  // EN 50160 band of the average voltage, of the RMS voltage when not provided
+	Overvoltage  Episodes
+	Undervoltage Episodes
+}
+
+// Quality object to hold the phase imbalance and voltage quality of a meter
+type Quality struct {
+	Phases           []PhaseQuality // Phases with a RMS voltage, in phase order
+	CurrentImbalance float64        // Largest deviation of a phase current from the average, in percent of the average
+	HasCurrent       bool           // Whether the three phase currents are provided with an average above 0
+	PowerImbalance   float64        // Largest deviation of a phase apparent power from the average, in percent of the average
+	HasPower         bool           // Whether the three phase apparent powers are provided with an average above 0
+}
+
+// VoltageEpisode object describing the start or the end of a voltage episode
+type VoltageEpisode struct {
+	Phase    int
+	Kind     string        // over or under
+	Started  bool          // Whether the episode starts, or ends
+	Voltage  float64       // RMS voltage of the frame
+	Duration time.Duration // Duration of an ended episode
+}
+
+// QualityTracker object to follow phase imbalance and voltage quality from successive frames
+type QualityTracker struct {
+	quality Quality
+	known   bool
+	phases  [3]PhaseQuality
+}
+
+// Add a frame, returning the voltage episodes started or ended by this frame
+func (tracker *QualityTracker) Add(frame Frame) []VoltageEpisode {
+	measurement := frame.Measurement()
+	quality := Quality{}
+	quality.CurrentImbalance, quality.HasCurrent = imbalance(measurement, Current, NoDirection)
+	quality.PowerImbalance, quality.HasPower = imbalance(measurement, ApparentPower, Imported)
+
+	var episodes []VoltageEpisode
+	for phase := 1; phase <= 3; phase++ {
+		voltage, ok := measurement.Get(Voltage, NoDirection, phase, "")
+		if !ok {
+			continue
+		}
+		state := &tracker.phases[phase-1]
+		state.Phase = phase
+		state.Voltage = voltage
+		state.Deviation = (voltage - NominalVoltage) / NominalVoltage * 100
+		state.Band = band(voltage)
+		if average, ok := measurement.Get(AverageVoltage, NoDirection, phase, ""); ok && average > 0 {
+			state.Band = band(average)
+		}
+
+		over := voltage > NominalVoltage*(1+voltageTolerance)
+		under := voltage < NominalVoltage*(1-voltageTolerance)
+		if episode, changed := follow(&state.Overvoltage, over, frame.Time); changed {
+			episode.Phase, episode.Kind, episode.Voltage = phase, Overvoltage, voltage
+			episodes = append(episodes, episode)
+		}
+		if episode, changed := follow(&state.Undervoltage, under, frame.Time); changed {
+			episode.Phase, episode.Kind, episode.Voltage = phase, Undervoltage, voltage
+			episodes = append(episodes, episode)
+		}
+		quality.Phases = append(quality.Phases, *state)
+	}
+
+	if len(quality.Phases) > 0 || quality.HasCurrent || quality.HasPower {
+		tracker.quality, tracker.known = quality, true
+	}
+	return episodes
+}
+
+// Quality returns phase imbalance and voltage quality, false until a frame provides voltages or phase values
+func (tracker *QualityTracker) Quality() (Quality, bool) {
+	return tracker.quality, tracker.known
+}
+
+// EN 50160 band of a voltage
+func band(voltage float64) string {
+	switch {
+	case voltage > NominalVoltage*(1+voltageTolerance):
+		return BandOvervoltage
+	case voltage < NominalVoltage*(1-voltageUnderTolerance):
+		return BandSevereUndervoltage
+	case voltage < NominalVoltage*(1-voltageTolerance):
+		return BandUndervoltage
+	default:
+		return BandNormal
+	}
+}
+
+// Follow the episodes of a kind, returning the episode started or ended by the frame
+func follow(episodes *Episodes, outside bool, at time.Time) (VoltageEpisode, bool) {
+	switch {
+	case outside && episodes.since.IsZero():
+		episodes.since, episodes.last = at, at
+		episodes.Count++
+		return VoltageEpisode{Started: true}, true
+	case outside:
+		if at.After(episodes.last) {
+			episodes.Duration += at.Sub(episodes.last)
+			episodes.last = at
+		}
+	case !episodes.since.IsZero():
+		// The episode lasts until the first frame within the tolerance
+		if at.After(episodes.last) {
+			episodes.Duration += at.Sub(episodes.last)
+		}
+		episode := VoltageEpisode{Duration: at.Sub(episodes.since)}
+		episodes.since = time.Time{}
+		return episode, true
+	}
+	return VoltageEpisode{}, false
+}
+
+// Largest deviation of the three phase values of a quantity from their average, in percent of the average
+func imbalance(measurement *LinkyMeasurement, quantity Quantity, direction Direction) (float64, bool) {
+	var values []float64
+	for phase := 1; phase <= 3; phase++ {
+		value, ok := measurement.Get(quantity, direction, phase, "")
+		if !ok {
+			return 0, false
+		}
+		values = append(values, value)
+	}
+	average := (values[0] + values[1] + values[2]) / 3
+	if average <= 0 {
+		return 0, false
+	}
+	var deviation float64
+	for _, value := range values {
+		deviation = math.Max(deviation, math.Abs(value-average))
+	}
+	return deviation / average * 100, true
+}
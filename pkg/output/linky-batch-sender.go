@@ -0,0 +1,105 @@
+package output
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	batchSenderRetryDelay    = time.Second
+	batchSenderMaxRetryDelay = time.Minute
+)
+
+// Error of a request that must not be retried
+type permanentError struct {
+	error
+}
+
+// Sender object to send batches in order, buffering them while the destination is unreachable
+type batchSender struct {
+	name    string
+	buffer  *batchBuffer
+	send    func(batch []byte) error
+	now     func() time.Time
+	delay   time.Duration // Delay before the next replay, 0 when the destination is reachable
+	retryAt time.Time     // Time of the next replay
+}
+
+// Construct a batch sender, buffering in memory when the directory is empty
+func newBatchSender(name string, directory string, maxBytes int64, send func(batch []byte) error) (*batchSender, error) {
+	buffer, err := newBatchBuffer(directory, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	return &batchSender{name: name, buffer: buffer, send: send, now: time.Now}, nil
+}
+
+// Send a batch after the buffered ones, the batch is buffered when the destination is unreachable,
+// the request is never retried here so that the output queue keeps draining during an outage
+func (sender *batchSender) sendBatch(batch []byte) {
+	if sender.buffer.empty() && sender.delay == 0 {
+		err := sender.send(batch)
+		if err == nil {
+			return
+		}
+		if _, permanent := err.(permanentError); permanent {
+			log.Errorf("%s rejected batch : %s", sender.name, err)
+			return
+		}
+		log.Warnf("%s unreachable, buffering batch : %s", sender.name, err)
+		sender.backOff()
+	}
+
+	if err := sender.buffer.add(batch); err != nil {
+		log.Errorf("Unable to buffer %s batch : %s", sender.name, err)
+	}
+	sender.replay()
+}
+
+// Send buffered batches in order, until the destination fails, nothing is sent before the backoff delay
+func (sender *batchSender) replay() {
+	if sender.delay > 0 && sender.now().Before(sender.retryAt) {
+		return
+	}
+
+	for {
+		batch, exists, err := sender.buffer.oldest()
+		if !exists {
+			sender.delay = 0
+			return
+		}
+		if err != nil {
+			// An unreadable batch would block the following ones forever
+			log.Errorf("Unable to read buffered %s batch, dropping it : %s", sender.name, err)
+		} else if err = sender.send(batch); err != nil {
+			if _, permanent := err.(permanentError); !permanent {
+				log.Debugf("%s still unreachable : %s", sender.name, err)
+				sender.backOff()
+				return
+			}
+			log.Errorf("%s rejected buffered batch : %s", sender.name, err)
+		}
+		if err := sender.buffer.removeOldest(); err != nil {
+			log.Errorf("Unable to remove buffered %s batch : %s", sender.name, err)
+			return
+		}
+	}
+}
+
+// Send buffered batches a last time, whatever the backoff delay
+func (sender *batchSender) close() {
+	sender.retryAt = time.Time{}
+	sender.replay()
+}
+
+// Double the delay before the next replay after a failure
+func (sender *batchSender) backOff() {
+	sender.delay *= 2
+	if sender.delay == 0 {
+		sender.delay = batchSenderRetryDelay
+	} else if sender.delay > batchSenderMaxRetryDelay {
+		sender.delay = batchSenderMaxRetryDelay
+	}
+	sender.retryAt = sender.now().Add(sender.delay)
+}
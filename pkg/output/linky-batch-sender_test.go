@@ -0,0 +1,71 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBatchSenderReplayDropsUnreadableBatch(t *testing.T) {
+	// Given
+	directory := t.TempDir()
+	if err := os.Symlink("missing", filepath.Join(directory, "00000000000000000000"+batchExtension)); err != nil {
+		t.Fatal(err)
+	}
+	var sent []string
+	sender, err := newBatchSender("test", directory, 1024, func(batch []byte) error {
+		sent = append(sent, string(batch))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.buffer.add([]byte("batch")); err != nil {
+		t.Fatal(err)
+	}
+
+	// When
+	sender.replay()
+
+	// Then
+	if len(sent) != 1 || sent[0] != "batch" {
+		t.Errorf("got %v sent, want [batch]", sent)
+	}
+	if !sender.buffer.empty() {
+		t.Error("buffer not emptied")
+	}
+}
+
+func TestBatchSenderBacksOff(t *testing.T) {
+	// Given
+	now := time.Date(2023, 6, 14, 12, 0, 0, 0, time.UTC)
+	var sent int
+	sender, err := newBatchSender("test", "", 1024, func(batch []byte) error {
+		sent++
+		return fmt.Errorf("unreachable")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender.now = func() time.Time { return now }
+
+	// When
+	sender.sendBatch([]byte("first"))
+	sender.sendBatch([]byte("second"))
+	sender.replay()
+	now = now.Add(batchSenderRetryDelay)
+	sender.replay()
+	sender.replay()
+	now = now.Add(batchSenderRetryDelay)
+	sender.replay()
+
+	// Then
+	if sent != 2 {
+		t.Errorf("got %d requests, want 2", sent)
+	}
+	if sender.delay != 2*batchSenderRetryDelay || sender.buffer.bytes != int64(len("firstsecond")) {
+		t.Errorf("got delay %s and %d buffered bytes", sender.delay, sender.buffer.bytes)
+	}
+}
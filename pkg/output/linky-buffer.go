@@ -0,0 +1,123 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Extension of batch files in a buffer directory
+const batchExtension = ".batch"
+
+// Buffer object to keep unsent batches in order, in memory or in a directory to survive restarts
+type batchBuffer struct {
+	directory string
+	maxBytes  int64
+	batches   [][]byte // Batches kept in memory, when there is no directory
+	files     []string // Batch files from the oldest, when there is a directory
+	sizes     []int64  // Size of each batch file
+	bytes     int64    // Total size of the buffered batches
+}
+
+// Construct a buffer, in memory when the directory is empty, batch files left by a previous run are kept
+func newBatchBuffer(directory string, maxBytes int64) (*batchBuffer, error) {
+	buffer := &batchBuffer{directory: directory, maxBytes: maxBytes}
+	if directory == "" {
+		return buffer, nil
+	}
+
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return nil, fmt.Errorf("Unable to create buffer directory : %s", err)
+	}
+	// Entries are sorted by name, so from the oldest batch
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read buffer directory : %s", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), batchExtension) {
+			continue
+		}
+		var size int64
+		if info, err := entry.Info(); err == nil {
+			size = info.Size()
+		}
+		buffer.files = append(buffer.files, filepath.Join(directory, entry.Name()))
+		buffer.sizes = append(buffer.sizes, size)
+		buffer.bytes += size
+	}
+	return buffer, nil
+}
+
+// Add a batch after the others, the oldest batches are dropped when the buffer is full
+func (buffer *batchBuffer) add(batch []byte) error {
+	if buffer.directory == "" {
+		buffer.batches = append(buffer.batches, batch)
+	} else {
+		// Write then rename so that a batch file is always complete
+		name := filepath.Join(buffer.directory, fmt.Sprintf("%020d%s", time.Now().UnixNano(), batchExtension))
+		if err := os.WriteFile(name+".tmp", batch, 0600); err != nil {
+			return err
+		}
+		if err := os.Rename(name+".tmp", name); err != nil {
+			return err
+		}
+		buffer.files = append(buffer.files, name)
+		buffer.sizes = append(buffer.sizes, int64(len(batch)))
+	}
+	buffer.bytes += int64(len(batch))
+
+	for buffer.bytes > buffer.maxBytes && !buffer.empty() {
+		log.Warn("Output buffer is full, dropping the oldest batch")
+		if err := buffer.removeOldest(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Return the oldest batch, false when the buffer is empty
+func (buffer *batchBuffer) oldest() ([]byte, bool, error) {
+	if buffer.directory == "" {
+		if len(buffer.batches) == 0 {
+			return nil, false, nil
+		}
+		return buffer.batches[0], true, nil
+	}
+
+	if len(buffer.files) == 0 {
+		return nil, false, nil
+	}
+	batch, err := os.ReadFile(buffer.files[0])
+	return batch, true, err
+}
+
+// Remove the oldest batch
+func (buffer *batchBuffer) removeOldest() error {
+	if buffer.directory == "" {
+		if len(buffer.batches) > 0 {
+			buffer.bytes -= int64(len(buffer.batches[0]))
+			buffer.batches = buffer.batches[1:]
+		}
+		return nil
+	}
+
+	if len(buffer.files) == 0 {
+		return nil
+	}
+	if err := os.Remove(buffer.files[0]); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	buffer.bytes -= buffer.sizes[0]
+	buffer.files, buffer.sizes = buffer.files[1:], buffer.sizes[1:]
+	return nil
+}
+
+// Return whether no batch is waiting
+func (buffer *batchBuffer) empty() bool {
+	return len(buffer.batches) == 0 && len(buffer.files) == 0
+}
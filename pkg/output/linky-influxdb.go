@@ -0,0 +1,188 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+const influxDBQueueSize = 256
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	stringEscaper      = strings.NewReplacer(`"`, `\"`, `\`, `\\`)
+)
+
+// InfluxDBOutput object to write frames as InfluxDB line protocol, batched and buffered while unreachable
+type InfluxDBOutput struct {
+	config config.InfluxDBConfig
+	client *http.Client
+	queue  *queue
+	lines  []string
+	sender *batchSender
+}
+
+// NewInfluxDBOutput method to construct InfluxDBOutput
+func NewInfluxDBOutput(influxDBConfig config.InfluxDBConfig) (*InfluxDBOutput, error) {
+	output := &InfluxDBOutput{
+		config: influxDBConfig,
+		client: &http.Client{Timeout: influxDBConfig.Timeout},
+	}
+	sender, err := newBatchSender("InfluxDB", influxDBConfig.BufferDirectory, influxDBConfig.BufferMaxBytes, output.write)
+	if err != nil {
+		return nil, err
+	}
+	output.sender = sender
+	output.queue = newTickingQueue("influxdb", influxDBQueueSize, influxDBConfig.FlushInterval, output.add, output.addEvent, output.flush)
+	return output, nil
+}
+
+// Publish implements Output
+func (output *InfluxDBOutput) Publish(meter string, frame core.Frame) {
+	output.queue.push(meter, frame)
+}
+
+// PublishEvent implements Output
+func (output *InfluxDBOutput) PublishEvent(event core.Event) {
+	output.queue.pushEvent(event)
+}
+
+// Close implements Output, pending frames are sent or buffered
+func (output *InfluxDBOutput) Close() error {
+	output.queue.close()
+	output.flush()
+	output.sender.close()
+	return nil
+}
+
+// Add a frame to the batch, sent when full
+func (output *InfluxDBOutput) add(meter string, frame core.Frame) {
+	line := lineProtocol(output.config.Measurement, meter, frame.Measurement())
+	if line == "" {
+		return
+	}
+	output.lines = append(output.lines, line)
+	if len(output.lines) >= output.config.BatchSize {
+		output.flush()
+	}
+}
+
+// Add an event to the batch, sent when full
+func (output *InfluxDBOutput) addEvent(event core.Event) {
+	output.lines = append(output.lines, eventLineProtocol(output.config.Measurement+"_event", event))
+	if len(output.lines) >= output.config.BatchSize {
+		output.flush()
+	}
+}
+
+// Send the batch, then the buffered ones
+func (output *InfluxDBOutput) flush() {
+	if len(output.lines) == 0 {
+		output.sender.replay()
+		return
+	}
+
+	batch := []byte(strings.Join(output.lines, "\n") + "\n")
+	output.lines = nil
+	output.sender.sendBatch(batch)
+}
+
+// Write a batch with the API of the configured version
+func (output *InfluxDBOutput) write(batch []byte) error {
+	query := url.Values{"precision": {"ns"}}
+	endpoint := strings.TrimRight(output.config.Url, "/")
+	if output.config.Version == 1 {
+		endpoint += "/write"
+		query.Set("db", output.config.Database)
+		if output.config.RetentionPolicy != "" {
+			query.Set("rp", output.config.RetentionPolicy)
+		}
+	} else {
+		endpoint += "/api/v2/write"
+		query.Set("org", output.config.Org)
+		query.Set("bucket", output.config.Bucket)
+	}
+
+	request, err := http.NewRequest(http.MethodPost, endpoint+"?"+query.Encode(), bytes.NewReader(batch))
+	if err != nil {
+		return permanentError{err}
+	}
+	request.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if output.config.Token != "" {
+		request.Header.Set("Authorization", "Token "+output.config.Token)
+	} else if output.config.Username != "" {
+		request.SetBasicAuth(output.config.Username, output.config.Password)
+	}
+
+	response, err := output.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode/100 == 2 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	err = fmt.Errorf("%s : %s", response.Status, strings.TrimSpace(string(body)))
+	if response.StatusCode == http.StatusBadRequest || response.StatusCode == http.StatusRequestEntityTooLarge {
+		return permanentError{err}
+	}
+	return err
+}
+
+// Build the line of a measurement, empty when it has no sample
+func lineProtocol(name string, meter string, measurement *core.LinkyMeasurement) string {
+	var fields []string
+	for _, sample := range measurement.Samples {
+		fields = append(fields, tagEscaper.Replace(sample.Key())+"="+strconv.FormatFloat(sample.Value, 'f', -1, 64))
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	for key, value := range map[string]string{"contract": measurement.Contract, "price_label": measurement.PriceLabel} {
+		if value != "" {
+			fields = append(fields, fmt.Sprintf(`%s="%s"`, key, stringEscaper.Replace(value)))
+		}
+	}
+	sort.Strings(fields)
+
+	return fmt.Sprintf("%s,meter=%s,mode=%s %s %d",
+		measurementEscaper.Replace(name),
+		tagEscaper.Replace(meter),
+		tagEscaper.Replace(measurement.Mode.String()),
+		strings.Join(fields, ","),
+		measurement.Time.UnixNano(),
+	)
+}
+
+// Build the line of an event, tagged with its meter and type
+func eventLineProtocol(name string, event core.Event) string {
+	fields := []string{fmt.Sprintf(`field="%s"`, stringEscaper.Replace(event.Field))}
+	for key, value := range map[string]string{"from": event.From, "to": event.To} {
+		if value != "" {
+			fields = append(fields, fmt.Sprintf(`%s="%s"`, key, stringEscaper.Replace(value)))
+		}
+	}
+	if event.Duration > 0 {
+		fields = append(fields, "duration_seconds="+strconv.FormatFloat(event.Duration, 'f', -1, 64))
+	}
+	sort.Strings(fields)
+
+	return fmt.Sprintf("%s,meter=%s,type=%s %s %d",
+		measurementEscaper.Replace(name),
+		tagEscaper.Replace(event.Meter),
+		tagEscaper.Replace(string(event.Type)),
+		strings.Join(fields, ","),
+		event.Time.UnixNano(),
+	)
+}
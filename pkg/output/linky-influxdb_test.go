@@ -0,0 +1,157 @@
+package output
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Start a server answering the given status codes in turn, then 204, and recording requests
+func influxDBServer(t *testing.T, statuses ...int) (*httptest.Server, func() []*http.Request, func() []string) {
+	var mutex sync.Mutex
+	var requests []*http.Request
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+		if len(requests) <= len(statuses) {
+			w.WriteHeader(statuses[len(requests)-1])
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	return server,
+		func() []*http.Request { mutex.Lock(); defer mutex.Unlock(); return requests },
+		func() []string { mutex.Lock(); defer mutex.Unlock(); return bodies }
+}
+
+func influxDBConfig(url string, version int) config.InfluxDBConfig {
+	return config.InfluxDBConfig{
+		Url: url, Version: version, Database: "energy", Username: "user", Password: "secret", Org: "home", Bucket: "energy", Token: "",
+		Measurement: "linky", BatchSize: 1, FlushInterval: time.Hour, Timeout: time.Second, BufferMaxBytes: 1024 * 1024,
+	}
+}
+
+func TestLineProtocol(t *testing.T) {
+	// Given
+	measurement := &core.LinkyMeasurement{Mode: core.Historical, Time: time.Unix(1, 5), Contract: `HC "1"`, Samples: []core.Sample{
+		{Quantity: core.ActiveEnergy, Direction: core.Imported, Index: "F1", Unit: core.WattHour, Value: 2345675},
+		{Quantity: core.Voltage, Phase: 1, Unit: core.Volt, Value: 229.5},
+	}}
+
+	// When
+	line := lineProtocol("linky power", "main meter", measurement)
+
+	// Then
+	expected := `linky\ power,meter=main\ meter,mode=historical active_energy_imported_f1=2345675,contract="HC \"1\"",voltage_phase1=229.5 1000000005`
+	if line != expected {
+		t.Errorf("got %s, want %s", line, expected)
+	}
+}
+
+func TestEventLineProtocol(t *testing.T) {
+	// Given
+	event := core.Event{Time: time.Unix(1, 5), Meter: "main meter", Type: core.OvervoltageEnded, Field: "voltage_phase2", To: "231", Duration: 12.5}
+
+	// When
+	line := eventLineProtocol("linky_event", event)
+
+	// Then
+	expected := `linky_event,meter=main\ meter,type=overvoltage_ended duration_seconds=12.5,field="voltage_phase2",to="231" 1000000005`
+	if line != expected {
+		t.Errorf("got %s, want %s", line, expected)
+	}
+}
+
+func TestInfluxDBOutputTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name          string
+		version       int
+		token         string
+		path          string
+		query         string
+		authorization string
+	}{
+		{"v1", 1, "", "/write", "db=energy&precision=ns", "Basic dXNlcjpzZWNyZXQ="},
+		{"v2", 2, "abc", "/api/v2/write", "bucket=energy&org=home&precision=ns", "Token abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests, bodies := influxDBServer(t)
+			influxDBConfig := influxDBConfig(server.URL, tt.version)
+			influxDBConfig.Token = tt.token
+			output, err := NewInfluxDBOutput(influxDBConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// When
+			output.Publish("main", historicalFrame(t))
+			output.Close()
+
+			// Then
+			if len(requests()) != 1 {
+				t.Fatalf("got %d requests, want 1", len(requests()))
+			}
+			request := requests()[0]
+			if request.URL.Path != tt.path || request.URL.RawQuery != tt.query || request.Header.Get("Authorization") != tt.authorization {
+				t.Errorf("unexpected request %s %s", request.URL, request.Header.Get("Authorization"))
+			}
+			if !strings.HasPrefix(bodies()[0], "linky,meter=main,mode=historical active_energy_imported=6662251,") {
+				t.Errorf("unexpected body %s", bodies()[0])
+			}
+		})
+	}
+}
+
+func TestInfluxDBOutputBuffer(t *testing.T) {
+	// Given
+	server, requests, bodies := influxDBServer(t, 503)
+	influxDBConfig := influxDBConfig(server.URL, 2)
+	influxDBConfig.BufferDirectory = t.TempDir()
+	output, err := NewInfluxDBOutput(influxDBConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := historicalFrame(t)
+
+	// When
+	output.Publish("main", frame)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(requests()) < 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	buffered, _ := os.ReadDir(influxDBConfig.BufferDirectory)
+	output.Close()
+
+	// Then
+	if len(buffered) != 1 {
+		t.Errorf("got %d buffered batches, want 1", len(buffered))
+	}
+	if len(requests()) != 2 || bodies()[1] != bodies()[0] {
+		t.Fatalf("got %d requests, want the batch replayed on close", len(requests()))
+	}
+	if !strings.HasSuffix(strings.TrimSpace(bodies()[1]), " "+strconv.FormatInt(frame.Time.UnixNano(), 10)) {
+		t.Errorf("original timestamp lost : %s", bodies()[1])
+	}
+	if remaining, _ := os.ReadDir(influxDBConfig.BufferDirectory); len(remaining) != 0 {
+		t.Errorf("got %d batches left in buffer", len(remaining))
+	}
+}
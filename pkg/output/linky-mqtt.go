@@ -0,0 +1,270 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	log "github.com/sirupsen/logrus"
+	"github.com/syberalexis/linky-exporter/pkg/broker"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+const (
+	mqttQueueSize  = 64
+	mqttAckTimeout = 10 * time.Second
+	mqttOnline     = "online"
+	mqttOffline    = "offline"
+)
+
+// MqttOutput object to publish frames to a MQTT broker, with Home Assistant discovery
+type MqttOutput struct {
+	config     config.MqttConfig
+	metrics    config.MetricsConfig // Meter identifiers are published as in metrics, aliased or hashed
+	client     mqtt.Client
+	queue      *queue
+	acks       chan mqttAck // Pending publications, checked by one worker
+	acksDone   chan struct{}
+	mutex      sync.Mutex
+	discovered map[string]bool // Sensors of meters whose discovery configurations were published since the last connection
+}
+
+// Publication waiting for its acknowledgement
+type mqttAck struct {
+	topic string
+	token mqtt.Token
+}
+
+// Home Assistant discovery configuration of a sensor
+type discoveryConfig struct {
+	Name              string          `json:"name"`
+	UniqueId          string          `json:"unique_id"`
+	StateTopic        string          `json:"state_topic"`
+	ValueTemplate     string          `json:"value_template"`
+	AvailabilityTopic string          `json:"availability_topic"`
+	UnitOfMeasurement string          `json:"unit_of_measurement,omitempty"`
+	DeviceClass       string          `json:"device_class,omitempty"`
+	StateClass        string          `json:"state_class,omitempty"`
+	Device            discoveryDevice `json:"device"`
+}
+
+// Home Assistant device grouping the sensors of a meter
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model"`
+}
+
+// NewMqttOutput method to construct MqttOutput, the connection is retried in background
+func NewMqttOutput(mqttConfig config.MqttConfig, metricsConfig config.MetricsConfig) *MqttOutput {
+	output := &MqttOutput{
+		config:     mqttConfig,
+		metrics:    metricsConfig,
+		acks:       make(chan mqttAck, mqttQueueSize),
+		acksDone:   make(chan struct{}),
+		discovered: make(map[string]bool),
+	}
+	go output.checkAcks()
+
+	output.client = broker.NewClient(mqttConfig.MqttConnection, func(options *mqtt.ClientOptions) {
+		options.SetWill(output.availabilityTopic(), mqttOffline, mqttConfig.Qos, true).SetOnConnectHandler(output.onConnect)
+	})
+	output.queue = newQueue("mqtt", mqttQueueSize, output.publish, output.publishEvent)
+
+	return output
+}
+
+// Publish implements Output
+func (output *MqttOutput) Publish(meter string, frame core.Frame) {
+	output.queue.push(meter, frame)
+}
+
+// PublishEvent implements Output
+func (output *MqttOutput) PublishEvent(event core.Event) {
+	output.queue.pushEvent(event)
+}
+
+// Close implements Output, the availability is set offline before disconnecting
+func (output *MqttOutput) Close() error {
+	output.queue.close()
+	if output.client.IsConnected() {
+		output.client.Publish(output.availabilityTopic(), output.config.Qos, true, mqttOffline).WaitTimeout(time.Second)
+	}
+	broker.Disconnect(output.client)
+	close(output.acks)
+	<-output.acksDone
+	return nil
+}
+
+// Announce availability and publish discovery configurations again on each connection
+func (output *MqttOutput) onConnect(client mqtt.Client) {
+	log.Infof("Connected to MQTT broker %s", output.config.Broker)
+	output.mutex.Lock()
+	output.discovered = make(map[string]bool)
+	output.mutex.Unlock()
+	client.Publish(output.availabilityTopic(), output.config.Qos, true, mqttOnline)
+}
+
+// Publish a frame as per label topics and a JSON state topic
+func (output *MqttOutput) publish(meter string, frame core.Frame) {
+	if !output.client.IsConnected() {
+		log.Debugf("MQTT broker not connected, frame of %s dropped", meter)
+		return
+	}
+	measurement := frame.Measurement()
+
+	if output.config.Discovery {
+		output.publishDiscovery(meter, measurement)
+	}
+
+	for _, dataSet := range frame.DataSets {
+		if strings.ContainsAny(dataSet.Label, "/+#") {
+			continue
+		}
+		value := strings.TrimSpace(dataSet.Value)
+		if core.IsIdentifier(dataSet.Label) {
+			value = output.metrics.MeterId(value)
+		}
+		output.send(output.meterTopic(meter, dataSet.Label), output.config.Retain, value)
+	}
+
+	payload, err := json.Marshal(stateOf(measurement, output.metrics.MeterId(measurement.MeterId)))
+	if err != nil {
+		log.Errorf("Failed to encode state of %s : %s", meter, err)
+		return
+	}
+	output.send(output.meterTopic(meter, "state"), output.config.Retain, payload)
+}
+
+// Publish an event as JSON to the event topic of its meter, events are not retained
+func (output *MqttOutput) publishEvent(event core.Event) {
+	if !output.client.IsConnected() {
+		log.Debugf("MQTT broker not connected, %s event of %s dropped", event.Type, event.Meter)
+		return
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Errorf("Failed to encode %s event of %s : %s", event.Type, event.Meter, err)
+		return
+	}
+	output.send(output.meterTopic(event.Meter, "event"), false, payload)
+}
+
+// Publish Home Assistant discovery configurations of the samples of a meter not announced yet, as some samples are
+// only present in later frames
+func (output *MqttOutput) publishDiscovery(meter string, measurement *core.LinkyMeasurement) {
+	device := discoveryDevice{
+		Identifiers:  []string{"linky_" + meter},
+		Name:         "Linky " + meter,
+		Manufacturer: "Enedis",
+		Model:        fmt.Sprintf("Linky (%s mode)", measurement.Mode),
+	}
+
+	for _, sample := range measurement.Samples {
+		key := sample.Key()
+		output.mutex.Lock()
+		discovered := output.discovered[meter+"/"+key]
+		output.discovered[meter+"/"+key] = true
+		output.mutex.Unlock()
+		if discovered {
+			continue
+		}
+
+		deviceClass, stateClass := discoveryClasses(sample)
+		payload, err := json.Marshal(discoveryConfig{
+			Name:              strings.ReplaceAll(key, "_", " "),
+			UniqueId:          fmt.Sprintf("linky_%s_%s", meter, key),
+			StateTopic:        output.meterTopic(meter, "state"),
+			ValueTemplate:     fmt.Sprintf("{{ value_json.%s }}", key),
+			AvailabilityTopic: output.availabilityTopic(),
+			UnitOfMeasurement: string(sample.Unit),
+			DeviceClass:       deviceClass,
+			StateClass:        stateClass,
+			Device:            device,
+		})
+		if err != nil {
+			log.Errorf("Failed to encode discovery of %s : %s", key, err)
+			continue
+		}
+		output.send(fmt.Sprintf("%s/sensor/linky_%s/%s/config", output.config.DiscoveryPrefix, meter, key), true, payload)
+	}
+}
+
+// Send a message without waiting for the acknowledgement, it is checked by the acknowledgement worker
+func (output *MqttOutput) send(topic string, retain bool, payload interface{}) {
+	output.acks <- mqttAck{topic: topic, token: output.client.Publish(topic, output.config.Qos, retain, payload)}
+}
+
+// Log the failed publications, in publication order, until the output is closed
+func (output *MqttOutput) checkAcks() {
+	defer close(output.acksDone)
+	for ack := range output.acks {
+		if !ack.token.WaitTimeout(mqttAckTimeout) {
+			log.Warnf("No acknowledgement of %s after %s", ack.topic, mqttAckTimeout)
+		} else if ack.token.Error() != nil {
+			log.Errorf("Failed to publish %s : %s", ack.topic, ack.token.Error())
+		}
+	}
+}
+
+// Topic announcing the exporter availability
+func (output *MqttOutput) availabilityTopic() string {
+	return output.config.TopicPrefix + "/availability"
+}
+
+// Topic of a meter
+func (output *MqttOutput) meterTopic(meter string, name string) string {
+	return fmt.Sprintf("%s/%s/%s", output.config.TopicPrefix, meter, name)
+}
+
+// Build the JSON state of a measurement with the published meter identifier, samples are keyed by Sample.Key
+func stateOf(measurement *core.LinkyMeasurement, meterId string) map[string]interface{} {
+	state := map[string]interface{}{
+		"time":     measurement.Time.Format(time.RFC3339),
+		"mode":     measurement.Mode.String(),
+		"meter_id": meterId,
+	}
+	if !measurement.MeterTime.IsZero() {
+		state["meter_time"] = measurement.MeterTime.Format(time.RFC3339)
+	}
+	for key, value := range map[string]string{
+		"contract":     measurement.Contract,
+		"price_label":  measurement.PriceLabel,
+		"tariff_index": measurement.TariffIndex,
+	} {
+		if value != "" {
+			state[key] = value
+		}
+	}
+	for _, sample := range measurement.Samples {
+		state[sample.Key()] = sample.Value
+	}
+	return state
+}
+
+// Home Assistant device and state classes of a sample
+func discoveryClasses(sample core.Sample) (string, string) {
+	switch sample.Unit {
+	case core.WattHour:
+		return "energy", "total_increasing"
+	case core.VarHour:
+		return "", "total_increasing"
+	case core.Ampere:
+		return "current", "measurement"
+	case core.Volt:
+		return "voltage", "measurement"
+	case core.VoltAmpere:
+		return "apparent_power", "measurement"
+	case core.Watt:
+		return "power", "measurement"
+	case core.KiloVoltAmpere, core.Minute:
+		return "", "measurement"
+	default:
+		return "", ""
+	}
+}
@@ -0,0 +1,173 @@
+package output
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	broker "github.com/mochi-co/mqtt/server"
+	"github.com/mochi-co/mqtt/server/events"
+	"github.com/mochi-co/mqtt/server/listeners"
+	"github.com/syberalexis/linky-exporter/internal/tictest"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Start an embedded broker recording published messages by topic
+func startBroker(t *testing.T) (string, func(topic string) (string, bool)) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	var mutex sync.Mutex
+	messages := make(map[string]string)
+	server := broker.NewServer(nil)
+	server.Events.OnMessage = func(client events.Client, packet events.Packet) (events.Packet, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		messages[packet.TopicName] = string(packet.Payload)
+		return packet, nil
+	}
+	if err := server.AddListener(listeners.NewTCP("test", address), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	// Wait for a message, as publishing is asynchronous
+	wait := func(topic string) (string, bool) {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			mutex.Lock()
+			message, exists := messages[topic]
+			mutex.Unlock()
+			if exists {
+				return message, true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return "", false
+	}
+	return "tcp://" + address, wait
+}
+
+// Historical frame of a base meter, received now
+func historicalFrame(t *testing.T) core.Frame {
+	return tictest.Frame(t, core.Historical, time.Now(), "ADCO 031762120345", "OPTARIF BASE", "ISOUSC 30", "BASE 006662251", "IINST 011", "PAPP 02530")
+}
+
+func TestMqttOutput(t *testing.T) {
+	// Given
+	address, wait := startBroker(t)
+	mqttConfig := config.MqttConfig{MqttConnection: config.MqttConnection{Broker: address, ClientId: "test"}, TopicPrefix: "linky", Discovery: true, DiscoveryPrefix: "homeassistant"}
+	output := NewMqttOutput(mqttConfig, config.MetricsConfig{})
+	defer output.Close()
+	if _, ok := wait("linky/availability"); !ok {
+		t.Fatal("not connected")
+	}
+
+	// When
+	output.Publish("main", historicalFrame(t))
+
+	// Then
+	if value, _ := wait("linky/main/PAPP"); value != "02530" {
+		t.Errorf("got PAPP %q, want 02530", value)
+	}
+
+	state, _ := wait("linky/main/state")
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(state), &values); err != nil {
+		t.Fatal(err)
+	}
+	if values["active_energy_imported"] != 6662251.0 || values["apparent_power_imported"] != 2530.0 || values["contract"] != "BASE" {
+		t.Errorf("unexpected state %s", state)
+	}
+
+	discovery, _ := wait("homeassistant/sensor/linky_main/active_energy_imported/config")
+	var sensor discoveryConfig
+	if err := json.Unmarshal([]byte(discovery), &sensor); err != nil {
+		t.Fatal(err)
+	}
+	if sensor.DeviceClass != "energy" || sensor.StateClass != "total_increasing" || sensor.UnitOfMeasurement != "Wh" ||
+		sensor.StateTopic != "linky/main/state" || sensor.ValueTemplate != "{{ value_json.active_energy_imported }}" {
+		t.Errorf("unexpected discovery %s", discovery)
+	}
+}
+
+func TestMqttOutputLateDiscovery(t *testing.T) {
+	// Given
+	address, wait := startBroker(t)
+	mqttConfig := config.MqttConfig{MqttConnection: config.MqttConnection{Broker: address, ClientId: "test"}, TopicPrefix: "linky", Discovery: true, DiscoveryPrefix: "homeassistant"}
+	output := NewMqttOutput(mqttConfig, config.MetricsConfig{})
+	defer output.Close()
+	if _, ok := wait("linky/availability"); !ok {
+		t.Fatal("not connected")
+	}
+	output.Publish("main", tictest.Frame(t, core.Historical, time.Now(), "ADCO 031762120345", "OPTARIF BASE", "BASE 006662251"))
+	if _, ok := wait("homeassistant/sensor/linky_main/active_energy_imported/config"); !ok {
+		t.Fatal("first frame not discovered")
+	}
+
+	// When
+	output.Publish("main", historicalFrame(t))
+
+	// Then
+	if _, ok := wait("homeassistant/sensor/linky_main/apparent_power_imported/config"); !ok {
+		t.Error("sample of a later frame not discovered")
+	}
+}
+
+func TestMqttOutputMeterAlias(t *testing.T) {
+	// Given
+	address, wait := startBroker(t)
+	mqttConfig := config.MqttConfig{MqttConnection: config.MqttConnection{Broker: address, ClientId: "test"}, TopicPrefix: "linky"}
+	output := NewMqttOutput(mqttConfig, config.MetricsConfig{MeterAliases: map[string]string{"031762120345": "home"}})
+	defer output.Close()
+	if _, ok := wait("linky/availability"); !ok {
+		t.Fatal("not connected")
+	}
+
+	// When
+	output.Publish("main", historicalFrame(t))
+
+	// Then
+	if value, _ := wait("linky/main/ADCO"); value != "home" {
+		t.Errorf("got ADCO %q, want home", value)
+	}
+	state, _ := wait("linky/main/state")
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(state), &values); err != nil {
+		t.Fatal(err)
+	}
+	if values["meter_id"] != "home" {
+		t.Errorf("got meter_id %v, want home", values["meter_id"])
+	}
+}
+
+func TestMqttOutputAvailability(t *testing.T) {
+	// Given
+	address, wait := startBroker(t)
+	output := NewMqttOutput(config.MqttConfig{MqttConnection: config.MqttConnection{Broker: address, ClientId: "test"}, TopicPrefix: "linky"}, config.MetricsConfig{})
+	if value, _ := wait("linky/availability"); value != "online" {
+		t.Fatalf("got availability %q, want online", value)
+	}
+
+	// When
+	output.Close()
+
+	// Then
+	deadline := time.Now().Add(5 * time.Second)
+	for value, _ := wait("linky/availability"); value != "offline"; value, _ = wait("linky/availability") {
+		if time.Now().After(deadline) {
+			t.Fatalf("got availability %q, want offline", value)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
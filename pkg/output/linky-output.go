@@ -0,0 +1,149 @@
+package output
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Output interface of destinations receiving each decoded frame of the meters
+type Output interface {
+	// Publish hands a frame to the output without blocking, it is dropped when the output is late
+	Publish(meter string, frame core.Frame)
+	// PublishEvent hands a meter event to the output without blocking, outputs without events ignore it
+	PublishEvent(event core.Event)
+	// Close sends pending frames and releases the output
+	Close() error
+}
+
+// Frame of a meter, or event when set, waiting in an output queue
+type meterFrame struct {
+	meter string
+	frame core.Frame
+	event *core.Event
+}
+
+// Queue object to hand frames to an output goroutine without blocking meters
+type queue struct {
+	name   string
+	frames chan meterFrame
+	done   chan struct{}
+	mutex  sync.RWMutex
+	closed bool
+}
+
+// Construct a queue handling frames and events in its own goroutine, events are dropped when handleEvent is nil
+func newQueue(name string, size int, handle func(meter string, frame core.Frame), handleEvent func(event core.Event)) *queue {
+	return newTickingQueue(name, size, 0, handle, handleEvent, nil)
+}
+
+// Construct a queue handling frames and events in its own goroutine, also calling tick at each interval when not 0
+func newTickingQueue(name string, size int, interval time.Duration, handle func(meter string, frame core.Frame), handleEvent func(event core.Event), tick func()) *queue {
+	q := &queue{name: name, frames: make(chan meterFrame, size), done: make(chan struct{})}
+
+	go func() {
+		defer close(q.done)
+
+		var ticks <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			ticks = ticker.C
+		}
+
+		for {
+			select {
+			case frame, ok := <-q.frames:
+				if !ok {
+					return
+				}
+				if frame.event == nil {
+					handle(frame.meter, frame.frame)
+				} else if handleEvent != nil {
+					handleEvent(*frame.event)
+				}
+			case <-ticks:
+				tick()
+			}
+		}
+	}()
+	return q
+}
+
+// Add a frame to the queue, it is dropped when the queue is full or closed
+func (q *queue) push(meter string, frame core.Frame) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+	if q.closed {
+		return
+	}
+
+	select {
+	case q.frames <- meterFrame{meter: meter, frame: frame}:
+	default:
+		log.Warnf("Output %s is late, frame of %s dropped", q.name, meter)
+	}
+}
+
+// Add an event to the queue, it is dropped when the queue is full or closed
+func (q *queue) pushEvent(event core.Event) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+	if q.closed {
+		return
+	}
+
+	select {
+	case q.frames <- meterFrame{meter: event.Meter, event: &event}:
+	default:
+		log.Warnf("Output %s is late, %s event of %s dropped", q.name, event.Type, event.Meter)
+	}
+}
+
+// Close the queue and wait for queued frames to be handled
+func (q *queue) close() {
+	q.mutex.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.frames)
+	}
+	q.mutex.Unlock()
+	<-q.done
+}
+
+// NewOutputs method to construct the enabled outputs, remote write metrics are built by the gatherer
+func NewOutputs(outputsConfig config.OutputsConfig, metricsConfig config.MetricsConfig, gather FrameGatherer) ([]Output, error) {
+	var outputs []Output
+	if outputsConfig.Mqtt != nil {
+		outputs = append(outputs, NewMqttOutput(*outputsConfig.Mqtt, metricsConfig))
+	}
+	if outputsConfig.InfluxDB != nil {
+		influxDB, err := NewInfluxDBOutput(*outputsConfig.InfluxDB)
+		if err != nil {
+			closeOutputs(outputs)
+			return nil, err
+		}
+		outputs = append(outputs, influxDB)
+	}
+	if outputsConfig.RemoteWrite != nil {
+		remoteWrite, err := NewRemoteWriteOutput(*outputsConfig.RemoteWrite, gather)
+		if err != nil {
+			closeOutputs(outputs)
+			return nil, err
+		}
+		outputs = append(outputs, remoteWrite)
+	}
+	return outputs, nil
+}
+
+// Close outputs, logging failures
+func closeOutputs(outputs []Output) {
+	for _, output := range outputs {
+		if err := output.Close(); err != nil {
+			log.Errorf("Failed to close output : %s", err)
+		}
+	}
+}
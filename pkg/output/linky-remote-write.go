@@ -0,0 +1,214 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/golang/snappy"
+	dto "github.com/prometheus/client_model/go"
+	log "github.com/sirupsen/logrus"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const remoteWriteQueueSize = 256
+
+// FrameGatherer returns the metrics of a frame, as exposed on /metrics
+type FrameGatherer func(meter string, frame core.Frame) ([]*dto.MetricFamily, error)
+
+// RemoteWriteOutput object to push metrics of each frame with the Prometheus remote write protocol
+type RemoteWriteOutput struct {
+	config config.RemoteWriteConfig
+	client *http.Client
+	gather FrameGatherer
+	queue  *queue
+	series map[string]*timeSeries // Series of the batch by labels
+	order  []string               // Labels of the series in the order they appeared
+	frames int                    // Number of frames in the batch
+	sender *batchSender
+}
+
+// Series object to hold the samples of one labels set
+type timeSeries struct {
+	labels  [][2]string // Label names and values, sorted by name
+	samples []remoteSample
+}
+
+// Sample object with its timestamp in milliseconds
+type remoteSample struct {
+	value     float64
+	timestamp int64
+}
+
+// NewRemoteWriteOutput method to construct RemoteWriteOutput
+func NewRemoteWriteOutput(remoteWriteConfig config.RemoteWriteConfig, gather FrameGatherer) (*RemoteWriteOutput, error) {
+	output := &RemoteWriteOutput{
+		config: remoteWriteConfig,
+		client: &http.Client{Timeout: remoteWriteConfig.Timeout},
+		gather: gather,
+		series: make(map[string]*timeSeries),
+	}
+	sender, err := newBatchSender("Remote write", remoteWriteConfig.QueueDirectory, remoteWriteConfig.QueueMaxBytes, output.write)
+	if err != nil {
+		return nil, err
+	}
+	output.sender = sender
+	output.queue = newTickingQueue("remote write", remoteWriteQueueSize, remoteWriteConfig.FlushInterval, output.add, nil, output.flush)
+	return output, nil
+}
+
+// Publish implements Output
+func (output *RemoteWriteOutput) Publish(meter string, frame core.Frame) {
+	output.queue.push(meter, frame)
+}
+
+// PublishEvent implements Output, events are not time series and are ignored
+func (output *RemoteWriteOutput) PublishEvent(event core.Event) {
+}
+
+// Close implements Output, pending frames are sent or queued
+func (output *RemoteWriteOutput) Close() error {
+	output.queue.close()
+	output.flush()
+	output.sender.close()
+	return nil
+}
+
+// Add the metrics of a frame to the batch, with the frame reception time
+func (output *RemoteWriteOutput) add(meter string, frame core.Frame) {
+	families, err := output.gather(meter, frame)
+	if err != nil {
+		log.Errorf("Unable to gather metrics of %s : %s", meter, err)
+		return
+	}
+
+	timestamp := frame.Time.UnixMilli()
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			value, ok := metricValue(family.GetType(), metric)
+			if !ok {
+				continue
+			}
+
+			labels := [][2]string{{"__name__", family.GetName()}}
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, [2]string{label.GetName(), label.GetValue()})
+			}
+			sort.Slice(labels, func(i, j int) bool { return labels[i][0] < labels[j][0] })
+
+			key := fmt.Sprint(labels)
+			series, exists := output.series[key]
+			if !exists {
+				series = &timeSeries{labels: labels}
+				output.series[key] = series
+				output.order = append(output.order, key)
+			}
+			series.samples = append(series.samples, remoteSample{value, timestamp})
+		}
+	}
+
+	output.frames++
+	if output.frames >= output.config.BatchSize {
+		output.flush()
+	}
+}
+
+// Send the batch, then the queued ones
+func (output *RemoteWriteOutput) flush() {
+	if output.frames == 0 {
+		output.sender.replay()
+		return
+	}
+
+	var series []*timeSeries
+	for _, key := range output.order {
+		series = append(series, output.series[key])
+	}
+	output.series = make(map[string]*timeSeries)
+	output.order = nil
+	output.frames = 0
+
+	output.sender.sendBatch(snappy.Encode(nil, encodeWriteRequest(series)))
+}
+
+// Write a compressed request
+func (output *RemoteWriteOutput) write(batch []byte) error {
+	request, err := http.NewRequest(http.MethodPost, output.config.Url, bytes.NewReader(batch))
+	if err != nil {
+		return permanentError{err}
+	}
+	request.Header.Set("Content-Encoding", "snappy")
+	request.Header.Set("Content-Type", "application/x-protobuf")
+	request.Header.Set("User-Agent", "linky-exporter")
+	request.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if output.config.BearerToken != "" {
+		request.Header.Set("Authorization", "Bearer "+output.config.BearerToken)
+	} else if output.config.Username != "" {
+		request.SetBasicAuth(output.config.Username, output.config.Password)
+	}
+
+	response, err := output.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode/100 == 2 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	err = fmt.Errorf("%s : %s", response.Status, strings.TrimSpace(string(body)))
+	if response.StatusCode/100 == 4 && response.StatusCode != http.StatusTooManyRequests {
+		return permanentError{err}
+	}
+	return err
+}
+
+// Value of a gauge, counter or untyped metric
+func metricValue(metricType dto.MetricType, metric *dto.Metric) (float64, bool) {
+	switch metricType {
+	case dto.MetricType_GAUGE:
+		return metric.GetGauge().GetValue(), true
+	case dto.MetricType_COUNTER:
+		return metric.GetCounter().GetValue(), true
+	case dto.MetricType_UNTYPED:
+		return metric.GetUntyped().GetValue(), true
+	default:
+		return 0, false
+	}
+}
+
+// Encode a prometheus.WriteRequest protobuf message
+func encodeWriteRequest(series []*timeSeries) []byte {
+	var request []byte
+	for _, s := range series {
+		var encoded []byte
+		for _, label := range s.labels {
+			var labelBytes []byte
+			labelBytes = protowire.AppendTag(labelBytes, 1, protowire.BytesType)
+			labelBytes = protowire.AppendString(labelBytes, label[0])
+			labelBytes = protowire.AppendTag(labelBytes, 2, protowire.BytesType)
+			labelBytes = protowire.AppendString(labelBytes, label[1])
+			encoded = protowire.AppendTag(encoded, 1, protowire.BytesType)
+			encoded = protowire.AppendBytes(encoded, labelBytes)
+		}
+		for _, sample := range s.samples {
+			var sampleBytes []byte
+			sampleBytes = protowire.AppendTag(sampleBytes, 1, protowire.Fixed64Type)
+			sampleBytes = protowire.AppendFixed64(sampleBytes, math.Float64bits(sample.value))
+			sampleBytes = protowire.AppendTag(sampleBytes, 2, protowire.VarintType)
+			sampleBytes = protowire.AppendVarint(sampleBytes, uint64(sample.timestamp))
+			encoded = protowire.AppendTag(encoded, 2, protowire.BytesType)
+			encoded = protowire.AppendBytes(encoded, sampleBytes)
+		}
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, encoded)
+	}
+	return request
+}
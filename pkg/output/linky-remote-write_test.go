@@ -0,0 +1,134 @@
+package output
+
+import (
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// Gatherer returning the apparent power of the frame as a gauge
+func papp(meter string, frame core.Frame) ([]*dto.MetricFamily, error) {
+	return []*dto.MetricFamily{{
+		Name: proto.String("linky_apparent_power_va"),
+		Type: dto.MetricType_GAUGE.Enum(),
+		Metric: []*dto.Metric{{
+			Label: []*dto.LabelPair{{Name: proto.String("meter"), Value: proto.String(meter)}},
+			Gauge: &dto.Gauge{Value: proto.Float64(float64(frame.Historical.Papp))},
+		}},
+	}}, nil
+}
+
+// Decode a compressed write request as series of labels and samples
+func decodeWriteRequest(t *testing.T, body []byte) []timeSeries {
+	request, err := snappy.Decode(nil, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := func(message []byte, handle func(number protowire.Number, typ protowire.Type, value []byte)) {
+		for len(message) > 0 {
+			number, typ, n := protowire.ConsumeTag(message)
+			message = message[n:]
+			n = protowire.ConsumeFieldValue(number, typ, message)
+			handle(number, typ, message[:n])
+			message = message[n:]
+		}
+	}
+
+	var series []timeSeries
+	fields(request, func(_ protowire.Number, _ protowire.Type, value []byte) {
+		content, _ := protowire.ConsumeBytes(value)
+		var s timeSeries
+		fields(content, func(number protowire.Number, _ protowire.Type, value []byte) {
+			message, _ := protowire.ConsumeBytes(value)
+			if number == 1 {
+				var label [2]string
+				fields(message, func(number protowire.Number, _ protowire.Type, value []byte) {
+					text, _ := protowire.ConsumeString(value)
+					label[number-1] = text
+				})
+				s.labels = append(s.labels, label)
+			} else {
+				var sample remoteSample
+				fields(message, func(number protowire.Number, _ protowire.Type, value []byte) {
+					if number == 1 {
+						bits, _ := protowire.ConsumeFixed64(value)
+						sample.value = math.Float64frombits(bits)
+					} else {
+						timestamp, _ := protowire.ConsumeVarint(value)
+						sample.timestamp = int64(timestamp)
+					}
+				})
+				s.samples = append(s.samples, sample)
+			}
+		})
+		series = append(series, s)
+	})
+	return series
+}
+
+func TestRemoteWriteOutput(t *testing.T) {
+	// Given
+	server, requests, bodies := influxDBServer(t)
+	output, err := NewRemoteWriteOutput(config.RemoteWriteConfig{Url: server.URL, BearerToken: "abc", BatchSize: 2, FlushInterval: time.Hour, Timeout: time.Second, QueueMaxBytes: 1024}, papp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := historicalFrame(t)
+	second := first
+	second.Time = first.Time.Add(time.Second)
+
+	// When
+	output.Publish("main", first)
+	output.Publish("main", second)
+	output.Close()
+
+	// Then
+	if len(requests()) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests()))
+	}
+	request := requests()[0]
+	if request.Header.Get("Content-Encoding") != "snappy" || request.Header.Get("Authorization") != "Bearer abc" || request.Header.Get("X-Prometheus-Remote-Write-Version") != "0.1.0" {
+		t.Errorf("unexpected headers %v", request.Header)
+	}
+	series := decodeWriteRequest(t, []byte(bodies()[0]))
+	if len(series) != 1 || len(series[0].samples) != 2 {
+		t.Fatalf("got %+v, want one series with 2 samples", series)
+	}
+	if series[0].labels[0] != [2]string{"__name__", "linky_apparent_power_va"} || series[0].labels[1] != [2]string{"meter", "main"} {
+		t.Errorf("unexpected labels %v", series[0].labels)
+	}
+	if series[0].samples[0] != (remoteSample{2530, first.Time.UnixMilli()}) || series[0].samples[1] != (remoteSample{2530, second.Time.UnixMilli()}) {
+		t.Errorf("unexpected samples %v", series[0].samples)
+	}
+}
+
+func TestRemoteWriteOutputReplay(t *testing.T) {
+	// Given
+	server, requests, bodies := influxDBServer(t, http.StatusServiceUnavailable)
+	output, err := NewRemoteWriteOutput(config.RemoteWriteConfig{Url: server.URL, BatchSize: 1, FlushInterval: time.Hour, Timeout: time.Second, QueueDirectory: t.TempDir(), QueueMaxBytes: 1024}, papp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := historicalFrame(t)
+
+	// When
+	output.Publish("main", frame)
+	output.Close()
+
+	// Then
+	if len(requests()) != 2 || bodies()[1] != bodies()[0] {
+		t.Fatalf("got %d requests, want the request replayed", len(requests()))
+	}
+	if series := decodeWriteRequest(t, []byte(bodies()[1])); series[0].samples[0].timestamp != frame.Time.UnixMilli() {
+		t.Errorf("original timestamp lost : %v", series[0].samples)
+	}
+}
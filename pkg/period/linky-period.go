@@ -0,0 +1,272 @@
+package period
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+const (
+	saveInterval = 5 * time.Minute // Maximum delay before saving totals, they are also saved when a period ends
+	tempoOffset  = 6 * time.Hour   // Start of the energy day of Tempo and EJP contracts
+	totalIndex   = "total"         // Index name of the total energy
+)
+
+// Index name of the total exported energy of producer meters
+const ExportedIndex = "exported"
+
+// Calendar periods in export order
+var Periods = []string{"day", "week", "month", "year"}
+
+// Total object to hold the energy of an index over a period window
+type Total struct {
+	Period string // day, week, month or year
+	Index  string // Supplier or distributor index, total for all indexes, exported for the energy injected
+	Window string // current or previous
+	Value  float64
+}
+
+// Window of a period, saved across restarts
+type window struct {
+	Start    time.Time          `json:"start"`
+	Baseline map[string]float64 `json:"baseline"`           // Indexes at the start of the window
+	Last     map[string]float64 `json:"last"`               // Indexes of the last frame
+	Previous map[string]float64 `json:"previous,omitempty"` // Totals of the previous window, unknown when the exporter missed it
+}
+
+// Tracker object to maintain calendar period totals of the energy indexes of meters
+type Tracker struct {
+	mutex    sync.Mutex
+	config   config.PeriodsConfig
+	meters   map[string]map[string]*window // Windows by meter and period
+	lastSave time.Time
+}
+
+// Open method to construct Tracker, loading totals saved in the state file
+func Open(periodsConfig config.PeriodsConfig) (*Tracker, error) {
+	tracker := &Tracker{config: periodsConfig, meters: make(map[string]map[string]*window), lastSave: time.Now()}
+	if periodsConfig.StateFile == "" {
+		return tracker, nil
+	}
+
+	content, err := os.ReadFile(periodsConfig.StateFile)
+	if os.IsNotExist(err) {
+		return tracker, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read period state file : %s", err)
+	}
+	if err := json.Unmarshal(content, &tracker.meters); err != nil {
+		return nil, fmt.Errorf("Unable to parse period state file %s : %s", periodsConfig.StateFile, err)
+	}
+	return tracker, nil
+}
+
+// Configure changes the day offset, the state file can not change
+func (tracker *Tracker) Configure(periodsConfig config.PeriodsConfig) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.config.DayOffset = periodsConfig.DayOffset
+}
+
+// StateFile returns the file keeping totals, empty when in memory
+func (tracker *Tracker) StateFile() string {
+	return tracker.config.StateFile
+}
+
+// Add the indexes of a frame to the windows of its meter, starting new windows when periods end
+func (tracker *Tracker) Add(meter string, frame core.Frame) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	measurement := frame.Measurement()
+	indexes := make(map[string]float64)
+	for _, sample := range measurement.Filter(core.ActiveEnergy) {
+		if sample.Direction == core.Exported && sample.Index == "" {
+			indexes[ExportedIndex] = sample.Value
+		}
+		if sample.Direction != core.Imported {
+			continue
+		}
+		if sample.Index == "" {
+			indexes[totalIndex] = sample.Value
+		} else {
+			indexes[sample.Index] = sample.Value
+		}
+	}
+	if len(indexes) == 0 {
+		return
+	}
+
+	windows, exists := tracker.meters[meter]
+	if !exists {
+		windows = make(map[string]*window)
+		tracker.meters[meter] = windows
+	}
+	offset := tracker.dayOffset(measurement)
+	ended := false
+	for _, period := range Periods {
+		current, exists := windows[period]
+		start := Start(period, frame.Time, offset)
+		switch {
+		case !exists:
+			current = &window{Start: start, Baseline: copyIndexes(indexes)}
+			windows[period] = current
+		case start.After(current.Start):
+			// Energy between the last frame and this one is counted in the new window
+			var previous map[string]float64
+			if current.Start.Equal(Start(period, start.Add(-time.Second), offset)) {
+				previous = difference(current.Last, current.Baseline)
+			}
+			current = &window{Start: start, Baseline: copyIndexes(current.Last), Previous: previous}
+			windows[period] = current
+			ended = true
+		}
+
+		for index, value := range indexes {
+			// A new index or a lower one, from a replaced meter, starts from the current value
+			if baseline, known := current.Baseline[index]; !known || value < baseline {
+				current.Baseline[index] = value
+			}
+		}
+		current.Last = copyIndexes(indexes)
+	}
+
+	if ended || time.Since(tracker.lastSave) >= saveInterval {
+		if err := tracker.save(); err != nil {
+			log.Errorf("Failed to save period totals : %s", err)
+		}
+	}
+}
+
+// Start of the energy day of a meter
+func (tracker *Tracker) dayOffset(measurement *core.LinkyMeasurement) time.Duration {
+	return DayOffset(tracker.config.DayOffset, measurement.Contract)
+}
+
+// DayOffset returns the start of the energy day, configured or 06:00 for Tempo and EJP contracts and midnight otherwise
+func DayOffset(configured *time.Duration, contract string) time.Duration {
+	if configured != nil {
+		return *configured
+	}
+	contract = strings.ToUpper(contract)
+	if strings.HasPrefix(contract, "BBR") || strings.Contains(contract, "TEMPO") || strings.Contains(contract, "EJP") {
+		return tempoOffset
+	}
+	return 0
+}
+
+// Totals returns the totals of a meter sorted by period, index and window, empty before its first frame
+func (tracker *Tracker) Totals(meter string) []Total {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	var totals []Total
+	for _, period := range Periods {
+		current, exists := tracker.meters[meter][period]
+		if !exists {
+			continue
+		}
+		for index, value := range difference(current.Last, current.Baseline) {
+			totals = append(totals, Total{Period: period, Index: index, Window: "current", Value: value})
+		}
+		for index, value := range current.Previous {
+			totals = append(totals, Total{Period: period, Index: index, Window: "previous", Value: value})
+		}
+	}
+	rank := make(map[string]int)
+	for i, period := range Periods {
+		rank[period] = i
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Period != totals[j].Period {
+			return rank[totals[i].Period] < rank[totals[j].Period]
+		}
+		if totals[i].Index != totals[j].Index {
+			return totals[i].Index < totals[j].Index
+		}
+		return totals[i].Window < totals[j].Window
+	})
+	return totals
+}
+
+// Save totals in the state file, through a temporary file to never leave a partial state
+func (tracker *Tracker) save() error {
+	tracker.lastSave = time.Now()
+	if tracker.config.StateFile == "" {
+		return nil
+	}
+
+	content, err := json.Marshal(tracker.meters)
+	if err != nil {
+		return err
+	}
+	temporary, err := os.CreateTemp(filepath.Dir(tracker.config.StateFile), ".linky-periods-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary.Name())
+	if _, err := temporary.Write(content); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temporary.Name(), tracker.config.StateFile)
+}
+
+// Close saves the totals
+func (tracker *Tracker) Close() error {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	return tracker.save()
+}
+
+// Start returns the start of the window of a period holding a time, in local time, days starting at the offset
+func Start(period string, t time.Time, offset time.Duration) time.Time {
+	t = t.In(time.Local)
+	hours, minutes := int(offset/time.Hour), int(offset%time.Hour/time.Minute)
+	year, month, day := t.Date()
+	if t.Before(time.Date(year, month, day, hours, minutes, 0, 0, time.Local)) {
+		year, month, day = t.AddDate(0, 0, -1).Date()
+	}
+
+	switch period {
+	case "week":
+		weekday := time.Date(year, month, day, 12, 0, 0, 0, time.Local).Weekday()
+		day -= (int(weekday) + 6) % 7
+	case "month":
+		day = 1
+	case "year":
+		month, day = time.January, 1
+	}
+	return time.Date(year, month, day, hours, minutes, 0, 0, time.Local)
+}
+
+// Copy indexes
+func copyIndexes(indexes map[string]float64) map[string]float64 {
+	copied := make(map[string]float64)
+	for index, value := range indexes {
+		copied[index] = value
+	}
+	return copied
+}
+
+// Energy of each index between the baseline and the last indexes
+func difference(last map[string]float64, baseline map[string]float64) map[string]float64 {
+	totals := make(map[string]float64)
+	for index, value := range last {
+		totals[index] = value - baseline[index]
+	}
+	return totals
+}
@@ -0,0 +1,86 @@
+package period
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/internal/tictest"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Historical Tempo frame at a time
+func tempoFrame(t *testing.T, at time.Time, hcjb string, hpjb string) core.Frame {
+	return tictest.Frame(t, core.Historical, at, "OPTARIF BBR(", "BBRHCJB "+hcjb, "BBRHPJB "+hpjb)
+}
+
+func TestPeriodStartTableDriven(t *testing.T) {
+	// Given
+	at := time.Date(2023, 6, 14, 5, 30, 0, 0, time.Local) // Wednesday
+	var tests = []struct {
+		period   string
+		offset   time.Duration
+		expected time.Time
+	}{
+		{"day", 0, time.Date(2023, 6, 14, 0, 0, 0, 0, time.Local)},
+		{"day", 6 * time.Hour, time.Date(2023, 6, 13, 6, 0, 0, 0, time.Local)},
+		{"week", 0, time.Date(2023, 6, 12, 0, 0, 0, 0, time.Local)},
+		{"month", 0, time.Date(2023, 6, 1, 0, 0, 0, 0, time.Local)},
+		{"year", 6 * time.Hour, time.Date(2023, 1, 1, 6, 0, 0, 0, time.Local)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.period+" "+tt.offset.String(), func(t *testing.T) {
+			// When
+			start := Start(tt.period, at, tt.offset)
+
+			// Then
+			if !start.Equal(tt.expected) {
+				t.Errorf("got %s, want %s", start, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTrackerTempoDayPersisted(t *testing.T) {
+	// Given
+	periodsConfig := config.PeriodsConfig{StateFile: filepath.Join(t.TempDir(), "periods.json")}
+	tracker, err := Open(periodsConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2023, 6, 14, 0, 0, 0, 0, time.Local)
+
+	// When
+	tracker.Add("main", tempoFrame(t, day.Add(-time.Hour), "000010000", "000020000"))
+	tracker.Add("main", tempoFrame(t, day.Add(5*time.Hour), "000010500", "000020000"))
+	tracker.Add("main", tempoFrame(t, day.Add(7*time.Hour), "000010600", "000020200"))
+	if err := tracker.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tracker, err = Open(periodsConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Then
+	var days []Total
+	for _, total := range tracker.Totals("main") {
+		if total.Period == "day" {
+			days = append(days, total)
+		}
+	}
+	expected := []Total{
+		{"day", "F1", "current", 100},
+		{"day", "F1", "previous", 500},
+		{"day", "F2", "current", 200},
+		{"day", "F2", "previous", 0},
+		{"day", "total", "current", 300},
+		{"day", "total", "previous", 500},
+	}
+	if !reflect.DeepEqual(days, expected) {
+		t.Errorf("got %+v, want %+v", days, expected)
+	}
+}
@@ -0,0 +1,49 @@
+package prom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/syberalexis/linky-exporter/pkg/alert"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+)
+
+// AlertCollector object to collect the headroom alert state of each meter and hook failures
+type AlertCollector struct {
+	watcher  *alert.Watcher
+	meters   []string
+	firing   *metricFamily
+	failures *metricFamily
+}
+
+// NewAlertCollector method to construct AlertCollector
+func NewAlertCollector(watcher *alert.Watcher, meters []string, labels LabelsConfig) (*AlertCollector, error) {
+	collector := &AlertCollector{
+		watcher:  watcher,
+		meters:   meters,
+		firing:   newMetricFamily(labels, "linky_power_headroom_alert", "Whether the headroom stayed below the threshold for the configured duration", prometheus.GaugeValue, config.MeterLabel),
+		failures: newMetricFamily(labels, "linky_alert_hook_failures_total", "Number of failed alert notifications since the hook was configured", prometheus.CounterValue, "hook"),
+	}
+	if err := familiesError(collector.firing, collector.failures); err != nil {
+		return nil, err
+	}
+	return collector, nil
+}
+
+// Describe implements required describe function for all prometheus collectors
+func (collector *AlertCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.firing.desc
+	ch <- collector.failures.desc
+}
+
+// Collect implements required collect function for all prometheus collectors
+func (collector *AlertCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, meter := range collector.meters {
+		firing := 0.0
+		if collector.watcher.Firing(meter) {
+			firing = 1
+		}
+		ch <- collector.firing.metric(firing, meter)
+	}
+	for hook, failures := range collector.watcher.Failures() {
+		ch <- collector.failures.metric(float64(failures), hook)
+	}
+}
@@ -0,0 +1,276 @@
+package prom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	log "github.com/sirupsen/logrus"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+	"github.com/syberalexis/linky-exporter/pkg/solar"
+)
+
+// JSON document of a frame with typed values
+type apiFrame struct {
+	Meter     string     `json:"meter"`
+	Mode      string     `json:"mode"`
+	Time      time.Time  `json:"time"`
+	MeterTime *time.Time `json:"meter_time,omitempty"`
+	Values    []apiValue `json:"values"`
+}
+
+// JSON document of a typed value
+type apiValue struct {
+	Label string      `json:"label"`
+	Value interface{} `json:"value"`
+	Unit  string      `json:"unit,omitempty"`
+	Time  *time.Time  `json:"time,omitempty"`
+}
+
+// JSON document of a frame as received
+type apiRawFrame struct {
+	Meter    string       `json:"meter"`
+	Mode     string       `json:"mode"`
+	Time     time.Time    `json:"time"`
+	DataSets []apiDataSet `json:"datasets"`
+}
+
+// JSON document of a data set as received
+type apiDataSet struct {
+	Label     string `json:"label"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Value     string `json:"value"`
+	Checksum  string `json:"checksum"`
+}
+
+// JSON document of the meter identification
+type apiMeter struct {
+	Meter          string   `json:"meter"`
+	Device         string   `json:"device"`
+	Mode           string   `json:"mode"`
+	Id             string   `json:"id"`
+	Prm            string   `json:"prm,omitempty"`
+	Version        string   `json:"version"`
+	Contract       string   `json:"contract"`
+	PriceLabel     string   `json:"price_label,omitempty"`
+	ReferencePower *float64 `json:"reference_power_kva,omitempty"`
+	BreakingPower  *float64 `json:"breaking_power_kva,omitempty"`
+}
+
+// JSON document of reactive power and tan φ
+type apiReactive struct {
+	Meter         string             `json:"meter"`
+	ReactivePower map[string]float64 `json:"reactive_power_var,omitempty"`
+	TanPhi        []apiTanPhi        `json:"tan_phi"`
+	Threshold     float64            `json:"threshold"`
+}
+
+// JSON document of tan φ within a window
+type apiTanPhi struct {
+	Window   string  `json:"window"`
+	Value    float64 `json:"value"`
+	Exceeded bool    `json:"exceeded"`
+}
+
+// Handle GET /api/v1/frame requests, returning the typed values of the last frame
+func (exporter *LinkyExporter) apiFrameHandler(w http.ResponseWriter, r *http.Request) {
+	meter, frame, ok := exporter.apiLastFrame(w, r)
+	if !ok {
+		return
+	}
+
+	writeJson(w, newApiFrame(meter.Name, frame, exporter.metricsConfig()))
+}
+
+// Build the JSON document of a frame with typed values, meter identifiers are replaced as in metrics
+func newApiFrame(meter string, frame core.Frame, metrics config.MetricsConfig) apiFrame {
+	document := apiFrame{Meter: meter, Mode: frame.Mode.String(), Time: frame.Time, Values: []apiValue{}}
+	if frame.Standard != nil && !frame.Standard.Date.IsZero() {
+		document.MeterTime = &frame.Standard.Date
+	}
+	for _, value := range frame.Values() {
+		apiValue := apiValue{Label: value.Label, Value: value.Value, Unit: string(value.Unit)}
+		if id, ok := value.Value.(string); ok && core.IsIdentifier(value.Label) {
+			apiValue.Value = metrics.MeterId(id)
+		}
+		if !value.Time.IsZero() {
+			apiValue.Time = &value.Time
+		}
+		document.Values = append(document.Values, apiValue)
+	}
+	return document
+}
+
+// Handle GET /api/v1/raw requests, returning the data sets of the last frame as received,
+// except meter identifiers replaced as in metrics with their received checksum
+func (exporter *LinkyExporter) apiRawHandler(w http.ResponseWriter, r *http.Request) {
+	meter, frame, ok := exporter.apiLastFrame(w, r)
+	if !ok {
+		return
+	}
+	metrics := exporter.metricsConfig()
+
+	document := apiRawFrame{Meter: meter.Name, Mode: frame.Mode.String(), Time: frame.Time, DataSets: []apiDataSet{}}
+	for _, dataSet := range frame.DataSets {
+		if core.IsIdentifier(dataSet.Label) {
+			dataSet.Value = metrics.MeterId(dataSet.Value)
+		}
+		document.DataSets = append(document.DataSets, apiDataSet(dataSet))
+	}
+	writeJson(w, document)
+}
+
+// Handle GET /api/v1/meter requests, returning the identification of the meter from its last frame
+func (exporter *LinkyExporter) apiMeterHandler(w http.ResponseWriter, r *http.Request) {
+	meter, frame, ok := exporter.apiLastFrame(w, r)
+	if !ok {
+		return
+	}
+
+	measurement := frame.Measurement()
+	metrics := exporter.metricsConfig()
+	document := apiMeter{
+		Meter:      meter.Name,
+		Device:     meter.Health().Device,
+		Mode:       frame.Mode.String(),
+		Id:         metrics.MeterId(measurement.MeterId),
+		Version:    measurement.Version,
+		Contract:   measurement.Contract,
+		PriceLabel: measurement.PriceLabel,
+	}
+	if measurement.Prm != "" {
+		document.Prm = metrics.MeterId(measurement.Prm)
+	}
+	if power, ok := measurement.Get(core.ReferencePower, core.NoDirection, 0, ""); ok {
+		document.ReferencePower = &power
+	}
+	if power, ok := measurement.Get(core.BreakingPower, core.NoDirection, 0, ""); ok {
+		document.BreakingPower = &power
+	}
+	writeJson(w, document)
+}
+
+// Handle GET /api/v1/reactive requests, returning reactive power by quadrant and tan φ of each window
+func (exporter *LinkyExporter) apiReactiveHandler(w http.ResponseWriter, r *http.Request) {
+	meter, _, ok := exporter.apiLastFrame(w, r)
+	if !ok {
+		return
+	}
+	estimate, ok := meter.Reactive()
+	if !ok {
+		http.Error(w, fmt.Sprintf("No reactive energy indexes received from %s yet", meter.Name), http.StatusServiceUnavailable)
+		return
+	}
+
+	document := apiReactive{Meter: meter.Name, TanPhi: []apiTanPhi{}, Threshold: estimate.Threshold}
+	if estimate.HasPower {
+		document.ReactivePower = make(map[string]float64)
+		for i, quadrant := range core.Quadrants {
+			document.ReactivePower[quadrant] = estimate.Power[i]
+		}
+	}
+	for _, tanPhi := range estimate.TanPhi {
+		document.TanPhi = append(document.TanPhi, apiTanPhi{Window: model.Duration(tanPhi.Window).String(), Value: tanPhi.Value, Exceeded: tanPhi.Exceeded})
+	}
+	writeJson(w, document)
+}
+
+// Handle POST /api/v1/solar/production requests, adding the production of the body when the HTTP input is enabled
+func (exporter *LinkyExporter) solarProductionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Only POST requests allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	exporter.mutex.Lock()
+	enabled := exporter.config.Solar != nil && exporter.config.Solar.Production.Http
+	exporter.mutex.Unlock()
+	if !enabled {
+		http.Error(w, "Solar production input not enabled", http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 4096))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	production, err := solar.ParseProduction(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	exporter.solar.AddProduction(time.Now(), production.Energy, production.Power)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Return the metrics configuration, used to publish meter identifiers as in metrics
+func (exporter *LinkyExporter) metricsConfig() config.MetricsConfig {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	return exporter.config.Metrics
+}
+
+// Return the meter selected by the meter parameter, optional with a single meter, and its last frame.
+// An error is written to the response when false is returned.
+func (exporter *LinkyExporter) apiLastFrame(w http.ResponseWriter, r *http.Request) (*core.Meter, core.Frame, bool) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "Only GET requests allowed", http.StatusMethodNotAllowed)
+		return nil, core.Frame{}, false
+	}
+
+	meter, status, err := exporter.selectMeter(r.URL.Query().Get("meter"))
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return nil, core.Frame{}, false
+	}
+	frame, ok := meter.Last()
+	if !ok {
+		http.Error(w, fmt.Sprintf("No frame received from %s", meter.Name), http.StatusServiceUnavailable)
+		return nil, core.Frame{}, false
+	}
+	return meter, frame, true
+}
+
+// Return the meter of the given name, or the only meter when no name is given, with the HTTP status of the error
+func (exporter *LinkyExporter) selectMeter(name string) (*core.Meter, int, error) {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+
+	if name != "" {
+		if meter, ok := exporter.meters[name]; ok {
+			return meter, http.StatusOK, nil
+		}
+		return nil, http.StatusNotFound, fmt.Errorf("Unknown meter %s", name)
+	}
+	if len(exporter.meters) == 0 {
+		return nil, http.StatusNotFound, fmt.Errorf("No meter configured")
+	}
+	if len(exporter.meters) == 1 {
+		for _, meter := range exporter.meters {
+			return meter, http.StatusOK, nil
+		}
+	}
+
+	var names []string
+	for name := range exporter.meters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, http.StatusBadRequest, fmt.Errorf("Parameter meter required, among : %s", strings.Join(names, ", "))
+}
+
+// Write a JSON document to the response
+func writeJson(w http.ResponseWriter, document interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(document); err != nil {
+		log.Errorf("Failed to write JSON response : %s", err)
+	}
+}
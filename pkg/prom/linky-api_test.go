@@ -0,0 +1,66 @@
+package prom
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestApiHandlersTableDriven(t *testing.T) {
+	// Given
+	linkyConfig := testConfig(frameServer(t))
+	linkyConfig.Devices[0].Name = "main"
+	linkyConfig.Devices[0].Mode = "standard"
+	linkyConfig.Metrics.MeterAliases = map[string]string{"031762120345": "home"}
+	exporter := NewLinkyExporter(linkyConfig, "")
+	if err := exporter.apply(exporter.config); err != nil {
+		t.Fatal(err)
+	}
+	defer exporter.stop()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if _, ok := exporter.meters["main"].Last(); ok {
+			break
+		}
+	}
+
+	var tests = []struct {
+		name     string
+		handler  http.HandlerFunc
+		url      string
+		status   int
+		expected string
+	}{
+		{"frame", exporter.apiFrameHandler, "/api/v1/frame", http.StatusOK, `{"meter":"main","mode":"standard","values":[{"label":"ADSC","value":"home"},{"label":"URMS1","unit":"V","value":229}]}`},
+		{"raw", exporter.apiRawHandler, "/api/v1/raw?meter=main", http.StatusOK, `{"datasets":[{"checksum":"/","label":"ADSC","value":"home"},{"checksum":"G","label":"URMS1","value":"229"}],"meter":"main","mode":"standard"}`},
+		{"meter", exporter.apiMeterHandler, "/api/v1/meter", http.StatusOK, `{"contract":"","id":"home","meter":"main","mode":"standard","version":""}`},
+		{"unknown meter", exporter.apiFrameHandler, "/api/v1/frame?meter=other", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			recorder := httptest.NewRecorder()
+			tt.handler(recorder, httptest.NewRequest("GET", tt.url, nil))
+
+			// Then
+			if recorder.Code != tt.status {
+				t.Fatalf("got %d : %s", recorder.Code, recorder.Body.String())
+			}
+			if tt.expected == "" {
+				return
+			}
+			// Reception time and device depend on the test run, keys are sorted once decoded
+			var document map[string]interface{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &document); err != nil {
+				t.Fatal(err)
+			}
+			delete(document, "time")
+			delete(document, "device")
+			if body, _ := json.Marshal(document); string(body) != tt.expected {
+				t.Errorf("got %s, want %s", body, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package prom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/tariff"
+)
+
+// CostCollector object to collect energy and subscription costs of each meter
+type CostCollector struct {
+	engine       *tariff.Engine
+	meters       []string
+	energy       *metricFamily
+	subscription *metricFamily
+}
+
+// NewCostCollector method to construct CostCollector
+func NewCostCollector(engine *tariff.Engine, meters []string, labels LabelsConfig) (*CostCollector, error) {
+	collector := &CostCollector{
+		engine:       engine,
+		meters:       meters,
+		energy:       newMetricFamily(labels, "linky_energy_cost_euros_total", "Cost of the energy consumed since the exporter started, by tariff index", prometheus.CounterValue, config.MeterLabel, "index"),
+		subscription: newMetricFamily(labels, "linky_subscription_cost_euros_total", "Cost of the subscription elapsed since the exporter started", prometheus.CounterValue, config.MeterLabel),
+	}
+	if err := familiesError(collector.energy, collector.subscription); err != nil {
+		return nil, err
+	}
+	return collector, nil
+}
+
+// Describe implements required describe function for all prometheus collectors
+func (collector *CostCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.energy.desc
+	ch <- collector.subscription.desc
+}
+
+// Collect implements required collect function for all prometheus collectors
+func (collector *CostCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, meter := range collector.meters {
+		costs, ok := collector.engine.Costs(meter)
+		if !ok {
+			continue
+		}
+		for index, cost := range costs.Energy {
+			ch <- collector.energy.metric(cost, meter, index)
+		}
+		ch <- collector.subscription.metric(costs.Subscription, meter)
+	}
+}
@@ -0,0 +1,119 @@
+package prom
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// JSON document of the last events
+type apiEvents struct {
+	Events []core.Event `json:"events"`
+}
+
+// Ring buffer keeping the last events of all meters
+type eventLog struct {
+	mutex  sync.Mutex
+	events []core.Event
+	next   int // Position of the next event
+	count  int
+}
+
+// Build an empty event log keeping size events
+func newEventLog(size int) *eventLog {
+	return &eventLog{events: make([]core.Event, size)}
+}
+
+// Change the number of events kept, the oldest ones are dropped when it shrinks
+func (events *eventLog) resize(size int) {
+	events.mutex.Lock()
+	defer events.mutex.Unlock()
+	if size == len(events.events) {
+		return
+	}
+
+	kept := events.ordered()
+	if len(kept) > size {
+		kept = kept[len(kept)-size:]
+	}
+	events.events = make([]core.Event, size)
+	copy(events.events, kept)
+	events.count = len(kept)
+	events.next = 0
+	if size > 0 {
+		events.next = len(kept) % size
+	}
+}
+
+// Add an event, replacing the oldest one when full
+func (events *eventLog) add(event core.Event) {
+	events.mutex.Lock()
+	defer events.mutex.Unlock()
+	if len(events.events) == 0 {
+		return
+	}
+
+	events.events[events.next] = event
+	events.next = (events.next + 1) % len(events.events)
+	if events.count < len(events.events) {
+		events.count++
+	}
+}
+
+// Events from the oldest to the newest, the mutex must be held
+func (events *eventLog) ordered() []core.Event {
+	ordered := make([]core.Event, 0, events.count)
+	if events.count == 0 {
+		return ordered
+	}
+	start := (events.next - events.count + len(events.events)) % len(events.events)
+	for i := 0; i < events.count; i++ {
+		ordered = append(ordered, events.events[(start+i)%len(events.events)])
+	}
+	return ordered
+}
+
+// Events of a meter and type after a time, all meters or types when empty, limited to the newest ones when limit is not 0
+func (events *eventLog) list(meter string, eventType string, since time.Time, limit int) []core.Event {
+	events.mutex.Lock()
+	defer events.mutex.Unlock()
+
+	selected := []core.Event{}
+	for _, event := range events.ordered() {
+		if (meter == "" || event.Meter == meter) && (eventType == "" || string(event.Type) == eventType) && (since.IsZero() || event.Time.After(since)) {
+			selected = append(selected, event)
+		}
+	}
+	if limit > 0 && len(selected) > limit {
+		selected = selected[len(selected)-limit:]
+	}
+	return selected
+}
+
+// Handle GET /api/v1/events requests, returning the last events from the oldest to the newest
+func (exporter *LinkyExporter) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "Only GET requests allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Events of meters removed from the configuration are kept
+	query := r.URL.Query()
+	since, err := parseApiTime(query.Get("since"), time.Time{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			http.Error(w, "Invalid limit "+value, http.StatusBadRequest)
+			return
+		}
+	}
+	writeJson(w, apiEvents{Events: exporter.events.list(query.Get("meter"), query.Get("type"), since, limit)})
+}
@@ -0,0 +1,75 @@
+package prom
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+func TestEventsHandlerTableDriven(t *testing.T) {
+	// Given
+	linkyConfig := testConfig("/dev/null")
+	linkyConfig.Events.Size = 3
+	exporter := NewLinkyExporter(linkyConfig, "")
+	start := time.Date(2023, 6, 14, 12, 0, 0, 0, time.UTC)
+	for i, event := range []core.Event{
+		{Meter: "null", Type: core.TariffChanged, Field: "price_label", From: "HC", To: "HP"},
+		{Meter: "null", Type: core.RelayToggled, Field: "relay_1", From: "off", To: "on"},
+		{Meter: "null", Type: core.SurgeStarted, Field: "status_surge"},
+		{Meter: "other", Type: core.TariffChanged, Field: "price_label", From: "HP", To: "HC"},
+	} {
+		event.Time = start.Add(time.Duration(i) * time.Minute)
+		exporter.publishEvent(event)
+	}
+
+	var tests = []struct {
+		name     string
+		query    string
+		status   int
+		expected string
+	}{
+		{"oldest dropped", "", http.StatusOK, `{"events":[{"time":"2023-06-14T12:01:00Z","meter":"null","type":"relay_toggled","field":"relay_1","from":"off","to":"on"},{"time":"2023-06-14T12:02:00Z","meter":"null","type":"surge_started","field":"status_surge"},{"time":"2023-06-14T12:03:00Z","meter":"other"`},
+		{"meter and type", "meter=other&type=tariff_changed", http.StatusOK, `{"events":[{"time":"2023-06-14T12:03:00Z","meter":"other","type":"tariff_changed","field":"price_label","from":"HP","to":"HC"}]}`},
+		{"since and limit", "since=2023-06-14T12:01:00Z&limit=1", http.StatusOK, `{"events":[{"time":"2023-06-14T12:03:00Z"`},
+		{"none", "type=overvoltage_started", http.StatusOK, `{"events":[]}`},
+		{"invalid limit", "limit=-1", http.StatusBadRequest, "Invalid limit -1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			recorder := httptest.NewRecorder()
+			exporter.eventsHandler(recorder, httptest.NewRequest("GET", "/api/v1/events?"+tt.query, nil))
+
+			// Then
+			if recorder.Code != tt.status || !strings.Contains(recorder.Body.String(), tt.expected) {
+				t.Errorf("got %d : %s", recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestEventLogResize(t *testing.T) {
+	// Given
+	events := newEventLog(3)
+	for i := 0; i < 5; i++ {
+		events.add(core.Event{Field: string(rune('a' + i))})
+	}
+
+	// When
+	events.resize(2)
+	events.add(core.Event{Field: "f"})
+
+	// Then
+	var fields []string
+	for _, event := range events.list("", "", time.Time{}, 0) {
+		fields = append(fields, event.Field)
+	}
+	if strings.Join(fields, "") != "ef" {
+		t.Errorf("got %v, want [e f]", fields)
+	}
+}
@@ -1,27 +1,556 @@
 package prom
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
 	log "github.com/sirupsen/logrus"
+	"github.com/syberalexis/linky-exporter/pkg/alert"
+	"github.com/syberalexis/linky-exporter/pkg/config"
 	"github.com/syberalexis/linky-exporter/pkg/core"
+	"github.com/syberalexis/linky-exporter/pkg/output"
+	"github.com/syberalexis/linky-exporter/pkg/period"
+	"github.com/syberalexis/linky-exporter/pkg/shedding"
+	"github.com/syberalexis/linky-exporter/pkg/solar"
+	"github.com/syberalexis/linky-exporter/pkg/store"
+	"github.com/syberalexis/linky-exporter/pkg/tariff"
 )
 
+// Delay given to in-flight requests on shutdown
+const shutdownTimeout = 30 * time.Second
+
 // LinkyExporter object to run exporter server and expose metrics
 type LinkyExporter struct {
-	Address string
-	Port    int
+	configFile string
+	config     *config.Config
+	registry   *prometheus.Registry
+	meters     map[string]*core.Meter
+	collectors []prometheus.Collector
+	mutex      sync.Mutex
+
+	outputsConfig *config.Config // Configuration the outputs were built with
+	outputs       []output.Output
+	history       *store.Store
+	periods       *period.Tracker
+	alerts        *alert.Watcher
+	shedding      *shedding.Engine
+	outputsMutex  sync.RWMutex // Separated from mutex as meters publish while being stopped, guards outputs, history, periods, alerts and shedding
+
+	publishEvents bool // Whether events are published to outputs, guarded by outputsMutex
+
+	stream     *frameStream
+	events     *eventLog
+	tariffs    *tariff.Engine
+	solar      *solar.Tracker
+	solarInput *solar.MqttInput // Replaced under mutex
+}
+
+// Build a registry with process metrics, replaced on each configuration as label names can not change in a registry
+func newRegistry(linkyCollectors []prometheus.Collector) (*prometheus.Registry, error) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	for _, collector := range linkyCollectors {
+		if err := registry.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// NewLinkyExporter method to construct LinkyExporter, the configuration file is reloaded on demand when set
+func NewLinkyExporter(config *config.Config, configFile string) *LinkyExporter {
+	return &LinkyExporter{
+		configFile: configFile,
+		config:     config,
+		meters:     make(map[string]*core.Meter),
+		stream:     newFrameStream(),
+		events:     newEventLog(config.Events.Size),
+		tariffs:    tariff.NewEngine(),
+		solar:      solar.NewTracker(),
+	}
 }
 
-// Run method to run http exporter server
-func (exporter *LinkyExporter) Run(connector core.LinkyConnector) {
-	log.Info(fmt.Sprintf("Beginning to serve on port :%d", exporter.Port))
+// Run method to run http exporter server until SIGTERM or SIGINT, then drain requests and close connectors
+func (exporter *LinkyExporter) Run() error {
+	if err := exporter.apply(exporter.config); err != nil {
+		return err
+	}
+
+	// Reload on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := exporter.Reload(); err != nil {
+				log.Errorf("Failed to reload configuration : %s", err)
+			}
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(exporter, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/-/reload", exporter.reloadHandler)
+	mux.HandleFunc("/probe", exporter.probeHandler)
+	mux.HandleFunc("/-/healthy", exporter.healthyHandler)
+	mux.HandleFunc("/-/ready", exporter.readyHandler)
+	mux.HandleFunc("/api/v1/frame", exporter.apiFrameHandler)
+	mux.HandleFunc("/api/v1/raw", exporter.apiRawHandler)
+	mux.HandleFunc("/api/v1/meter", exporter.apiMeterHandler)
+	mux.HandleFunc("/api/v1/reactive", exporter.apiReactiveHandler)
+	mux.HandleFunc("/api/v1/stream", exporter.streamHandler)
+	mux.HandleFunc("/api/v1/ws", exporter.websocketHandler)
+	mux.HandleFunc("/api/v1/history", exporter.historyHandler)
+	mux.HandleFunc("/api/v1/events", exporter.eventsHandler)
+	mux.HandleFunc("/api/v1/solar/production", exporter.solarProductionHandler)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+	server := &http.Server{Handler: mux}
+	errs := make(chan error, 1)
+	log.Info(fmt.Sprintf("Beginning to serve on %s", exporter.config.Web.ListenAddress))
+	go func() { errs <- serve(server, exporter.config.Web) }()
+	notifyCtx, stopNotify := context.WithCancel(context.Background())
+	defer stopNotify()
+	go exporter.notifySystemd(notifyCtx)
+
+	select {
+	case err := <-errs:
+		exporter.stop()
+		return err
+	case sig := <-stop:
+		log.Infof("Received %s, shutting down", sig)
+	}
+
+	// Streams never end by themselves, close them so that shutdown does not wait for them
+	stopNotify()
+	exporter.stream.close()
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := server.Shutdown(ctx)
+	exporter.stop()
+	log.Info("Exporter stopped")
+	return err
+}
+
+// Stop all meters, closing their connections, then outputs, history, periods, alerts and shedding
+func (exporter *LinkyExporter) stop() {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+
+	for _, meter := range exporter.meters {
+		meter.Stop()
+	}
+	exporter.replaceOutputs(nil)
+	exporter.replaceHistory(nil)
+	exporter.replacePeriods(nil)
+	exporter.replaceAlerts(nil)
+	exporter.replaceShedding(nil)
+	exporter.replaceSolarInput(nil)
+}
+
+// Build the gatherer of the metrics of a frame, as exposed on /metrics
+func frameGatherer(schema MetricsSchema, labels LabelsConfig, meterLabel bool) output.FrameGatherer {
+	return func(meter string, frame core.Frame) ([]*dto.MetricFamily, error) {
+		meterLabels := labels
+		if meterLabel {
+			meterLabels = labels.withConstLabel(config.MeterLabel, meter)
+		}
+		collector, err := NewFrameCollector(meter, frame, schema, meterLabels)
+		if err != nil {
+			return nil, err
+		}
+
+		registry := prometheus.NewRegistry()
+		if err := registry.Register(collector); err != nil {
+			return nil, err
+		}
+		return registry.Gather()
+	}
+}
+
+// Replace outputs, closing the previous ones
+func (exporter *LinkyExporter) replaceOutputs(outputs []output.Output) {
+	exporter.outputsMutex.Lock()
+	previous := exporter.outputs
+	exporter.outputs = outputs
+	exporter.outputsMutex.Unlock()
+
+	for _, previousOutput := range previous {
+		if err := previousOutput.Close(); err != nil {
+			log.Errorf("Failed to close output : %s", err)
+		}
+	}
+}
+
+// Replace the history store, closing the previous one
+func (exporter *LinkyExporter) replaceHistory(history *store.Store) {
+	exporter.outputsMutex.Lock()
+	previous := exporter.history
+	exporter.history = history
+	exporter.outputsMutex.Unlock()
+
+	if previous != nil && previous != history {
+		if err := previous.Close(); err != nil {
+			log.Errorf("Failed to close store : %s", err)
+		}
+	}
+}
+
+// Replace the period tracker, saving the previous one
+func (exporter *LinkyExporter) replacePeriods(periods *period.Tracker) {
+	exporter.outputsMutex.Lock()
+	previous := exporter.periods
+	exporter.periods = periods
+	exporter.outputsMutex.Unlock()
+
+	if previous != nil && previous != periods {
+		if err := previous.Close(); err != nil {
+			log.Errorf("Failed to save period totals : %s", err)
+		}
+	}
+}
+
+// Replace the headroom alert watcher, closing the previous one
+func (exporter *LinkyExporter) replaceAlerts(alerts *alert.Watcher) {
+	exporter.outputsMutex.Lock()
+	previous := exporter.alerts
+	exporter.alerts = alerts
+	exporter.outputsMutex.Unlock()
+
+	if previous != nil && previous != alerts {
+		previous.Close()
+	}
+}
+
+// Replace the MQTT production input, closing the previous one, the mutex must be held
+func (exporter *LinkyExporter) replaceSolarInput(input *solar.MqttInput) {
+	if exporter.solarInput != nil && exporter.solarInput != input {
+		exporter.solarInput.Close()
+	}
+	exporter.solarInput = input
+}
+
+// Replace the load shedding engine, closing the previous one
+func (exporter *LinkyExporter) replaceShedding(engine *shedding.Engine) {
+	exporter.outputsMutex.Lock()
+	previous := exporter.shedding
+	exporter.shedding = engine
+	exporter.outputsMutex.Unlock()
+
+	if previous != nil && previous != engine {
+		previous.Close()
+	}
+}
+
+// Publish a frame of a meter to all outputs, streams, history, periods, alerts and shedding
+func (exporter *LinkyExporter) publish(meter string, frame core.Frame) {
+	exporter.stream.broadcast(meter, frame)
+	exporter.tariffs.Add(meter, frame)
+	exporter.solar.Add(meter, frame)
+
+	exporter.outputsMutex.RLock()
+	defer exporter.outputsMutex.RUnlock()
+
+	for _, output := range exporter.outputs {
+		output.Publish(meter, frame)
+	}
+	if exporter.history != nil {
+		if err := exporter.history.Record(meter, frame); err != nil {
+			log.Error(err)
+		}
+	}
+	if exporter.periods != nil {
+		exporter.periods.Add(meter, frame)
+	}
+	if exporter.alerts != nil {
+		exporter.alerts.Add(meter, frame)
+	}
+	if exporter.shedding != nil {
+		exporter.shedding.Add(meter, frame)
+	}
+}
+
+// Record an event of a meter, publishing it to outputs when enabled
+func (exporter *LinkyExporter) publishEvent(event core.Event) {
+	log.Debugf("Event %s of %s on %s", event.Type, event.Meter, event.Field)
+	exporter.events.add(event)
+
+	exporter.outputsMutex.RLock()
+	defer exporter.outputsMutex.RUnlock()
+	if !exporter.publishEvents {
+		return
+	}
+	for _, output := range exporter.outputs {
+		output.PublishEvent(event)
+	}
+}
+
+// Gather implements prometheus.Gatherer with the registry of the current configuration
+func (exporter *LinkyExporter) Gather() ([]*dto.MetricFamily, error) {
+	exporter.mutex.Lock()
+	registry := exporter.registry
+	exporter.mutex.Unlock()
+
+	return registry.Gather()
+}
+
+// Reload method to load the configuration file again and apply its changes
+func (exporter *LinkyExporter) Reload() error {
+	if exporter.configFile == "" {
+		return fmt.Errorf("No configuration file to reload")
+	}
+
+	newConfig, err := config.Load(exporter.configFile)
+	if err != nil {
+		return err
+	}
+	if err := exporter.apply(newConfig); err != nil {
+		return err
+	}
+	log.Info("Configuration reloaded")
+	return nil
+}
+
+// Handle POST /-/reload requests
+func (exporter *LinkyExporter) reloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Only POST requests allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := exporter.Reload(); err != nil {
+		log.Errorf("Failed to reload configuration : %s", err)
+		http.Error(w, fmt.Sprintf("Failed to reload configuration : %s", err), http.StatusInternalServerError)
+	}
+}
+
+// Apply a configuration, meters keep reading when their device settings did not change
+func (exporter *LinkyExporter) apply(newConfig *config.Config) error {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+
+	schema, err := ParseMetricsSchema(newConfig.Metrics.Schema)
+	if err != nil {
+		return err
+	}
+	labels := NewLabelsConfig(newConfig.Metrics)
+
+	previousDevices := make(map[string]config.DeviceConfig)
+	for _, device := range exporter.config.Devices {
+		previousDevices[device.Name] = device
+	}
+
+	// Build meters and their collectors, a meter labels its metrics when several are configured
+	meters := make(map[string]*core.Meter)
+	var meterList []*core.Meter
+	var newCollectors []prometheus.Collector
+	for _, device := range newConfig.Devices {
+		meter, exists := exporter.meters[device.Name]
+		if !exists || previousDevices[device.Name] != device {
+			connector, err := device.Connector()
+			if err != nil {
+				return err
+			}
+			meter = core.NewMeter(device.Name, connector)
+			name := device.Name
+			meter.Listen(func(frame core.Frame) { exporter.publish(name, frame) })
+			meter.Observe(exporter.publishEvent)
+		}
+
+		meterLabels := labels
+		if len(newConfig.Devices) > 1 {
+			meterLabels = labels.withConstLabel(config.MeterLabel, device.Name)
+		}
+		collector, err := NewLinkyCollector(meter, schema, meterLabels)
+		if err != nil {
+			return err
+		}
+
+		meters[device.Name] = meter
+		meterList = append(meterList, meter)
+		newCollectors = append(newCollectors, collector)
+	}
+
+	// Derived collectors are only checked once built, as some wrap controllers to discard on failure
+	var collectorsErr error
+	addCollector := func(collector prometheus.Collector, err error) {
+		if err != nil {
+			if collectorsErr == nil {
+				collectorsErr = err
+			}
+			return
+		}
+		newCollectors = append(newCollectors, collector)
+	}
+	addCollector(NewPowerCollector(meterList, labels))
+	addCollector(NewQualityCollector(meterList, labels))
+	addCollector(NewHealthCollector(meterList, newConfig.FrameTimeout, labels))
+
+	var names []string
+	for _, device := range newConfig.Devices {
+		names = append(names, device.Name)
+	}
+	var catalogue *tariff.Catalogue
+	if newConfig.TariffFile != "" {
+		if catalogue, err = tariff.Load(newConfig.TariffFile); err != nil {
+			return err
+		}
+		addCollector(NewCostCollector(exporter.tariffs, names, labels))
+	}
+
+	if newConfig.Solar != nil {
+		addCollector(NewSolarCollector(exporter.solar, newConfig.Solar.GridMeter, labels))
+	}
+
+	// Period totals are only loaded again when their state file changes, as they are saved while running
+	periods := exporter.periods
+	if newConfig.Periods == nil {
+		periods = nil
+	} else if periods == nil || periods.StateFile() != newConfig.Periods.StateFile {
+		if periods, err = period.Open(*newConfig.Periods); err != nil {
+			return err
+		}
+	}
+	if periods != nil {
+		addCollector(NewPeriodCollector(periods, names, labels))
+	}
+
+	// The alert watcher is only built again when its configuration changes, to keep pending alerts and hook connections
+	alerts := exporter.alerts
+	if newConfig.Headroom == nil {
+		alerts = nil
+	} else if alerts == nil || !alerts.Configured(*newConfig.Headroom) {
+		alerts = alert.NewWatcher(*newConfig.Headroom, alert.NewHooks(newConfig.Headroom.Hooks))
+	}
+	if alerts != nil {
+		addCollector(NewAlertCollector(alerts, names, labels))
+	}
+	// The load shedding engine is only built again when its configuration changes, to keep actuator states
+	engine := exporter.shedding
+	if newConfig.LoadShedding == nil {
+		engine = nil
+	} else if engine == nil || !engine.Configured(*newConfig.LoadShedding) {
+		engine = shedding.NewEngine(*newConfig.LoadShedding, shedding.NewActuators(newConfig.LoadShedding.Actuators))
+	}
+	if engine != nil {
+		addCollector(NewSheddingCollector(engine, labels))
+	}
+	discardControllers := func() {
+		if alerts != nil && alerts != exporter.alerts {
+			alerts.Close()
+		}
+		if engine != nil && engine != exporter.shedding {
+			engine.Close()
+		}
+	}
+
+	if collectorsErr != nil {
+		discardControllers()
+		return collectorsErr
+	}
+	registry, err := newRegistry(newCollectors)
+	if err != nil {
+		discardControllers()
+		return err
+	}
+
+	// The store is only opened again when its file changes, as it is locked while open
+	history := exporter.history
+	if newConfig.Store == nil {
+		history = nil
+	} else if history == nil || history.Path() != newConfig.Store.Path {
+		if history, err = store.Open(*newConfig.Store); err != nil {
+			discardControllers()
+			return err
+		}
+	}
+
+	var outputs []output.Output
+	outputsChanged := exporter.outputsConfig == nil ||
+		!reflect.DeepEqual(exporter.outputsConfig.Outputs, newConfig.Outputs) ||
+		!reflect.DeepEqual(exporter.outputsConfig.Metrics, newConfig.Metrics) ||
+		len(exporter.outputsConfig.Devices) > 1 != (len(newConfig.Devices) > 1)
+	if outputsChanged {
+		gather := frameGatherer(schema, labels, len(newConfig.Devices) > 1)
+		if outputs, err = output.NewOutputs(newConfig.Outputs, newConfig.Metrics, gather); err != nil {
+			if history != nil && history != exporter.history {
+				history.Close()
+			}
+			discardControllers()
+			return err
+		}
+	}
+
+	exporter.stream.configure(newConfig.Metrics)
+
+	// Stop replaced meters before starting new ones, they may share a device
+	for name, meter := range exporter.meters {
+		if meters[name] != meter {
+			log.Infof("Device %s changed, disconnecting", name)
+			meter.Stop()
+		}
+	}
+	// Meters are only configured once the configuration is accepted, as kept meters are running
+	estimation := core.EstimationConfig{
+		PowerWindow:     newConfig.PowerWindow,
+		TanPhiWindows:   newConfig.TanPhi.Windows,
+		TanPhiThreshold: newConfig.TanPhi.Threshold,
+	}
+	for name, meter := range meters {
+		meter.Configure(estimation)
+		if exporter.meters[name] != meter {
+			meter.Start(context.Background())
+		}
+	}
+	exporter.meters = meters
+	exporter.collectors = newCollectors
+	exporter.registry = registry
 
-	prometheus.MustRegister(NewLinkyCollector(connector))
-	http.Handle("/metrics", promhttp.Handler())
+	if outputsChanged {
+		exporter.replaceOutputs(outputs)
+		exporter.outputsConfig = newConfig
+	}
+	if history != nil {
+		history.Configure(*newConfig.Store)
+	}
+	exporter.replaceHistory(history)
+	exporter.tariffs.SetCatalogue(catalogue)
+	if periods != nil {
+		periods.Configure(*newConfig.Periods)
+	}
+	exporter.replacePeriods(periods)
+	exporter.replaceAlerts(alerts)
+	exporter.replaceShedding(engine)
+	exporter.events.resize(newConfig.Events.Size)
+	exporter.outputsMutex.Lock()
+	exporter.publishEvents = newConfig.Events.Publish
+	exporter.outputsMutex.Unlock()
+	var dayOffset *time.Duration
+	if newConfig.Periods != nil {
+		dayOffset = newConfig.Periods.DayOffset
+	}
+	exporter.solar.Configure(newConfig.Solar, dayOffset)
+	if newConfig.Solar == nil || newConfig.Solar.Production.Mqtt == nil {
+		exporter.replaceSolarInput(nil)
+	} else if exporter.solarInput == nil || !exporter.solarInput.Configured(*newConfig.Solar.Production.Mqtt) {
+		exporter.replaceSolarInput(solar.NewMqttInput(*newConfig.Solar.Production.Mqtt, exporter.solar))
+	}
 
-	log.Fatal(http.ListenAndServe(fmt.Sprintf("%s:%d", exporter.Address, exporter.Port), nil))
+	if exporter.config.Web != newConfig.Web {
+		log.Warn("Web configuration changes are only applied after a restart")
+	}
+	exporter.config = newConfig
+	return nil
 }
@@ -0,0 +1,67 @@
+package prom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+)
+
+func testConfig(devices ...string) *config.Config {
+	linkyConfig := &config.Config{}
+	for _, device := range devices {
+		linkyConfig.Devices = append(linkyConfig.Devices, config.DeviceConfig{Name: strings.TrimPrefix(device, "/dev/"), Device: device, Mode: "historical"})
+	}
+	linkyConfig.SetDefaults()
+	return linkyConfig
+}
+
+func TestExporterApplyMeters(t *testing.T) {
+	// Given
+	exporter := NewLinkyExporter(testConfig("/dev/null", "/dev/zero"), "")
+	expected := `
+# HELP linky_meter_up Whether a frame was received from the meter within the frame timeout
+# TYPE linky_meter_up gauge
+linky_meter_up{meter="null"} 0
+linky_meter_up{meter="zero"} 0
+`
+
+	// When
+	err := exporter.apply(exporter.config)
+	defer exporter.apply(testConfig())
+
+	// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := testutil.GatherAndCompare(exporter.registry, strings.NewReader(expected), "linky_meter_up"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExporterApplyKeepsMeters(t *testing.T) {
+	// Given
+	exporter := NewLinkyExporter(testConfig("/dev/null", "/dev/zero"), "")
+	if err := exporter.apply(exporter.config); err != nil {
+		t.Fatal(err)
+	}
+	kept := exporter.meters["null"]
+
+	// When
+	newConfig := testConfig("/dev/null")
+	newConfig.Metrics.Schema = string(SchemaV2)
+	err := exporter.apply(newConfig)
+	defer exporter.apply(testConfig())
+
+	// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exporter.meters["null"] != kept || exporter.meters["zero"] != nil {
+		t.Error("unchanged meter must be kept and removed meter dropped")
+	}
+	if count := testutil.CollectAndCount(exporter.collectors[len(exporter.collectors)-1]); count != 4 {
+		t.Errorf("got %d health metrics, want 4", count)
+	}
+}
@@ -0,0 +1,66 @@
+package prom
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// HealthCollector object to collect the reading state of each meter
+type HealthCollector struct {
+	meters    []*core.Meter
+	timeout   time.Duration
+	up        *metricFamily
+	info      *metricFamily
+	lastFrame *metricFamily
+	frames    *metricFamily
+	errors    *metricFamily
+}
+
+// NewHealthCollector method to construct HealthCollector
+func NewHealthCollector(meters []*core.Meter, timeout time.Duration, labels LabelsConfig) (*HealthCollector, error) {
+	names := []string{config.MeterLabel}
+	collector := &HealthCollector{
+		meters:    meters,
+		timeout:   timeout,
+		up:        newMetricFamily(labels, "linky_meter_up", "Whether a frame was received from the meter within the frame timeout", prometheus.GaugeValue, names...),
+		info:      newMetricFamily(labels, "linky_meter_info", "Device and detected TIC mode of the meter", prometheus.GaugeValue, config.MeterLabel, "device", "mode"),
+		lastFrame: newMetricFamily(labels, "linky_meter_last_frame_timestamp_seconds", "Reception time of the last frame", prometheus.GaugeValue, names...),
+		frames:    newMetricFamily(labels, "linky_meter_frames_total", "Number of decoded frames", prometheus.CounterValue, names...),
+		errors:    newMetricFamily(labels, "linky_meter_read_errors_total", "Number of reading errors", prometheus.CounterValue, names...),
+	}
+	if err := familiesError(collector.up, collector.info, collector.lastFrame, collector.frames, collector.errors); err != nil {
+		return nil, err
+	}
+	return collector, nil
+}
+
+// Describe implements required describe function for all prometheus collectors
+func (collector *HealthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.up.desc
+	ch <- collector.info.desc
+	ch <- collector.lastFrame.desc
+	ch <- collector.frames.desc
+	ch <- collector.errors.desc
+}
+
+// Collect implements required collect function for all prometheus collectors
+func (collector *HealthCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, meter := range collector.meters {
+		health := meter.Health()
+		up := 0.0
+		if health.Up(collector.timeout) {
+			up = 1
+		}
+
+		ch <- collector.up.metric(up, health.Name)
+		ch <- collector.info.metric(1, health.Name, health.Device, health.Mode.String())
+		ch <- collector.frames.metric(float64(health.Frames), health.Name)
+		ch <- collector.errors.metric(float64(health.Errors), health.Name)
+		if !health.LastFrame.IsZero() {
+			ch <- collector.lastFrame.metric(float64(health.LastFrame.UnixMilli())/1000, health.Name)
+		}
+	}
+}
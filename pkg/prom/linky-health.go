@@ -0,0 +1,61 @@
+package prom
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Handle /-/healthy requests, the exporter is healthy while it serves requests
+func (exporter *LinkyExporter) healthyHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintln(w, "Linky exporter is Healthy.")
+}
+
+// Handle /-/ready requests, the exporter is ready when each meter decoded a frame within the frame timeout
+func (exporter *LinkyExporter) readyHandler(w http.ResponseWriter, r *http.Request) {
+	ready, report := exporter.ready()
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, "Linky exporter is not Ready.\n"+report)
+		return
+	}
+	fmt.Fprint(w, "Linky exporter is Ready.\n"+report)
+}
+
+// Return whether all meters are up, and the state of each meter
+func (exporter *LinkyExporter) ready() (bool, string) {
+	healths, timeout := exporter.healths()
+
+	ready := true
+	var report strings.Builder
+	for _, health := range healths {
+		switch {
+		case health.LastFrame.IsZero():
+			report.WriteString(fmt.Sprintf("%s : no frame received\n", health.Name))
+		case !health.Up(timeout):
+			report.WriteString(fmt.Sprintf("%s : last frame %s ago\n", health.Name, time.Since(health.LastFrame).Round(time.Second)))
+		default:
+			report.WriteString(fmt.Sprintf("%s : up\n", health.Name))
+			continue
+		}
+		ready = false
+	}
+	return ready, report.String()
+}
+
+// Return the health of each meter sorted by name, and the frame timeout
+func (exporter *LinkyExporter) healths() ([]core.MeterHealth, time.Duration) {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+
+	var healths []core.MeterHealth
+	for _, meter := range exporter.meters {
+		healths = append(healths, meter.Health())
+	}
+	sort.Slice(healths, func(i, j int) bool { return healths[i].Name < healths[j].Name })
+	return healths, exporter.config.FrameTimeout
+}
@@ -0,0 +1,40 @@
+package prom
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadyHandlerTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name     string
+		devices  []string
+		expected int
+		report   string
+	}{
+		{"probe only", nil, http.StatusOK, "Linky exporter is Ready.\n"},
+		{"no frame", []string{"/dev/null"}, http.StatusServiceUnavailable, "null : no frame received\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := NewLinkyExporter(testConfig(tt.devices...), "")
+			if err := exporter.apply(exporter.config); err != nil {
+				t.Fatal(err)
+			}
+			defer exporter.stop()
+
+			// When
+			recorder := httptest.NewRecorder()
+			exporter.readyHandler(recorder, httptest.NewRequest("GET", "/-/ready", nil))
+
+			// Then
+			if recorder.Code != tt.expected || !strings.HasSuffix(recorder.Body.String(), tt.report) {
+				t.Errorf("got %d : %s", recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
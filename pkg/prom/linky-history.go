@@ -0,0 +1,121 @@
+package prom
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/store"
+)
+
+const (
+	defaultHistoryRange = 24 * time.Hour // Range queried without from parameter
+	maxHistoryPoints    = 11000          // Maximum number of aggregated points, as Prometheus
+)
+
+// JSON document of the history of a meter
+type apiHistory struct {
+	Meter  string        `json:"meter"`
+	From   time.Time     `json:"from"`
+	To     time.Time     `json:"to"`
+	Step   float64       `json:"step_seconds,omitempty"`
+	Points []store.Point `json:"points"`
+}
+
+// Handle GET /api/v1/history requests, returning recorded values of a meter between from and to, aggregated by step
+func (exporter *LinkyExporter) historyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "Only GET requests allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	exporter.outputsMutex.RLock()
+	history := exporter.history
+	exporter.outputsMutex.RUnlock()
+	if history == nil {
+		http.Error(w, "History store not configured", http.StatusNotFound)
+		return
+	}
+
+	// Meters removed from the configuration keep their history
+	query := r.URL.Query()
+	meter := query.Get("meter")
+	if meter == "" {
+		selected, status, err := exporter.selectMeter("")
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		meter = selected.Name
+	}
+
+	to, err := parseApiTime(query.Get("to"), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	from, err := parseApiTime(query.Get("from"), to.Add(-defaultHistoryRange))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	step, err := parseApiDuration(query.Get("step"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if to.Before(from) {
+		http.Error(w, "Parameter to must not be before from", http.StatusBadRequest)
+		return
+	}
+	if step > 0 && to.Sub(from)/step > maxHistoryPoints {
+		http.Error(w, fmt.Sprintf("Exceeded maximum of %d points, increase step", maxHistoryPoints), http.StatusBadRequest)
+		return
+	}
+
+	points, err := history.Query(meter, from, to, step)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if points == nil {
+		points = []store.Point{}
+	}
+	writeJson(w, apiHistory{Meter: meter, From: from, To: to, Step: step.Seconds(), Points: points})
+}
+
+// Parse a time as RFC 3339 or Unix seconds, the default is used when empty
+func parseApiTime(value string, defaultTime time.Time) (time.Time, error) {
+	if value == "" {
+		return defaultTime, nil
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		integer, fraction := math.Modf(seconds)
+		return time.Unix(int64(integer), int64(fraction*1e9)), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("Invalid time %s, expected RFC 3339 or Unix seconds", value)
+}
+
+// Parse a duration as Go duration or seconds, zero when empty
+func parseApiDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid duration %s, expected a duration as 5m or seconds", value)
+		}
+		duration = time.Duration(seconds * float64(time.Second))
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("Invalid duration %s, must be positive", value)
+	}
+	return duration, nil
+}
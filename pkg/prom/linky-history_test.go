@@ -0,0 +1,58 @@
+package prom
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/config"
+)
+
+func TestHistoryHandlerTableDriven(t *testing.T) {
+	// Given
+	linkyConfig := testConfig("/dev/null")
+	linkyConfig.Store = &config.StoreConfig{Path: filepath.Join(t.TempDir(), "linky.db")}
+	linkyConfig.SetDefaults()
+	exporter := NewLinkyExporter(linkyConfig, "")
+	if err := exporter.apply(exporter.config); err != nil {
+		t.Fatal(err)
+	}
+	defer exporter.stop()
+	frame := streamFrame(t)
+	frame.Time = time.Now().Add(-time.Hour).Truncate(time.Hour)
+	at := frame.Time.Unix()
+	if err := exporter.history.Record("null", frame); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name     string
+		query    string
+		status   int
+		expected string
+	}{
+		{"raw", fmt.Sprintf("from=%d&to=%d", at-60, at+60), http.StatusOK, `"points":[{"time":"` + frame.Time.Format(time.RFC3339) + `","values":{"voltage_phase1":229}}]`},
+		{"step", "from=" + frame.Time.Add(-90*time.Minute).UTC().Format(time.RFC3339) + "&step=1h&meter=null", http.StatusOK, `"step_seconds":3600,"points":[{"time":"` + frame.Time.Add(30*time.Minute).Format(time.RFC3339) + `"`},
+		{"empty", "meter=other", http.StatusOK, `"points":[]`},
+		{"invalid step", "step=fast", http.StatusBadRequest, "Invalid duration fast"},
+		{"too many points", "step=1s", http.StatusBadRequest, "Exceeded maximum"},
+		{"to before from", "from=1686730000&to=1686729000", http.StatusBadRequest, "must not be before"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			recorder := httptest.NewRecorder()
+			exporter.historyHandler(recorder, httptest.NewRequest("GET", "/api/v1/history?"+tt.query, nil))
+
+			// Then
+			if recorder.Code != tt.status || !strings.Contains(recorder.Body.String(), tt.expected) {
+				t.Errorf("got %d : %s", recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
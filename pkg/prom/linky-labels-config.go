@@ -0,0 +1,84 @@
+package prom
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/syberalexis/linky-exporter/pkg/config"
+)
+
+// Wildcard matching all metric families when dropping labels
+const ALL_FAMILIES = "*"
+
+var labelNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// LabelsConfig object to customize labels of all metrics
+type LabelsConfig struct {
+	ConstLabels  map[string]string   // Labels added to every metric
+	MeterAliases map[string]string   // Value replacing linky_id, by meter identifier
+	IdHashSalt   string              // Salt to publish a hash instead of linky_id, disabled when empty
+	DropLabels   map[string][]string // Labels removed by metric family name, ALL_FAMILIES for every family
+}
+
+// Validate labels configuration
+func (config LabelsConfig) Validate() error {
+	for name := range config.ConstLabels {
+		if !labelNameRegex.MatchString(name) || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("Invalid constant label name : %s", name)
+		}
+	}
+	return nil
+}
+
+// ParseDropLabels parses family=label values into drop labels configuration
+func ParseDropLabels(values []string) (map[string][]string, error) {
+	dropLabels := make(map[string][]string)
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("Invalid drop label, expected family=label : %s", value)
+		}
+		dropLabels[parts[0]] = append(dropLabels[parts[0]], parts[1])
+	}
+	return dropLabels, nil
+}
+
+// Return true when the label must be removed from the metric family
+func (config LabelsConfig) isDropped(family string, label string) bool {
+	for _, name := range []string{family, ALL_FAMILIES} {
+		for _, dropped := range config.DropLabels[name] {
+			if dropped == label {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Return the published value of a meter identifier
+func (labels LabelsConfig) meterId(id string) string {
+	return config.MetricsConfig{MeterAliases: labels.MeterAliases, IdHashSalt: labels.IdHashSalt}.MeterId(id)
+}
+
+// NewLabelsConfig builds labels configuration from metrics configuration
+func NewLabelsConfig(metrics config.MetricsConfig) LabelsConfig {
+	return LabelsConfig{
+		ConstLabels:  metrics.Labels,
+		MeterAliases: metrics.MeterAliases,
+		IdHashSalt:   metrics.IdHashSalt,
+		DropLabels:   metrics.DropLabels,
+	}
+}
+
+// Return a copy of the configuration with an additional constant label
+func (config LabelsConfig) withConstLabel(name string, value string) LabelsConfig {
+	constLabels := map[string]string{name: value}
+	for key, labelValue := range config.ConstLabels {
+		if key != name {
+			constLabels[key] = labelValue
+		}
+	}
+	config.ConstLabels = constLabels
+	return config
+}
@@ -0,0 +1,57 @@
+package prom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+func TestLabelsConfigTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name     string
+		labels   LabelsConfig
+		expected string
+	}{
+		{"const labels", LabelsConfig{ConstLabels: map[string]string{"site": "home"}}, `linky_voltage_volts{linky_id="XXXX",phase="1",site="home"} 229`},
+		{"alias", LabelsConfig{MeterAliases: map[string]string{"XXXX": "main"}, IdHashSalt: "salt"}, `linky_voltage_volts{linky_id="main",phase="1"} 229`},
+		{"hash", LabelsConfig{IdHashSalt: "salt"}, `linky_voltage_volts{linky_id="6ca5b375dde030e3",phase="1"} 229`},
+		{"drop family", LabelsConfig{DropLabels: map[string][]string{"linky_voltage_volts": {"linky_id"}}}, `linky_voltage_volts{phase="1"} 229`},
+		{"drop all", LabelsConfig{DropLabels: map[string][]string{ALL_FAMILIES: {"linky_id"}}}, `linky_voltage_volts{phase="1"} 229`},
+		{"drop other", LabelsConfig{DropLabels: map[string][]string{"linky_current_amperes": {"linky_id"}}}, `linky_voltage_volts{linky_id="XXXX",phase="1"} 229`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			schema, err := newMetricsSchema(SchemaV2, tt.labels)
+			if err != nil {
+				t.Fatal(err)
+			}
+			measurement := triplePhaseMeasurement()
+			measurement.Samples = measurement.Filter(core.Voltage)[:1]
+			collector := measurementCollector{schema, measurement}
+			expected := "# HELP linky_voltage_volts RMS voltage in V\n# TYPE linky_voltage_volts gauge\n" + tt.expected + "\n"
+
+			// Then
+			if err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "linky_voltage_volts"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestLabelsConfigConflict(t *testing.T) {
+	// Given
+	labels := LabelsConfig{ConstLabels: map[string]string{"phase": "1"}}
+
+	// When
+	_, err := newMetricsSchema(SchemaV2, labels)
+
+	// Then
+	if err == nil {
+		t.Error("expected conflict error")
+	}
+}
@@ -0,0 +1,64 @@
+package prom
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Label holding the meter identifier
+const LINKY_ID = "linky_id"
+
+// Metric family object to build metrics sharing a name, a type and label names
+type metricFamily struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	labels    LabelsConfig
+	names     []string // Label names of the family before dropping
+	kept      []bool   // Whether each label is kept
+	err       error
+}
+
+// Construct a metric family with customized labels
+func newMetricFamily(labels LabelsConfig, name string, help string, valueType prometheus.ValueType, names ...string) *metricFamily {
+	family := &metricFamily{valueType: valueType, labels: labels, names: names, kept: make([]bool, len(names))}
+
+	var variableLabels []string
+	for i, label := range names {
+		if !labels.isDropped(name, label) {
+			family.kept[i] = true
+			variableLabels = append(variableLabels, label)
+		}
+		if _, exists := labels.ConstLabels[label]; exists {
+			family.err = fmt.Errorf("Constant label %s conflicts with %s label", label, name)
+		}
+	}
+
+	family.desc = prometheus.NewDesc(name, help, variableLabels, labels.ConstLabels)
+	return family
+}
+
+// Return the first label error of the families, such as a constant label conflicting with a variable one
+func familiesError(families ...*metricFamily) error {
+	for _, family := range families {
+		if family.err != nil {
+			return family.err
+		}
+	}
+	return nil
+}
+
+// Build a metric of the family, label values are given in label names order
+func (family *metricFamily) metric(value float64, labelValues ...string) prometheus.Metric {
+	var values []string
+	for i, labelValue := range labelValues {
+		if !family.kept[i] {
+			continue
+		}
+		if family.names[i] == LINKY_ID || family.names[i] == "prm" {
+			labelValue = family.labels.meterId(labelValue)
+		}
+		values = append(values, labelValue)
+	}
+	return prometheus.MustNewConstMetric(family.desc, family.valueType, value, values...)
+}
@@ -0,0 +1,52 @@
+package prom
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// MetricsSchema name of the exposed metrics naming
+type MetricsSchema string
+
+const (
+	SchemaV1 MetricsSchema = "v1" // Original french metrics, kept for existing dashboards
+	SchemaV2 MetricsSchema = "v2" // Metrics following Prometheus naming conventions
+)
+
+// Schema object to turn measurements into metrics
+type metricsSchema interface {
+	// All metric families of the schema
+	families() []*metricFamily
+	// Send to channel all metrics of a measurement
+	collect(ch chan<- prometheus.Metric, measurement *core.LinkyMeasurement)
+}
+
+// ParseMetricsSchema parses a schema name
+func ParseMetricsSchema(value string) (MetricsSchema, error) {
+	switch MetricsSchema(value) {
+	case SchemaV1, SchemaV2:
+		return MetricsSchema(value), nil
+	}
+	return "", fmt.Errorf("Unknown metrics schema : %s", value)
+}
+
+// Construct the metrics schema with customized labels
+func newMetricsSchema(schema MetricsSchema, labels LabelsConfig) (metricsSchema, error) {
+	if err := labels.Validate(); err != nil {
+		return nil, err
+	}
+
+	var built metricsSchema
+	if schema == SchemaV2 {
+		built = newSchemaV2(labels)
+	} else {
+		built = newSchemaV1(labels)
+	}
+
+	if err := familiesError(built.families()...); err != nil {
+		return nil, err
+	}
+	return built, nil
+}
@@ -0,0 +1,100 @@
+package prom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Collector of a fixed measurement
+type measurementCollector struct {
+	schema      metricsSchema
+	measurement *core.LinkyMeasurement
+}
+
+func (collector measurementCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, family := range collector.schema.families() {
+		ch <- family.desc
+	}
+}
+
+func (collector measurementCollector) Collect(ch chan<- prometheus.Metric) {
+	collector.schema.collect(ch, collector.measurement)
+}
+
+// Build a triple phase standard measurement
+func triplePhaseMeasurement() *core.LinkyMeasurement {
+	tic := core.StandardTicValue{}
+	tic.ParseParam("ADSC", []string{"XXXX", "7"})
+	tic.ParseParam("EAST", []string{"041585532", "F"})
+	tic.ParseParam("EASF01", []string{"041352473", "<"})
+	tic.ParseParam("URMS1", []string{"229", "H"})
+	tic.ParseParam("URMS2", []string{"231", "H"})
+	tic.ParseParam("URMS3", []string{"233", "H"})
+	tic.ParseParam("PREF", []string{"09", "E"})
+	tic.ParseParam("SINSTS", []string{"01700", "N"})
+	tic.ParseParam("SINSTS1", []string{"01000", "N"})
+	tic.ParseParam("SINSTS2", []string{"00500", "N"})
+	tic.ParseParam("SINSTS3", []string{"00200", "N"})
+	tic.ParseParam("SINSTI", []string{"00000", "F"})
+	return core.ConvertStandardTicValue(tic)
+}
+
+func TestSchemaV1TriplePhase(t *testing.T) {
+	// Given
+	collector := measurementCollector{newSchemaV1(LabelsConfig{}), triplePhaseMeasurement()}
+	expected := `
+# HELP linky_power Puissance apparente en VA
+# TYPE linky_power gauge
+linky_power{linky_id="XXXX",mode="produced",phase="0"} 0
+linky_power{linky_id="XXXX",mode="used",phase="0"} 1700
+linky_power{linky_id="XXXX",mode="used",phase="1"} 1000
+linky_power{linky_id="XXXX",mode="used",phase="2"} 500
+linky_power{linky_id="XXXX",mode="used",phase="3"} 200
+# HELP linky_voltage Tension efficace en V
+# TYPE linky_voltage gauge
+linky_voltage{linky_id="XXXX",phase="1"} 229
+linky_voltage{linky_id="XXXX",phase="2"} 231
+linky_voltage{linky_id="XXXX",phase="3"} 233
+`
+
+	// When
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "linky_power", "linky_voltage")
+
+	// Then
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSchemaV2TriplePhase(t *testing.T) {
+	// Given
+	collector := measurementCollector{newSchemaV2(LabelsConfig{}), triplePhaseMeasurement()}
+	expected := `
+# HELP linky_apparent_power_va Instantaneous apparent power in VA
+# TYPE linky_apparent_power_va gauge
+linky_apparent_power_va{direction="exported",linky_id="XXXX",phase="total"} 0
+linky_apparent_power_va{direction="imported",linky_id="XXXX",phase="1"} 1000
+linky_apparent_power_va{direction="imported",linky_id="XXXX",phase="2"} 500
+linky_apparent_power_va{direction="imported",linky_id="XXXX",phase="3"} 200
+linky_apparent_power_va{direction="imported",linky_id="XXXX",phase="total"} 1700
+# HELP linky_energy_active_imported_wh_total Active energy drawn from the grid in Wh, by supplier (F) or distributor (D) tariff index
+# TYPE linky_energy_active_imported_wh_total counter
+linky_energy_active_imported_wh_total{linky_id="XXXX",tariff_index="F1"} 4.1352473e+07
+linky_energy_active_imported_wh_total{linky_id="XXXX",tariff_index="total"} 4.1585532e+07
+# HELP linky_reference_power_va Subscribed apparent power in VA
+# TYPE linky_reference_power_va gauge
+linky_reference_power_va{linky_id="XXXX"} 9000
+`
+
+	// When
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "linky_apparent_power_va", "linky_energy_active_imported_wh_total", "linky_reference_power_va")
+
+	// Then
+	if err != nil {
+		t.Error(err)
+	}
+}
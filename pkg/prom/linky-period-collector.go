@@ -0,0 +1,67 @@
+package prom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/period"
+)
+
+// PeriodCollector object to collect calendar period totals of each meter
+type PeriodCollector struct {
+	tracker  *period.Tracker
+	meters   []string
+	energy   *metricFamily
+	exported *metricFamily
+	balance  *metricFamily
+}
+
+// NewPeriodCollector method to construct PeriodCollector
+func NewPeriodCollector(tracker *period.Tracker, meters []string, labels LabelsConfig) (*PeriodCollector, error) {
+	collector := &PeriodCollector{
+		tracker:  tracker,
+		meters:   meters,
+		energy:   newMetricFamily(labels, "linky_energy_period_wh", "Energy consumed over the current or previous calendar period, by tariff index", prometheus.GaugeValue, config.MeterLabel, "period", "index", "window"),
+		exported: newMetricFamily(labels, "linky_energy_exported_period_wh", "Energy injected into the grid over the current or previous calendar period", prometheus.GaugeValue, config.MeterLabel, "period", "window"),
+		balance:  newMetricFamily(labels, "linky_grid_balance_period_wh", "Energy consumed minus energy injected over the current or previous calendar period", prometheus.GaugeValue, config.MeterLabel, "period", "window"),
+	}
+	if err := familiesError(collector.energy, collector.exported, collector.balance); err != nil {
+		return nil, err
+	}
+	return collector, nil
+}
+
+// Describe implements required describe function for all prometheus collectors
+func (collector *PeriodCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.energy.desc
+	ch <- collector.exported.desc
+	ch <- collector.balance.desc
+}
+
+// Collect implements required collect function for all prometheus collectors
+func (collector *PeriodCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, meter := range collector.meters {
+		exported := make(map[[2]string]float64)
+		consumed := make(map[[2]string]float64)
+		var windows [][2]string
+		for _, total := range collector.tracker.Totals(meter) {
+			window := [2]string{total.Period, total.Window}
+			switch total.Index {
+			case period.ExportedIndex:
+				exported[window] = total.Value
+				ch <- collector.exported.metric(total.Value, meter, total.Period, total.Window)
+				continue
+			case "total":
+				consumed[window] = total.Value
+				windows = append(windows, window)
+			}
+			ch <- collector.energy.metric(total.Value, meter, total.Period, total.Index, total.Window)
+		}
+
+		// Balance of the windows with both a consumed and an exported total, whatever their order
+		for _, window := range windows {
+			if injected, ok := exported[window]; ok {
+				ch <- collector.balance.metric(consumed[window]-injected, meter, window[0], window[1])
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package prom
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/syberalexis/linky-exporter/internal/tictest"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+	"github.com/syberalexis/linky-exporter/pkg/period"
+)
+
+func TestPeriodCollectorGridBalance(t *testing.T) {
+	// Given
+	tracker, err := period.Open(config.PeriodsConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2023, 6, 14, 12, 0, 0, 0, time.Local)
+	for i, indexes := range [][2]string{{"000010000", "000005000"}, {"000010500", "000005200"}} {
+		tracker.Add("main", tictest.Frame(t, core.Standard, day.Add(time.Duration(i)*time.Minute), "EAST\t"+indexes[0], "EAIT\t"+indexes[1]))
+	}
+	var tests = []struct {
+		name     string
+		labels   LabelsConfig
+		expected string
+	}{
+		{"labels", LabelsConfig{}, `
+# HELP linky_grid_balance_period_wh Energy consumed minus energy injected over the current or previous calendar period
+# TYPE linky_grid_balance_period_wh gauge
+linky_grid_balance_period_wh{meter="main",period="day",window="current"} 300
+linky_grid_balance_period_wh{meter="main",period="month",window="current"} 300
+linky_grid_balance_period_wh{meter="main",period="week",window="current"} 300
+linky_grid_balance_period_wh{meter="main",period="year",window="current"} 300
+`},
+		{"dropped and constant labels", LabelsConfig{ConstLabels: map[string]string{"site": "home"}, DropLabels: map[string][]string{"linky_grid_balance_period_wh": {"window"}}}, `
+# HELP linky_grid_balance_period_wh Energy consumed minus energy injected over the current or previous calendar period
+# TYPE linky_grid_balance_period_wh gauge
+linky_grid_balance_period_wh{meter="main",period="day",site="home"} 300
+linky_grid_balance_period_wh{meter="main",period="month",site="home"} 300
+linky_grid_balance_period_wh{meter="main",period="week",site="home"} 300
+linky_grid_balance_period_wh{meter="main",period="year",site="home"} 300
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			collector, err := NewPeriodCollector(tracker, []string{"main"}, tt.labels)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Then
+			if err := testutil.CollectAndCompare(collector, strings.NewReader(tt.expected), "linky_grid_balance_period_wh"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestPeriodCollectorConflictingLabel(t *testing.T) {
+	// Given
+	labels := LabelsConfig{ConstLabels: map[string]string{config.MeterLabel: "home"}}
+
+	// When
+	_, err := NewPeriodCollector(nil, []string{"main"}, labels)
+
+	// Then
+	if err == nil || err.Error() != "Constant label meter conflicts with linky_energy_period_wh label" {
+		t.Errorf("got error %v", err)
+	}
+}
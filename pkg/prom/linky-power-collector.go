@@ -0,0 +1,138 @@
+package prom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// PowerCollector object to collect the power derived from energy indexes, the headroom and the net grid exchange of each meter
+type PowerCollector struct {
+	meters             []*core.Meter
+	activePower        *metricFamily
+	powerFactor        *metricFamily
+	reactivePower      *metricFamily
+	tanPhi             *metricFamily
+	tanPhiExceeded     *metricFamily
+	headroom           *metricFamily
+	breakingHeadroom   *metricFamily
+	overrun            *metricFamily
+	overruns           *metricFamily
+	overrunSeconds     *metricFamily
+	lastOverrunSeconds *metricFamily
+	netPower           *metricFamily
+}
+
+// NewPowerCollector method to construct PowerCollector
+func NewPowerCollector(meters []*core.Meter, labels LabelsConfig) (*PowerCollector, error) {
+	names := []string{config.MeterLabel}
+	collector := &PowerCollector{
+		meters:             meters,
+		activePower:        newMetricFamily(labels, "linky_active_power_watts", "Active power derived from the total energy index, averaged over the power window", prometheus.GaugeValue, names...),
+		powerFactor:        newMetricFamily(labels, "linky_power_factor", "Estimated power factor, derived active power over average apparent power", prometheus.GaugeValue, names...),
+		reactivePower:      newMetricFamily(labels, "linky_reactive_power_var", "Reactive power derived from the reactive energy index of the quadrant, averaged over the power window", prometheus.GaugeValue, config.MeterLabel, "quadrant"),
+		tanPhi:             newMetricFamily(labels, "linky_tan_phi", "Inductive reactive energy over active energy consumed within the window", prometheus.GaugeValue, config.MeterLabel, "window"),
+		tanPhiExceeded:     newMetricFamily(labels, "linky_tan_phi_threshold_exceeded", "Whether tan φ within the window is above the threshold", prometheus.GaugeValue, config.MeterLabel, "window"),
+		headroom:           newMetricFamily(labels, "linky_power_headroom_va", "Subscribed power minus apparent power, negative above the subscribed power", prometheus.GaugeValue, names...),
+		breakingHeadroom:   newMetricFamily(labels, "linky_power_breaking_headroom_va", "Breaking power minus apparent power, negative above the breaking power", prometheus.GaugeValue, names...),
+		overrun:            newMetricFamily(labels, "linky_power_overrun", "Whether the subscribed power is exceeded, as signaled by the meter or when apparent power is above it", prometheus.GaugeValue, names...),
+		overruns:           newMetricFamily(labels, "linky_power_overruns_total", "Number of subscribed power overrun events since the exporter started", prometheus.CounterValue, names...),
+		overrunSeconds:     newMetricFamily(labels, "linky_power_overrun_seconds_total", "Duration of subscribed power overrun events since the exporter started", prometheus.CounterValue, names...),
+		netPower:           newMetricFamily(labels, "linky_grid_net_power_va", "Apparent power drawn minus apparent power injected, negative when exporting", prometheus.GaugeValue, names...),
+		lastOverrunSeconds: newMetricFamily(labels, "linky_power_last_overrun_duration_seconds", "Duration of the last ended subscribed power overrun event", prometheus.GaugeValue, names...),
+	}
+	if err := familiesError(collector.activePower, collector.powerFactor, collector.reactivePower, collector.tanPhi, collector.tanPhiExceeded, collector.headroom, collector.breakingHeadroom, collector.overrun, collector.overruns, collector.overrunSeconds, collector.lastOverrunSeconds, collector.netPower); err != nil {
+		return nil, err
+	}
+	return collector, nil
+}
+
+// Describe implements required describe function for all prometheus collectors
+func (collector *PowerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.activePower.desc
+	ch <- collector.powerFactor.desc
+	ch <- collector.reactivePower.desc
+	ch <- collector.tanPhi.desc
+	ch <- collector.tanPhiExceeded.desc
+	ch <- collector.headroom.desc
+	ch <- collector.breakingHeadroom.desc
+	ch <- collector.overrun.desc
+	ch <- collector.overruns.desc
+	ch <- collector.overrunSeconds.desc
+	ch <- collector.lastOverrunSeconds.desc
+	ch <- collector.netPower.desc
+}
+
+// Collect implements required collect function for all prometheus collectors
+func (collector *PowerCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, meter := range collector.meters {
+		if estimate, ok := meter.Power(); ok {
+			ch <- collector.activePower.metric(estimate.ActivePower, meter.Name)
+			if estimate.HasPowerFactor {
+				ch <- collector.powerFactor.metric(estimate.PowerFactor, meter.Name)
+			}
+		}
+
+		collector.collectReactive(ch, meter)
+		collector.collectOverruns(ch, meter)
+		collector.collectNetPower(ch, meter)
+	}
+}
+
+// Collect the net power exchanged with the grid by a producer meter
+func (collector *PowerCollector) collectNetPower(ch chan<- prometheus.Metric, meter *core.Meter) {
+	frame, ok := meter.Last()
+	if !ok {
+		return
+	}
+	measurement := frame.Measurement()
+	exported, ok := measurement.Get(core.ApparentPower, core.Exported, 0, "")
+	if !ok {
+		return
+	}
+	imported, _ := measurement.Get(core.ApparentPower, core.Imported, 0, "")
+	ch <- collector.netPower.metric(imported-exported, meter.Name)
+}
+
+// Collect reactive power and tan φ of a meter
+func (collector *PowerCollector) collectReactive(ch chan<- prometheus.Metric, meter *core.Meter) {
+	reactive, ok := meter.Reactive()
+	if !ok {
+		return
+	}
+	if reactive.HasPower {
+		for i, quadrant := range core.Quadrants {
+			ch <- collector.reactivePower.metric(reactive.Power[i], meter.Name, quadrant)
+		}
+	}
+	for _, tanPhi := range reactive.TanPhi {
+		window := model.Duration(tanPhi.Window).String()
+		exceeded := 0.0
+		if tanPhi.Exceeded {
+			exceeded = 1
+		}
+		ch <- collector.tanPhi.metric(tanPhi.Value, meter.Name, window)
+		ch <- collector.tanPhiExceeded.metric(exceeded, meter.Name, window)
+	}
+}
+
+// Collect headroom and overrun events of a meter
+func (collector *PowerCollector) collectOverruns(ch chan<- prometheus.Metric, meter *core.Meter) {
+	overruns, ok := meter.Overruns()
+	if !ok {
+		return
+	}
+	overrun := 0.0
+	if overruns.Headroom.Overrun {
+		overrun = 1
+	}
+	ch <- collector.headroom.metric(overruns.Headroom.Headroom, meter.Name)
+	if overruns.Headroom.HasBreaking {
+		ch <- collector.breakingHeadroom.metric(overruns.Headroom.Breaking, meter.Name)
+	}
+	ch <- collector.overrun.metric(overrun, meter.Name)
+	ch <- collector.overruns.metric(float64(overruns.Count), meter.Name)
+	ch <- collector.overrunSeconds.metric(overruns.Duration.Seconds(), meter.Name)
+	ch <- collector.lastOverrunSeconds.metric(overruns.LastDuration.Seconds(), meter.Name)
+}
@@ -0,0 +1,111 @@
+package prom
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+const (
+	// Probe timeout when Prometheus does not send its scrape timeout
+	defaultProbeTimeout = 10 * time.Second
+	// Time kept from the scrape timeout to send the response
+	probeTimeoutOffset = 500 * time.Millisecond
+)
+
+// Handle GET /probe?target=...&mode=... requests, reading one frame of the target with a fresh registry
+func (exporter *LinkyExporter) probeHandler(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "Target parameter is missing", http.StatusBadRequest)
+		return
+	}
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = "auto"
+	}
+	connector, err := config.DeviceConfig{Name: target, Device: target, Mode: mode}.Connector()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// A serial port can only be opened once, and a network gateway may only serve one client
+	exporter.mutex.Lock()
+	metrics := exporter.config.Metrics
+	devices := exporter.config.Devices
+	exporter.mutex.Unlock()
+	for _, device := range devices {
+		if device.Device == target {
+			http.Error(w, fmt.Sprintf("Target %s is read by meter %s", target, device.Name), http.StatusConflict)
+			return
+		}
+	}
+	schema, err := ParseMetricsSchema(metrics.Schema)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), probeTimeout(r))
+	defer cancel()
+	registry, err := probe(ctx, target, connector, schema, NewLabelsConfig(metrics))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// Read one frame of the target and build the registry of its metrics
+func probe(ctx context.Context, target string, connector core.LinkyConnector, schema MetricsSchema, labels LabelsConfig) (*prometheus.Registry, error) {
+	success := prometheus.NewGauge(prometheus.GaugeOpts{Name: "probe_success", Help: "Whether a frame was read from the target"})
+	duration := prometheus.NewGauge(prometheus.GaugeOpts{Name: "probe_duration_seconds", Help: "Duration of the probe in seconds"})
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(success, duration)
+
+	start := time.Now()
+	frame, err := core.ReadFrame(ctx, connector)
+	duration.Set(time.Since(start).Seconds())
+	if err != nil {
+		log.Warnf("Probe of %s failed : %s", target, err)
+		return registry, nil
+	}
+
+	collector, err := NewFrameCollector(target, frame, schema, labels)
+	if err != nil {
+		return nil, err
+	}
+	if err := registry.Register(collector); err != nil {
+		return nil, err
+	}
+	success.Set(1)
+	return registry, nil
+}
+
+// Timeout of the probe from the Prometheus scrape timeout header
+func probeTimeout(r *http.Request) time.Duration {
+	header := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")
+	if header == "" {
+		return defaultProbeTimeout
+	}
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		log.Debug(fmt.Sprintf("Invalid scrape timeout header %s", header))
+		return defaultProbeTimeout
+	}
+
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout > probeTimeoutOffset {
+		timeout -= probeTimeoutOffset
+	}
+	return timeout
+}
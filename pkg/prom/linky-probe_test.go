@@ -0,0 +1,92 @@
+package prom
+
+import (
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/syberalexis/linky-exporter/internal/tictest"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Serve a standard frame to each connection, returning the target address
+func frameServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write(tictest.Raw(core.Standard, "ADSC\t031762120345", "URMS1\t229"))
+			conn.Close()
+		}
+	}()
+	return "tcp://" + listener.Addr().String()
+}
+
+func TestProbeHandlerTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name     string
+		target   string
+		mode     string
+		expected string
+	}{
+		{"success", frameServer(t), "standard", "probe_success 1\n"},
+		{"auto", frameServer(t), "auto", "probe_success 1\n"},
+		{"failure", "tcp://127.0.0.1:1", "auto", "probe_success 0\n"},
+	}
+	exporter := NewLinkyExporter(testConfig(), "")
+	exporter.config.Metrics.Schema = string(SchemaV2)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			recorder := httptest.NewRecorder()
+			exporter.probeHandler(recorder, httptest.NewRequest("GET", "/probe?target="+tt.target+"&mode="+tt.mode, nil))
+			body := recorder.Body.String()
+
+			// Then
+			if recorder.Code != 200 || !strings.Contains(body, tt.expected) || !strings.Contains(body, "probe_duration_seconds") {
+				t.Errorf("unexpected response %d : %s", recorder.Code, body)
+			}
+			if tt.expected == "probe_success 1\n" && !strings.Contains(body, `linky_voltage_volts{linky_id="031762120345",phase="1"} 229`) {
+				t.Errorf("missing meter metrics : %s", body)
+			}
+		})
+	}
+}
+
+func TestProbeHandlerInvalid(t *testing.T) {
+	// Given
+	exporter := NewLinkyExporter(testConfig(), "")
+
+	// When
+	recorder := httptest.NewRecorder()
+	exporter.probeHandler(recorder, httptest.NewRequest("GET", "/probe?target=/dev/null&mode=fast", nil))
+
+	// Then
+	if recorder.Code != 400 {
+		t.Errorf("got status %d, want 400", recorder.Code)
+	}
+}
+
+func TestProbeHandlerConfiguredDevice(t *testing.T) {
+	// Given
+	exporter := NewLinkyExporter(testConfig("/dev/null"), "")
+
+	// When
+	recorder := httptest.NewRecorder()
+	exporter.probeHandler(recorder, httptest.NewRequest("GET", "/probe?target=/dev/null", nil))
+
+	// Then
+	if recorder.Code != 409 || !strings.Contains(recorder.Body.String(), "Target /dev/null is read by meter") {
+		t.Errorf("unexpected response %d : %s", recorder.Code, recorder.Body.String())
+	}
+}
@@ -0,0 +1,94 @@
+package prom
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// QualityCollector object to collect phase imbalance and EN 50160 voltage quality of each meter
+type QualityCollector struct {
+	meters           []*core.Meter
+	currentImbalance *metricFamily
+	powerImbalance   *metricFamily
+	deviation        *metricFamily
+	band             *metricFamily
+	episode          *metricFamily
+	episodes         *metricFamily
+	episodeSeconds   *metricFamily
+}
+
+// NewQualityCollector method to construct QualityCollector
+func NewQualityCollector(meters []*core.Meter, labels LabelsConfig) (*QualityCollector, error) {
+	names := []string{config.MeterLabel}
+	phaseNames := []string{config.MeterLabel, "phase"}
+	kindNames := []string{config.MeterLabel, "phase", "kind"}
+	collector := &QualityCollector{
+		meters:           meters,
+		currentImbalance: newMetricFamily(labels, "linky_phase_current_imbalance_percent", "Largest deviation of a phase current from the average of the three phases, in percent of the average", prometheus.GaugeValue, names...),
+		powerImbalance:   newMetricFamily(labels, "linky_phase_power_imbalance_percent", "Largest deviation of a phase apparent power from the average of the three phases, in percent of the average", prometheus.GaugeValue, names...),
+		deviation:        newMetricFamily(labels, "linky_voltage_deviation_percent", "Deviation of the RMS voltage from the nominal 230 V, in percent", prometheus.GaugeValue, phaseNames...),
+		band:             newMetricFamily(labels, "linky_voltage_band", "EN 50160 band of the average voltage, of the RMS voltage when the meter does not provide it", prometheus.GaugeValue, config.MeterLabel, "phase", "band"),
+		episode:          newMetricFamily(labels, "linky_voltage_episode", "Whether the RMS voltage is outside the ±10% tolerance", prometheus.GaugeValue, kindNames...),
+		episodes:         newMetricFamily(labels, "linky_voltage_episodes_total", "Number of over or under voltage episodes since the exporter started", prometheus.CounterValue, kindNames...),
+		episodeSeconds:   newMetricFamily(labels, "linky_voltage_episode_seconds_total", "Duration of over or under voltage episodes since the exporter started", prometheus.CounterValue, kindNames...),
+	}
+	if err := familiesError(collector.currentImbalance, collector.powerImbalance, collector.deviation, collector.band, collector.episode, collector.episodes, collector.episodeSeconds); err != nil {
+		return nil, err
+	}
+	return collector, nil
+}
+
+// Describe implements required describe function for all prometheus collectors
+func (collector *QualityCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.currentImbalance.desc
+	ch <- collector.powerImbalance.desc
+	ch <- collector.deviation.desc
+	ch <- collector.band.desc
+	ch <- collector.episode.desc
+	ch <- collector.episodes.desc
+	ch <- collector.episodeSeconds.desc
+}
+
+// Collect implements required collect function for all prometheus collectors
+func (collector *QualityCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, meter := range collector.meters {
+		quality, ok := meter.Quality()
+		if !ok {
+			continue
+		}
+		if quality.HasCurrent {
+			ch <- collector.currentImbalance.metric(quality.CurrentImbalance, meter.Name)
+		}
+		if quality.HasPower {
+			ch <- collector.powerImbalance.metric(quality.PowerImbalance, meter.Name)
+		}
+
+		for _, phase := range quality.Phases {
+			name := strconv.Itoa(phase.Phase)
+			ch <- collector.deviation.metric(phase.Deviation, meter.Name, name)
+			for _, band := range core.VoltageBands {
+				current := 0.0
+				if phase.Band == band {
+					current = 1
+				}
+				ch <- collector.band.metric(current, meter.Name, name, band)
+			}
+			collector.collectEpisodes(ch, meter.Name, name, core.Overvoltage, phase.Overvoltage)
+			collector.collectEpisodes(ch, meter.Name, name, core.Undervoltage, phase.Undervoltage)
+		}
+	}
+}
+
+// Collect the voltage episodes of a kind on a phase
+func (collector *QualityCollector) collectEpisodes(ch chan<- prometheus.Metric, meter string, phase string, kind string, episodes core.Episodes) {
+	ongoing := 0.0
+	if episodes.Ongoing() {
+		ongoing = 1
+	}
+	ch <- collector.episode.metric(ongoing, meter, phase, kind)
+	ch <- collector.episodes.metric(float64(episodes.Count), meter, phase, kind)
+	ch <- collector.episodeSeconds.metric(episodes.Duration.Seconds(), meter, phase, kind)
+}
@@ -0,0 +1,188 @@
+package prom
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+const USED = "used"
+const PRODUCED = "produced"
+
+// Names of status register fields in linky_status metric
+var statusNames = map[string]string{
+	core.StatusDryContact:                   "Contact sec",
+	core.StatusCutOffDevice:                 "Organe de coupure",
+	core.StatusLinkyTerminalShield:          "État du cache-bornes distributeur",
+	core.StatusSurge:                        "Surtension sur une des phases",
+	core.StatusReferencePowerExceeded:       "Dépassement de la puissance de référence",
+	core.StatusConsumption:                  "Fonctionnement producteur/consommateur",
+	core.StatusEnergyDirection:              "Sens de l énergie active",
+	core.StatusContractTypePrice:            "Tarif en cours sur le contrat fourniture",
+	core.StatusContractTypePriceDistributor: "Tarif en cours sur le contrat distributeur",
+	core.StatusClock:                        "Mode dégradée de l horloge",
+	core.StatusTic:                          "État de la sortie télé-information",
+	core.StatusEuridisLink:                  "État de la sortie communication Euridis",
+	core.StatusCPL:                          "Statut du CPL",
+	core.StatusCPLSync:                      "Synchronisation CPL",
+	core.StatusTempoContractColor:           "Couleur du jour pour le contrat historique tempo",
+	core.StatusTempoContractNextDayColor:    "Couleur du lendemain pour le contrat historique tempo",
+	core.StatusMovingPeakNotice:             "Préavis pointes mobiles",
+	core.StatusMovingPeak:                   "Pointe mobile (PM)",
+}
+
+// Original metrics schema
+type schemaV1 struct {
+	linkyDate              *metricFamily
+	energyTotal            *metricFamily
+	energy                 *metricFamily
+	reactiveEnergyTotal    *metricFamily
+	intensity              *metricFamily
+	voltage                *metricFamily
+	power                  *metricFamily
+	powerLastYear          *metricFamily
+	powerMax               *metricFamily
+	powerReference         *metricFamily
+	loadCurvePoint         *metricFamily
+	loadCurvePointLastYear *metricFamily
+	averageVoltage         *metricFamily
+	status                 *metricFamily
+	movablePeak            *metricFamily
+	relay                  *metricFamily
+	providerDayInfo        *metricFamily
+}
+
+// Construct original metrics schema
+func newSchemaV1(labels LabelsConfig) *schemaV1 {
+	return &schemaV1{
+		linkyDate:              newMetricFamily(labels, "linky_timestamp", "Timestamp en seconde", prometheus.CounterValue, "linky_id", "version", "contract", "pricing"),
+		energyTotal:            newMetricFamily(labels, "linky_energy_total", "Total Energie en Wh", prometheus.CounterValue, "linky_id", "mode"),
+		energy:                 newMetricFamily(labels, "linky_energy", "Energie en Wh", prometheus.CounterValue, "linky_id", "mode", "index"),
+		reactiveEnergyTotal:    newMetricFamily(labels, "linky_reactive_energy_total", "Total Energie réactive en Wh", prometheus.CounterValue, "linky_id", "index"),
+		intensity:              newMetricFamily(labels, "linky_intensity", "Courant efficace en A", prometheus.GaugeValue, "linky_id", "phase"),
+		voltage:                newMetricFamily(labels, "linky_voltage", "Tension efficace en V", prometheus.GaugeValue, "linky_id", "phase"),
+		power:                  newMetricFamily(labels, "linky_power", "Puissance apparente en VA", prometheus.GaugeValue, "linky_id", "mode", "phase"),
+		powerLastYear:          newMetricFamily(labels, "linky_power_last_year", "Puissance apparente n-1 en VA", prometheus.GaugeValue, "linky_id", "mode", "phase"),
+		powerMax:               newMetricFamily(labels, "linky_power_max", "Puissance apparente en VA", prometheus.GaugeValue, "linky_id", "mode", "phase"),
+		powerReference:         newMetricFamily(labels, "linky_power_reference", "Puissance apparente de référence en kVA", prometheus.GaugeValue, "linky_id", "type"),
+		loadCurvePoint:         newMetricFamily(labels, "linky_load_curve_point", "Point de courbe de charge en W", prometheus.GaugeValue, "linky_id", "mode"),
+		loadCurvePointLastYear: newMetricFamily(labels, "linky_load_curve_point_last_year", "Point de courbe de charge n-1 en W", prometheus.GaugeValue, "linky_id", "mode"),
+		averageVoltage:         newMetricFamily(labels, "linky_voltage_average", "Tension moyenne en V", prometheus.GaugeValue, "linky_id", "phase"),
+		status:                 newMetricFamily(labels, "linky_status", "Statuts issus du registre", prometheus.GaugeValue, "linky_id", "name"),
+		movablePeak:            newMetricFamily(labels, "linky_movable_peak", "Pointe mobile", prometheus.GaugeValue, "linky_id", "type", "phase"),
+		relay:                  newMetricFamily(labels, "linky_relay", "Etat du relai", prometheus.GaugeValue, "linky_id", "id"),
+		providerDayInfo:        newMetricFamily(labels, "linky_provider_day_info", "Numéro du jour en cours, du prochain jour et de son profil", prometheus.GaugeValue, "linky_id", "prm", "current_day", "next_day", "next_day_profile"),
+	}
+}
+
+// All metric families of the schema
+func (schema *schemaV1) families() []*metricFamily {
+	return []*metricFamily{
+		schema.linkyDate,
+		schema.energyTotal,
+		schema.energy,
+		schema.reactiveEnergyTotal,
+		schema.intensity,
+		schema.voltage,
+		schema.power,
+		schema.powerLastYear,
+		schema.powerMax,
+		schema.powerReference,
+		schema.loadCurvePoint,
+		schema.loadCurvePointLastYear,
+		schema.averageVoltage,
+		schema.status,
+		schema.movablePeak,
+		schema.relay,
+		schema.providerDayInfo,
+	}
+}
+
+// Send to channel all metrics of a measurement
+func (schema *schemaV1) collect(ch chan<- prometheus.Metric, measurement *core.LinkyMeasurement) {
+	// Date
+	schema.fillLinkyDateMetric(ch, measurement)
+	for _, sample := range measurement.Samples {
+		schema.fillSampleMetric(ch, measurement, sample)
+	}
+	// Provider Day Info
+	// Not enabled now, it's possible to overload metrics cardinality
+	// schema.fillProviderDayInfoMetric(ch, measurement)
+}
+
+// Send to channel linky_date metric, 0 when the meter does not send its clock
+func (schema *schemaV1) fillLinkyDateMetric(ch chan<- prometheus.Metric, measurement *core.LinkyMeasurement) {
+	var timestamp float64
+	if !measurement.MeterTime.IsZero() {
+		timestamp = float64(measurement.MeterTime.Unix())
+	}
+	ch <- schema.linkyDate.metric(timestamp, measurement.MeterId, measurement.Version, measurement.Contract, measurement.PriceLabel)
+}
+
+// Send to channel the metric matching the sample
+func (schema *schemaV1) fillSampleMetric(ch chan<- prometheus.Metric, measurement *core.LinkyMeasurement, sample core.Sample) {
+	linkyId := measurement.MeterId
+	switch sample.Quantity {
+	case core.ActiveEnergy:
+		if sample.Index == "" {
+			ch <- schema.energyTotal.metric(sample.Value, linkyId, directionMode(sample))
+		} else {
+			ch <- schema.energy.metric(sample.Value, linkyId, directionMode(sample), sample.Index)
+		}
+	case core.ReactiveEnergy:
+		ch <- schema.reactiveEnergyTotal.metric(sample.Value, linkyId, sample.Index)
+	case core.Current:
+		ch <- schema.intensity.metric(sample.Value, linkyId, strconv.Itoa(sample.Phase))
+	case core.Voltage:
+		ch <- schema.voltage.metric(sample.Value, linkyId, strconv.Itoa(sample.Phase))
+	case core.AverageVoltage:
+		ch <- schema.averageVoltage.metric(sample.Value, linkyId, strconv.Itoa(sample.Phase))
+	case core.ApparentPower:
+		ch <- schema.power.metric(sample.Value, linkyId, directionMode(sample), powerPhase(measurement, sample))
+	case core.ApparentPowerMax:
+		ch <- schema.powerMax.metric(sample.Value, linkyId, directionMode(sample), powerPhase(measurement, sample))
+	case core.ApparentPowerMaxLastYear:
+		ch <- schema.powerLastYear.metric(sample.Value, linkyId, directionMode(sample), powerPhase(measurement, sample))
+	case core.ReferencePower:
+		ch <- schema.powerReference.metric(sample.Value, linkyId, "subscribed")
+	case core.BreakingPower:
+		ch <- schema.powerReference.metric(sample.Value, linkyId, "breaking")
+	case core.LoadCurvePoint:
+		ch <- schema.loadCurvePoint.metric(sample.Value, linkyId, directionMode(sample))
+	case core.LoadCurvePointLastYear:
+		ch <- schema.loadCurvePointLastYear.metric(sample.Value, linkyId, directionMode(sample))
+	case core.Status:
+		ch <- schema.status.metric(sample.Value, linkyId, statusNames[sample.Index])
+	case core.MovablePeakStart:
+		ch <- schema.movablePeak.metric(sample.Value, linkyId, "start", sample.Index)
+	case core.MovablePeakEnd:
+		ch <- schema.movablePeak.metric(sample.Value, linkyId, "end", sample.Index)
+	case core.Relay:
+		ch <- schema.relay.metric(sample.Value, linkyId, sample.Index)
+	}
+}
+
+// Send to channel linky_provider_day_info metric
+func (schema *schemaV1) fillProviderDayInfoMetric(ch chan<- prometheus.Metric, measurement *core.LinkyMeasurement) {
+	ch <- schema.providerDayInfo.metric(1, measurement.MeterId, measurement.Prm, measurement.DayNumber, measurement.NextDayNumber, measurement.NextDayProfile)
+}
+
+// Return the mode label of a sample
+func directionMode(sample core.Sample) string {
+	if sample.Direction == core.Exported {
+		return PRODUCED
+	}
+	return USED
+}
+
+// Return the phase label of a power sample.
+// The used total is labelled as phase 1 on single phase meters and 0 otherwise, the produced total is always phase 0.
+func powerPhase(measurement *core.LinkyMeasurement, sample core.Sample) string {
+	if sample.Phase == 0 && sample.Direction == core.Imported {
+		if _, triplePhase := measurement.Get(sample.Quantity, sample.Direction, 1, ""); !triplePhase {
+			return "1"
+		}
+	}
+	return strconv.Itoa(sample.Phase)
+}
@@ -0,0 +1,155 @@
+package prom
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Label value of samples not specific to a phase or a tariff index
+const TOTAL = "total"
+
+// Metrics schema following Prometheus naming conventions
+type schemaV2 struct {
+	info                   *metricFamily
+	meterTime              *metricFamily
+	energyImported         *metricFamily
+	energyExported         *metricFamily
+	reactiveEnergy         *metricFamily
+	current                *metricFamily
+	voltage                *metricFamily
+	averageVoltage         *metricFamily
+	apparentPower          *metricFamily
+	apparentPowerMax       *metricFamily
+	apparentPowerLastYear  *metricFamily
+	referencePower         *metricFamily
+	breakingPower          *metricFamily
+	loadCurvePoint         *metricFamily
+	loadCurvePointLastYear *metricFamily
+	peakNotice             *metricFamily
+	movablePeakStart       *metricFamily
+	movablePeakEnd         *metricFamily
+	status                 *metricFamily
+	relay                  *metricFamily
+}
+
+// Construct Prometheus conventions metrics schema
+func newSchemaV2(labels LabelsConfig) *schemaV2 {
+	return &schemaV2{
+		info:                   newMetricFamily(labels, "linky_info", "Meter information, always 1", prometheus.GaugeValue, "linky_id", "version", "contract", "pricing"),
+		meterTime:              newMetricFamily(labels, "linky_meter_timestamp_seconds", "Meter clock as a Unix timestamp", prometheus.GaugeValue, "linky_id"),
+		energyImported:         newMetricFamily(labels, "linky_energy_active_imported_wh_total", "Active energy drawn from the grid in Wh, by supplier (F) or distributor (D) tariff index", prometheus.CounterValue, "linky_id", "tariff_index"),
+		energyExported:         newMetricFamily(labels, "linky_energy_active_exported_wh_total", "Active energy injected into the grid in Wh", prometheus.CounterValue, "linky_id", "tariff_index"),
+		reactiveEnergy:         newMetricFamily(labels, "linky_energy_reactive_varh_total", "Reactive energy in VArh by quadrant", prometheus.CounterValue, "linky_id", "quadrant"),
+		current:                newMetricFamily(labels, "linky_current_amperes", "RMS current in A", prometheus.GaugeValue, "linky_id", "phase"),
+		voltage:                newMetricFamily(labels, "linky_voltage_volts", "RMS voltage in V", prometheus.GaugeValue, "linky_id", "phase"),
+		averageVoltage:         newMetricFamily(labels, "linky_voltage_average_volts", "Average RMS voltage over the last period in V", prometheus.GaugeValue, "linky_id", "phase"),
+		apparentPower:          newMetricFamily(labels, "linky_apparent_power_va", "Instantaneous apparent power in VA", prometheus.GaugeValue, "linky_id", "direction", "phase"),
+		apparentPowerMax:       newMetricFamily(labels, "linky_apparent_power_max_va", "Maximum apparent power of the day in VA", prometheus.GaugeValue, "linky_id", "direction", "phase"),
+		apparentPowerLastYear:  newMetricFamily(labels, "linky_apparent_power_max_last_year_va", "Maximum apparent power of the same day last year in VA", prometheus.GaugeValue, "linky_id", "direction", "phase"),
+		referencePower:         newMetricFamily(labels, "linky_reference_power_va", "Subscribed apparent power in VA", prometheus.GaugeValue, "linky_id"),
+		breakingPower:          newMetricFamily(labels, "linky_breaking_power_va", "Apparent power cutting the breaker in VA", prometheus.GaugeValue, "linky_id"),
+		loadCurvePoint:         newMetricFamily(labels, "linky_load_curve_point_watts", "Last active load curve point in W", prometheus.GaugeValue, "linky_id", "direction"),
+		loadCurvePointLastYear: newMetricFamily(labels, "linky_load_curve_point_previous_watts", "Previous active load curve point in W", prometheus.GaugeValue, "linky_id", "direction"),
+		peakNotice:             newMetricFamily(labels, "linky_peak_notice_minutes", "Notice before the next EJP peak period in minutes", prometheus.GaugeValue, "linky_id"),
+		movablePeakStart:       newMetricFamily(labels, "linky_movable_peak_start", "Start of the movable peak", prometheus.GaugeValue, "linky_id", "peak"),
+		movablePeakEnd:         newMetricFamily(labels, "linky_movable_peak_end", "End of the movable peak", prometheus.GaugeValue, "linky_id", "peak"),
+		status:                 newMetricFamily(labels, "linky_status", "Field value of the meter status register", prometheus.GaugeValue, "linky_id", "name"),
+		relay:                  newMetricFamily(labels, "linky_relay_closed", "Relay state, 1 when closed", prometheus.GaugeValue, "linky_id", "relay"),
+	}
+}
+
+// All metric families of the schema
+func (schema *schemaV2) families() []*metricFamily {
+	return []*metricFamily{
+		schema.info,
+		schema.meterTime,
+		schema.energyImported,
+		schema.energyExported,
+		schema.reactiveEnergy,
+		schema.current,
+		schema.voltage,
+		schema.averageVoltage,
+		schema.apparentPower,
+		schema.apparentPowerMax,
+		schema.apparentPowerLastYear,
+		schema.referencePower,
+		schema.breakingPower,
+		schema.loadCurvePoint,
+		schema.loadCurvePointLastYear,
+		schema.peakNotice,
+		schema.movablePeakStart,
+		schema.movablePeakEnd,
+		schema.status,
+		schema.relay,
+	}
+}
+
+// Send to channel all metrics of a measurement
+func (schema *schemaV2) collect(ch chan<- prometheus.Metric, measurement *core.LinkyMeasurement) {
+	linkyId := measurement.MeterId
+	ch <- schema.info.metric(1, linkyId, measurement.Version, measurement.Contract, measurement.PriceLabel)
+	if !measurement.MeterTime.IsZero() {
+		ch <- schema.meterTime.metric(float64(measurement.MeterTime.Unix()), linkyId)
+	}
+
+	for _, sample := range measurement.Samples {
+		switch sample.Quantity {
+		case core.ActiveEnergy:
+			if sample.Direction == core.Exported {
+				ch <- schema.energyExported.metric(sample.Value, linkyId, tariffIndex(sample))
+			} else {
+				ch <- schema.energyImported.metric(sample.Value, linkyId, tariffIndex(sample))
+			}
+		case core.ReactiveEnergy:
+			ch <- schema.reactiveEnergy.metric(sample.Value, linkyId, sample.Index)
+		case core.Current:
+			ch <- schema.current.metric(sample.Value, linkyId, phase(sample))
+		case core.Voltage:
+			ch <- schema.voltage.metric(sample.Value, linkyId, phase(sample))
+		case core.AverageVoltage:
+			ch <- schema.averageVoltage.metric(sample.Value, linkyId, phase(sample))
+		case core.ApparentPower:
+			ch <- schema.apparentPower.metric(sample.Value, linkyId, string(sample.Direction), phase(sample))
+		case core.ApparentPowerMax:
+			ch <- schema.apparentPowerMax.metric(sample.Value, linkyId, string(sample.Direction), phase(sample))
+		case core.ApparentPowerMaxLastYear:
+			ch <- schema.apparentPowerLastYear.metric(sample.Value, linkyId, string(sample.Direction), phase(sample))
+		case core.ReferencePower:
+			ch <- schema.referencePower.metric(sample.Value*1000, linkyId)
+		case core.BreakingPower:
+			ch <- schema.breakingPower.metric(sample.Value*1000, linkyId)
+		case core.LoadCurvePoint:
+			ch <- schema.loadCurvePoint.metric(sample.Value, linkyId, string(sample.Direction))
+		case core.LoadCurvePointLastYear:
+			ch <- schema.loadCurvePointLastYear.metric(sample.Value, linkyId, string(sample.Direction))
+		case core.PeakNotice:
+			ch <- schema.peakNotice.metric(sample.Value, linkyId)
+		case core.MovablePeakStart:
+			ch <- schema.movablePeakStart.metric(sample.Value, linkyId, sample.Index)
+		case core.MovablePeakEnd:
+			ch <- schema.movablePeakEnd.metric(sample.Value, linkyId, sample.Index)
+		case core.Status:
+			ch <- schema.status.metric(sample.Value, linkyId, sample.Index)
+		case core.Relay:
+			ch <- schema.relay.metric(sample.Value, linkyId, sample.Index)
+		}
+	}
+}
+
+// Return the phase label of a sample
+func phase(sample core.Sample) string {
+	if sample.Phase == 0 {
+		return TOTAL
+	}
+	return strconv.Itoa(sample.Phase)
+}
+
+// Return the tariff index label of an energy sample
+func tariffIndex(sample core.Sample) string {
+	if sample.Index == "" {
+		return TOTAL
+	}
+	return sample.Index
+}
@@ -0,0 +1,61 @@
+package prom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/syberalexis/linky-exporter/pkg/shedding"
+)
+
+// SheddingCollector object to collect the rules and actuators of load shedding
+type SheddingCollector struct {
+	engine   *shedding.Engine
+	rule     *metricFamily
+	on       *metricFamily
+	actions  *metricFamily
+	failures *metricFamily
+}
+
+// NewSheddingCollector method to construct SheddingCollector
+func NewSheddingCollector(engine *shedding.Engine, labels LabelsConfig) (*SheddingCollector, error) {
+	collector := &SheddingCollector{
+		engine:   engine,
+		rule:     newMetricFamily(labels, "linky_shedding_rule_active", "Whether all conditions of the rule held on the last frame", prometheus.GaugeValue, "rule", "actuator"),
+		on:       newMetricFamily(labels, "linky_shedding_actuator_on", "Whether the actuator was last switched on, absent before its first switch", prometheus.GaugeValue, "actuator"),
+		actions:  newMetricFamily(labels, "linky_shedding_actions_total", "Number of switches sent to the actuator, by state", prometheus.CounterValue, "actuator", "state"),
+		failures: newMetricFamily(labels, "linky_shedding_action_failures_total", "Number of switches the actuator failed to apply", prometheus.CounterValue, "actuator"),
+	}
+	if err := familiesError(collector.rule, collector.on, collector.actions, collector.failures); err != nil {
+		return nil, err
+	}
+	return collector, nil
+}
+
+// Describe implements required describe function for all prometheus collectors
+func (collector *SheddingCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.rule.desc
+	ch <- collector.on.desc
+	ch <- collector.actions.desc
+	ch <- collector.failures.desc
+}
+
+// Collect implements required collect function for all prometheus collectors
+func (collector *SheddingCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, rule := range collector.engine.Rules() {
+		active := 0.0
+		if rule.Active {
+			active = 1
+		}
+		ch <- collector.rule.metric(active, rule.Name, rule.Actuator)
+	}
+	for _, actuator := range collector.engine.Actuators() {
+		if actuator.Known {
+			on := 0.0
+			if actuator.On {
+				on = 1
+			}
+			ch <- collector.on.metric(on, actuator.Name)
+		}
+		ch <- collector.actions.metric(float64(actuator.SwitchesOn), actuator.Name, "on")
+		ch <- collector.actions.metric(float64(actuator.SwitchesOff), actuator.Name, "off")
+		ch <- collector.failures.metric(float64(actuator.Failures), actuator.Name)
+	}
+}
@@ -0,0 +1,65 @@
+package prom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/solar"
+)
+
+// SolarCollector object to collect the energy flows and self-consumption of a photovoltaic installation
+type SolarCollector struct {
+	tracker         *solar.Tracker
+	meter           string
+	energy          *metricFamily
+	selfConsumption *metricFamily
+	selfSufficiency *metricFamily
+	production      *metricFamily
+}
+
+// NewSolarCollector method to construct SolarCollector, labelled by the grid meter
+func NewSolarCollector(tracker *solar.Tracker, meter string, labels LabelsConfig) (*SolarCollector, error) {
+	names := []string{config.MeterLabel, "period", "window"}
+	collector := &SolarCollector{
+		tracker:         tracker,
+		meter:           meter,
+		energy:          newMetricFamily(labels, "linky_solar_energy_period_wh", "Energy imported, exported, produced and self-consumed over the current or previous calendar period", prometheus.GaugeValue, config.MeterLabel, "period", "window", "flow"),
+		selfConsumption: newMetricFamily(labels, "linky_solar_self_consumption_ratio", "Share of the production consumed by the installation over the calendar period", prometheus.GaugeValue, names...),
+		selfSufficiency: newMetricFamily(labels, "linky_solar_self_sufficiency_ratio", "Share of the consumption covered by the production over the calendar period", prometheus.GaugeValue, names...),
+		production:      newMetricFamily(labels, "linky_solar_production_power_watts", "Last production power of the production source", prometheus.GaugeValue, config.MeterLabel),
+	}
+	if err := familiesError(collector.energy, collector.selfConsumption, collector.selfSufficiency, collector.production); err != nil {
+		return nil, err
+	}
+	return collector, nil
+}
+
+// Describe implements required describe function for all prometheus collectors
+func (collector *SolarCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.energy.desc
+	ch <- collector.selfConsumption.desc
+	ch <- collector.selfSufficiency.desc
+	ch <- collector.production.desc
+}
+
+// Collect implements required collect function for all prometheus collectors
+func (collector *SolarCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, balance := range collector.tracker.Balances() {
+		for flow, value := range map[string]float64{
+			"imported":      balance.Imported,
+			"exported":      balance.Exported,
+			"produced":      balance.Produced,
+			"self_consumed": balance.SelfConsumed(),
+		} {
+			ch <- collector.energy.metric(value, collector.meter, balance.Period, balance.Window, flow)
+		}
+		if ratio, ok := balance.SelfConsumption(); ok {
+			ch <- collector.selfConsumption.metric(ratio, collector.meter, balance.Period, balance.Window)
+		}
+		if ratio, ok := balance.SelfSufficiency(); ok {
+			ch <- collector.selfSufficiency.metric(ratio, collector.meter, balance.Period, balance.Window)
+		}
+	}
+	if power, ok := collector.tracker.Production(); ok {
+		ch <- collector.production.metric(power, collector.meter)
+	}
+}
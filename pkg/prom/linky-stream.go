@@ -0,0 +1,219 @@
+package prom
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+const (
+	streamBufferSize    = 8                // Frames kept for a slow subscriber before dropping new ones
+	streamKeepAlive     = 15 * time.Second // Delay between keep alive messages of idle streams
+	streamWriteTimeout  = 10 * time.Second // Timeout of one WebSocket write
+	streamCloseGraceful = time.Second      // Delay given to the WebSocket close handshake
+)
+
+// Subscriber of the frame stream, receiving JSON documents of frames
+type streamSubscriber struct {
+	meter   string // Meter to receive frames from, all meters when empty
+	frames  chan []byte
+	dropped uint64
+}
+
+// Stream object to broadcast frames of all meters to many subscribers without ever blocking meters
+type frameStream struct {
+	mutex       sync.Mutex
+	subscribers map[*streamSubscriber]struct{}
+	metrics     config.MetricsConfig // Meter identifiers are sent as in metrics, aliased or hashed
+	closed      bool
+}
+
+// Build an empty frame stream
+func newFrameStream() *frameStream {
+	return &frameStream{subscribers: make(map[*streamSubscriber]struct{})}
+}
+
+// Configure how meter identifiers are sent
+func (stream *frameStream) configure(metrics config.MetricsConfig) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.metrics = metrics
+}
+
+// Subscribe to the frames of a meter, or all meters when empty, the channel is closed with the stream
+func (stream *frameStream) subscribe(meter string) *streamSubscriber {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	subscriber := &streamSubscriber{meter: meter, frames: make(chan []byte, streamBufferSize)}
+	if stream.closed {
+		close(subscriber.frames)
+		return subscriber
+	}
+	stream.subscribers[subscriber] = struct{}{}
+	return subscriber
+}
+
+// Stop sending frames to a subscriber
+func (stream *frameStream) unsubscribe(subscriber *streamSubscriber) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	if _, ok := stream.subscribers[subscriber]; ok {
+		delete(stream.subscribers, subscriber)
+		close(subscriber.frames)
+	}
+}
+
+// Send a frame to subscribers, a subscriber with a full buffer misses the frame
+func (stream *frameStream) broadcast(meter string, frame core.Frame) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	if len(stream.subscribers) == 0 {
+		return
+	}
+	document, err := json.Marshal(newApiFrame(meter, frame, stream.metrics))
+	if err != nil {
+		log.Errorf("Failed to encode frame : %s", err)
+		return
+	}
+	for subscriber := range stream.subscribers {
+		if subscriber.meter != "" && subscriber.meter != meter {
+			continue
+		}
+		select {
+		case subscriber.frames <- document:
+		default:
+			subscriber.dropped++
+			log.Debugf("Stream subscriber too slow, %d frames of %s dropped", subscriber.dropped, meter)
+		}
+	}
+}
+
+// Close all subscriptions, ending their requests
+func (stream *frameStream) close() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	stream.closed = true
+	for subscriber := range stream.subscribers {
+		delete(stream.subscribers, subscriber)
+		close(subscriber.frames)
+	}
+}
+
+// Check the meter parameter of a stream request, empty for all meters
+func (exporter *LinkyExporter) streamMeter(w http.ResponseWriter, r *http.Request) (string, bool) {
+	meter := r.URL.Query().Get("meter")
+	if meter == "" {
+		return "", true
+	}
+	if _, status, err := exporter.selectMeter(meter); err != nil {
+		http.Error(w, err.Error(), status)
+		return "", false
+	}
+	return meter, true
+}
+
+// Handle GET /api/v1/stream requests, sending each frame as a Server-Sent Event
+func (exporter *LinkyExporter) streamHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	meter, ok := exporter.streamMeter(w, r)
+	if !ok {
+		return
+	}
+
+	subscriber := exporter.stream.subscribe(meter)
+	defer exporter.stream.unsubscribe(subscriber)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case document, ok := <-subscriber.frames:
+			if !ok {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: frame\ndata: %s\n\n", document); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep alive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// Handle GET /api/v1/ws requests, sending each frame as a WebSocket text message
+func (exporter *LinkyExporter) websocketHandler(w http.ResponseWriter, r *http.Request) {
+	meter, ok := exporter.streamMeter(w, r)
+	if !ok {
+		return
+	}
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debugf("WebSocket upgrade failed : %s", err)
+		return
+	}
+	defer conn.Close()
+
+	subscriber := exporter.stream.subscribe(meter)
+	defer exporter.stream.unsubscribe(subscriber)
+
+	// Read messages until the client closes the connection, answering pings
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case document, ok := <-subscriber.frames:
+			if !ok {
+				message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "Exporter shutting down")
+				conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(streamCloseGraceful))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, document); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package prom
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/syberalexis/linky-exporter/internal/tictest"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Standard frame to broadcast
+func streamFrame(t *testing.T) core.Frame {
+	return tictest.Frame(t, core.Standard, time.Now(), "ADSC\t031762120345", "URMS1\t229")
+}
+
+// Wait until the stream has the given number of subscribers
+func waitSubscribers(t *testing.T, stream *frameStream, count int) {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		stream.mutex.Lock()
+		subscribers := len(stream.subscribers)
+		stream.mutex.Unlock()
+		if subscribers == count {
+			return
+		}
+	}
+	t.Fatalf("no %d subscribers", count)
+}
+
+func TestFrameStreamSlowSubscriber(t *testing.T) {
+	// Given
+	stream := newFrameStream()
+	slow := stream.subscribe("")
+	other := stream.subscribe("other")
+	frame := streamFrame(t)
+
+	// When
+	for i := 0; i < streamBufferSize+2; i++ {
+		stream.broadcast("main", frame)
+	}
+	stream.close()
+
+	// Then
+	if len(slow.frames) != streamBufferSize || slow.dropped != 2 {
+		t.Errorf("got %d frames and %d dropped, want %d and 2", len(slow.frames), slow.dropped, streamBufferSize)
+	}
+	if _, ok := <-other.frames; ok {
+		t.Error("frame of another meter received")
+	}
+}
+
+func TestStreamHandlersTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name    string
+		receive func(t *testing.T, url string) func() string
+	}{
+		{"sse", func(t *testing.T, url string) func() string {
+			response, err := http.Get(url + "/api/v1/stream")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { response.Body.Close() })
+			if response.Header.Get("Content-Type") != "text/event-stream" {
+				t.Errorf("unexpected content type %s", response.Header.Get("Content-Type"))
+			}
+			reader := bufio.NewReader(response.Body)
+			return func() string {
+				event, _ := reader.ReadString('\n')
+				data, _ := reader.ReadString('\n')
+				return event + data
+			}
+		}},
+		{"websocket", func(t *testing.T, url string) func() string {
+			conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http")+"/api/v1/ws", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { conn.Close() })
+			return func() string {
+				_, message, _ := conn.ReadMessage()
+				return string(message)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := NewLinkyExporter(testConfig(), "")
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/v1/stream", exporter.streamHandler)
+			mux.HandleFunc("/api/v1/ws", exporter.websocketHandler)
+			server := httptest.NewServer(mux)
+			defer server.Close()
+			defer exporter.stream.close()
+			exporter.stream.configure(config.MetricsConfig{MeterAliases: map[string]string{"031762120345": "home"}})
+			next := tt.receive(t, server.URL)
+			waitSubscribers(t, exporter.stream, 1)
+
+			// When
+			exporter.publish("main", streamFrame(t))
+
+			// Then
+			if message := next(); !strings.Contains(message, `{"meter":"main","mode":"standard"`) || !strings.Contains(message, `{"label":"ADSC","value":"home"}`) ||
+				!strings.Contains(message, `{"label":"URMS1","value":229,"unit":"V"}`) {
+				t.Errorf("unexpected message %s", message)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package prom
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/daemon"
+	log "github.com/sirupsen/logrus"
+)
+
+// Delay between two status notifications
+const systemdStatusInterval = 5 * time.Second
+
+// Systemd notifier state, sending READY=1 after the first frames and watchdog pings while frames are decoded
+type systemdNotifier struct {
+	exporter *LinkyExporter
+	notify   func(state string) (bool, error)
+	started  time.Time
+	watchdog time.Duration // Watchdog period, 0 when disabled
+	ready    bool
+}
+
+// Notify systemd until the context is done, nothing is sent when not started by systemd with Type=notify
+func (exporter *LinkyExporter) notifySystemd(ctx context.Context) {
+	if os.Getenv("NOTIFY_SOCKET") == "" {
+		return
+	}
+
+	watchdog, err := daemon.SdWatchdogEnabled(false)
+	if err != nil {
+		log.Errorf("Invalid systemd watchdog : %s", err)
+	}
+	notifier := &systemdNotifier{
+		exporter: exporter,
+		notify:   func(state string) (bool, error) { return daemon.SdNotify(false, state) },
+		started:  time.Now(),
+		watchdog: watchdog,
+	}
+
+	interval := systemdStatusInterval
+	if watchdog > 0 && watchdog/2 < interval {
+		interval = watchdog / 2
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		notifier.tick(time.Now())
+		select {
+		case <-ctx.Done():
+			notifier.send(daemon.SdNotifyStopping)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Send readiness, status and watchdog ping according to meters health
+func (notifier *systemdNotifier) tick(now time.Time) {
+	healths, _ := notifier.exporter.healths()
+
+	received := true
+	alive := true
+	var status []string
+	for _, health := range healths {
+		lastActivity := health.LastFrame
+		if health.LastFrame.IsZero() {
+			received = false
+			lastActivity = notifier.started
+			status = append(status, fmt.Sprintf("%s: no frame", health.Name))
+		} else {
+			status = append(status, fmt.Sprintf("%s: %s, last frame %s ago", health.Name, health.Mode, now.Sub(health.LastFrame).Round(time.Second)))
+		}
+		if notifier.watchdog > 0 && now.Sub(lastActivity) > notifier.watchdog {
+			alive = false
+		}
+	}
+
+	states := []string{"STATUS=" + strings.Join(status, "; ")}
+	if received && !notifier.ready {
+		notifier.ready = true
+		states = append(states, daemon.SdNotifyReady)
+	}
+	if notifier.watchdog > 0 {
+		if alive {
+			states = append(states, daemon.SdNotifyWatchdog)
+		} else {
+			log.Warn("No frame decoded within the watchdog period, stopping systemd watchdog pings")
+		}
+	}
+	notifier.send(strings.Join(states, "\n"))
+}
+
+// Send a notification to systemd
+func (notifier *systemdNotifier) send(state string) {
+	if _, err := notifier.notify(state); err != nil {
+		log.Errorf("Failed to notify systemd : %s", err)
+	}
+}
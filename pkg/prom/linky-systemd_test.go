@@ -0,0 +1,53 @@
+package prom
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSystemdNotifierTableDriven(t *testing.T) {
+	// Given
+	now := time.Now()
+	var tests = []struct {
+		name     string
+		devices  []string
+		started  time.Time
+		watchdog time.Duration
+		expected string
+	}{
+		{"probe only", nil, now, 0, "STATUS=\nREADY=1"},
+		{"no frame", []string{"/dev/null"}, now, 0, "STATUS=null: no frame"},
+		{"watchdog", []string{"/dev/null"}, now.Add(-5 * time.Second), 10 * time.Second, "STATUS=null: no frame\nWATCHDOG=1"},
+		{"watchdog expired", []string{"/dev/null"}, now.Add(-20 * time.Second), 10 * time.Second, "STATUS=null: no frame"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := NewLinkyExporter(testConfig(tt.devices...), "")
+			if err := exporter.apply(exporter.config); err != nil {
+				t.Fatal(err)
+			}
+			defer exporter.stop()
+			var states []string
+			notifier := &systemdNotifier{
+				exporter: exporter,
+				notify:   func(state string) (bool, error) { states = append(states, state); return true, nil },
+				started:  tt.started,
+				watchdog: tt.watchdog,
+			}
+
+			// When
+			notifier.tick(now)
+			notifier.tick(now)
+
+			// Then
+			if len(states) != 2 || states[0] != tt.expected {
+				t.Errorf("got states %q, want %q", states, tt.expected)
+			}
+			if strings.Contains(states[1], "READY=1") {
+				t.Error("READY=1 must be sent once")
+			}
+		})
+	}
+}
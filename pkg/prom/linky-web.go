@@ -0,0 +1,74 @@
+package prom
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/prometheus/exporter-toolkit/web"
+	log "github.com/sirupsen/logrus"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+)
+
+// Serve HTTP with TLS and basic auth from the web configuration file, and bearer token authentication
+func serve(server *http.Server, webConfig config.WebConfig) error {
+	if webConfig.BearerTokenFile != "" {
+		token, err := os.ReadFile(webConfig.BearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("Unable to read bearer token file : %s", err)
+		}
+		server.Handler = bearerTokenHandler(strings.TrimSpace(string(token)), server.Handler)
+	}
+
+	systemdSocket := false
+	flags := &web.FlagConfig{
+		WebListenAddresses: &[]string{webConfig.ListenAddress},
+		WebSystemdSocket:   &systemdSocket,
+		WebConfigFile:      &webConfig.ConfigFile,
+	}
+	return web.ListenAndServe(server, flags, kitLogger{})
+}
+
+// Require the token in Authorization header of each request, except health checks of orchestrators
+func bearerTokenHandler(token string, handler http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/-/healthy" || r.URL.Path == "/-/ready" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Logger of exporter toolkit writing to logrus
+type kitLogger struct{}
+
+// Log implements go-kit logger with msg and level keys mapped to logrus
+func (kitLogger) Log(keyvals ...interface{}) error {
+	fields := log.Fields{}
+	message := ""
+	level := log.InfoLevel
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		switch key {
+		case "msg":
+			message = fmt.Sprint(keyvals[i+1])
+		case "level":
+			if parsed, err := log.ParseLevel(fmt.Sprint(keyvals[i+1])); err == nil {
+				level = parsed
+			}
+		default:
+			fields[key] = keyvals[i+1]
+		}
+	}
+	log.WithFields(fields).Log(level, message)
+	return nil
+}
@@ -0,0 +1,42 @@
+package prom
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBearerTokenHandlerTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name          string
+		path          string
+		authorization string
+		expected      int
+	}{
+		{"valid", "/metrics", "Bearer secret", http.StatusOK},
+		{"invalid", "/metrics", "Bearer other", http.StatusUnauthorized},
+		{"basic", "/metrics", "Basic c2VjcmV0", http.StatusUnauthorized},
+		{"missing", "/metrics", "", http.StatusUnauthorized},
+		{"healthy", "/-/healthy", "", http.StatusOK},
+		{"ready", "/-/ready", "", http.StatusOK},
+	}
+	handler := bearerTokenHandler("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest("GET", tt.path, nil)
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			handler.ServeHTTP(recorder, request)
+
+			// Then
+			if recorder.Code != tt.expected {
+				t.Errorf("got status %d, want %d", recorder.Code, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package shedding
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/syberalexis/linky-exporter/pkg/broker"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+)
+
+// Actuator interface of switched loads
+type Actuator interface {
+	// Set switches the load on or off, blocking until done or failed
+	Set(on bool) error
+	// Close releases the actuator, the load keeps its last state
+	Close() error
+}
+
+// NewActuators method to construct the configured actuators by name
+func NewActuators(actuatorsConfig []config.ActuatorConfig) map[string]Actuator {
+	actuators := make(map[string]Actuator)
+	for _, actuatorConfig := range actuatorsConfig {
+		switch {
+		case actuatorConfig.Gpio != nil && actuatorConfig.Gpio.Sysfs != "":
+			actuators[actuatorConfig.Name] = NewSysfsGpio(*actuatorConfig.Gpio)
+		case actuatorConfig.Gpio != nil:
+			actuators[actuatorConfig.Name] = NewChipGpio(*actuatorConfig.Gpio)
+		case actuatorConfig.Mqtt != nil:
+			actuators[actuatorConfig.Name] = NewMqttActuator(*actuatorConfig.Mqtt)
+		case actuatorConfig.Http != nil:
+			actuators[actuatorConfig.Name] = NewHttpActuator(*actuatorConfig.Http)
+		}
+	}
+	return actuators
+}
+
+// SysfsGpio object to drive a GPIO output through its sysfs value file, the line must be exported as an output
+type SysfsGpio struct {
+	config config.GpioConfig
+}
+
+// NewSysfsGpio method to construct SysfsGpio
+func NewSysfsGpio(gpioConfig config.GpioConfig) *SysfsGpio {
+	return &SysfsGpio{config: gpioConfig}
+}
+
+// Set implements Actuator
+func (gpio *SysfsGpio) Set(on bool) error {
+	value := "0"
+	if on != gpio.config.ActiveLow {
+		value = "1"
+	}
+	return os.WriteFile(gpio.config.Sysfs, []byte(value), 0644)
+}
+
+// Close implements Actuator
+func (gpio *SysfsGpio) Close() error {
+	return nil
+}
+
+// MqttActuator object to switch a load by publishing to a MQTT topic, the connection is retried in background
+type MqttActuator struct {
+	config config.MqttSwitch
+	client mqtt.Client
+}
+
+// NewMqttActuator method to construct MqttActuator
+func NewMqttActuator(mqttConfig config.MqttSwitch) *MqttActuator {
+	return &MqttActuator{config: mqttConfig, client: broker.NewClient(mqttConfig.MqttConnection, nil)}
+}
+
+// Set implements Actuator
+func (actuator *MqttActuator) Set(on bool) error {
+	if !actuator.client.IsConnected() {
+		return fmt.Errorf("MQTT broker %s not connected", actuator.config.Broker)
+	}
+	payload := actuator.config.PayloadOff
+	if on {
+		payload = actuator.config.PayloadOn
+	}
+	token := actuator.client.Publish(actuator.config.Topic, actuator.config.Qos, actuator.config.Retain, payload)
+	if !token.WaitTimeout(10 * time.Second) {
+		return fmt.Errorf("Timeout publishing to %s", actuator.config.Topic)
+	}
+	return token.Error()
+}
+
+// Close implements Actuator
+func (actuator *MqttActuator) Close() error {
+	broker.Disconnect(actuator.client)
+	return nil
+}
+
+// HttpActuator object to switch a load by requesting an URL for each state
+type HttpActuator struct {
+	config config.HttpSwitch
+	client *http.Client
+}
+
+// NewHttpActuator method to construct HttpActuator
+func NewHttpActuator(httpConfig config.HttpSwitch) *HttpActuator {
+	return &HttpActuator{config: httpConfig, client: &http.Client{Timeout: httpConfig.Timeout}}
+}
+
+// Set implements Actuator
+func (actuator *HttpActuator) Set(on bool) error {
+	url := actuator.config.OffUrl
+	if on {
+		url = actuator.config.OnUrl
+	}
+	request, err := http.NewRequest(actuator.config.Method, url, nil)
+	if err != nil {
+		return err
+	}
+	for name, value := range actuator.config.Headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := actuator.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("%s answered %s", url, response.Status)
+	}
+	return nil
+}
+
+// Close implements Actuator
+func (actuator *HttpActuator) Close() error {
+	actuator.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,128 @@
+//go:build linux
+
+package shedding
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"unsafe"
+
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"golang.org/x/sys/unix"
+)
+
+// GPIO character device uAPI v2, from linux/gpio.h
+const (
+	gpioLineFlagActiveLow  = 1 << 1
+	gpioLineFlagOutput     = 1 << 3
+	gpioLineAttrOutputVals = 2
+	gpioGetLineIoctl       = 0xc250b407 // _IOWR(0xB4, 0x07, struct gpio_v2_line_request)
+	gpioSetValuesIoctl     = 0xc010b40f // _IOWR(0xB4, 0x0F, struct gpio_v2_line_values)
+)
+
+type gpioLineAttribute struct {
+	id      uint32
+	padding uint32
+	values  uint64
+}
+
+type gpioLineConfigAttribute struct {
+	attr gpioLineAttribute
+	mask uint64
+}
+
+type gpioLineConfig struct {
+	flags    uint64
+	numAttrs uint32
+	padding  [5]uint32
+	attrs    [10]gpioLineConfigAttribute
+}
+
+type gpioLineRequest struct {
+	offsets         [64]uint32
+	consumer        [32]byte
+	config          gpioLineConfig
+	numLines        uint32
+	eventBufferSize uint32
+	padding         [5]uint32
+	fd              int32
+}
+
+type gpioLineValues struct {
+	bits uint64
+	mask uint64
+}
+
+// ChipGpio object to drive a GPIO output line of a character device, requested on the first switch
+type ChipGpio struct {
+	config config.GpioConfig
+	mutex  sync.Mutex
+	line   *os.File
+}
+
+// NewChipGpio method to construct ChipGpio
+func NewChipGpio(gpioConfig config.GpioConfig) *ChipGpio {
+	return &ChipGpio{config: gpioConfig}
+}
+
+// Set implements Actuator
+func (gpio *ChipGpio) Set(on bool) error {
+	gpio.mutex.Lock()
+	defer gpio.mutex.Unlock()
+
+	// The kernel applies active low, the requested value is the logical state
+	var value uint64
+	if on {
+		value = 1
+	}
+	if gpio.line == nil {
+		return gpio.request(value)
+	}
+	values := gpioLineValues{bits: value, mask: 1}
+	return ioctl(gpio.line.Fd(), gpioSetValuesIoctl, unsafe.Pointer(&values))
+}
+
+// Request the line as an output with its initial value
+func (gpio *ChipGpio) request(value uint64) error {
+	chip, err := os.OpenFile(gpio.config.Chip, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer chip.Close()
+
+	request := gpioLineRequest{numLines: 1}
+	request.offsets[0] = uint32(gpio.config.Line)
+	copy(request.consumer[:], "linky-exporter")
+	request.config.flags = gpioLineFlagOutput
+	if gpio.config.ActiveLow {
+		request.config.flags |= gpioLineFlagActiveLow
+	}
+	request.config.numAttrs = 1
+	request.config.attrs[0] = gpioLineConfigAttribute{attr: gpioLineAttribute{id: gpioLineAttrOutputVals, values: value}, mask: 1}
+	if err := ioctl(chip.Fd(), gpioGetLineIoctl, unsafe.Pointer(&request)); err != nil {
+		return fmt.Errorf("Unable to request line %d of %s : %s", gpio.config.Line, gpio.config.Chip, err)
+	}
+	gpio.line = os.NewFile(uintptr(request.fd), fmt.Sprintf("%s line %d", gpio.config.Chip, gpio.config.Line))
+	return nil
+}
+
+// Close implements Actuator, releasing the line
+func (gpio *ChipGpio) Close() error {
+	gpio.mutex.Lock()
+	defer gpio.mutex.Unlock()
+	if gpio.line == nil {
+		return nil
+	}
+	err := gpio.line.Close()
+	gpio.line = nil
+	return err
+}
+
+// Call an ioctl on a file descriptor
+func ioctl(fd uintptr, request uintptr, argument unsafe.Pointer) error {
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, request, uintptr(argument)); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+//go:build !linux
+
+package shedding
+
+import (
+	"fmt"
+
+	"github.com/syberalexis/linky-exporter/pkg/config"
+)
+
+// ChipGpio object standing for GPIO character devices, only available on Linux
+type ChipGpio struct {
+	config config.GpioConfig
+}
+
+// NewChipGpio method to construct ChipGpio
+func NewChipGpio(gpioConfig config.GpioConfig) *ChipGpio {
+	return &ChipGpio{config: gpioConfig}
+}
+
+// Set implements Actuator
+func (gpio *ChipGpio) Set(on bool) error {
+	return fmt.Errorf("GPIO character device %s is only supported on Linux, use a sysfs value file", gpio.config.Chip)
+}
+
+// Close implements Actuator
+func (gpio *ChipGpio) Close() error {
+	return nil
+}
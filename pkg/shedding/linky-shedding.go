@@ -0,0 +1,297 @@
+package shedding
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+const commandQueueSize = 16
+
+// State of a rule between frames
+type rule struct {
+	config  config.RuleConfig
+	active  bool
+	holding []bool // Conditions holding on the last frame, for hysteresis
+}
+
+// State of an actuator between frames
+type actuator struct {
+	config   config.ActuatorConfig
+	actuator Actuator
+	known    bool // Whether the state was set, unknown before the first switch and after a failure
+	on       bool
+	changed  time.Time // Time of the last switch
+	switches map[bool]uint64
+	failures uint64
+}
+
+// Switch command handed to the actuator goroutine
+type command struct {
+	name string
+	on   bool
+}
+
+// RuleState object describing whether a rule was active on the last frame
+type RuleState struct {
+	Name     string
+	Actuator string
+	Active   bool
+}
+
+// ActuatorState object describing the state and switches of an actuator
+type ActuatorState struct {
+	Name        string
+	Known       bool
+	On          bool
+	SwitchesOn  uint64
+	SwitchesOff uint64
+	Failures    uint64
+}
+
+// Engine object to switch actuators from rules evaluated on each frame
+type Engine struct {
+	config    config.SheddingConfig
+	mutex     sync.Mutex
+	rules     []*rule
+	actuators map[string]*actuator
+	commands  chan command
+	done      chan struct{}
+	closed    bool
+}
+
+// NewEngine method to construct Engine, actuators are switched in background
+func NewEngine(sheddingConfig config.SheddingConfig, actuators map[string]Actuator) *Engine {
+	engine := &Engine{
+		config:    sheddingConfig,
+		actuators: make(map[string]*actuator),
+		commands:  make(chan command, commandQueueSize),
+		done:      make(chan struct{}),
+	}
+	for _, actuatorConfig := range sheddingConfig.Actuators {
+		engine.actuators[actuatorConfig.Name] = &actuator{config: actuatorConfig, actuator: actuators[actuatorConfig.Name], switches: make(map[bool]uint64)}
+	}
+	for _, ruleConfig := range sheddingConfig.Rules {
+		engine.rules = append(engine.rules, &rule{config: ruleConfig, holding: make([]bool, len(ruleConfig.Conditions))})
+	}
+
+	go func() {
+		defer close(engine.done)
+		for command := range engine.commands {
+			engine.run(command)
+		}
+	}()
+	return engine
+}
+
+// Configured returns whether the engine was built with a configuration
+func (engine *Engine) Configured(sheddingConfig config.SheddingConfig) bool {
+	return reflect.DeepEqual(engine.config, sheddingConfig)
+}
+
+// Add a frame of a meter, evaluating its rules and switching actuators whose state changes
+func (engine *Engine) Add(meter string, frame core.Frame) {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+	measurement := frame.Measurement()
+
+	// Rules are evaluated on the frames of their meter, and keep their state on frames of other meters
+	evaluated := make(map[string]bool)
+	for _, rule := range engine.rules {
+		if rule.config.Meter != "" && rule.config.Meter != meter {
+			continue
+		}
+		evaluated[rule.config.Actuator] = true
+		rule.active = rule.evaluate(frame, measurement)
+	}
+
+	// The first active rule of an actuator sets its state, whatever its meter, the default state applies without any
+	wanted := make(map[string]bool)
+	for _, rule := range engine.rules {
+		if _, set := wanted[rule.config.Actuator]; rule.active && !set {
+			wanted[rule.config.Actuator] = rule.config.State == "on"
+		}
+	}
+
+	for name := range evaluated {
+		actuator := engine.actuators[name]
+		on, set := wanted[name]
+		if !set {
+			on = actuator.config.Default == "on"
+		}
+		if actuator.known && actuator.on == on {
+			continue
+		}
+		if actuator.known {
+			minimum := actuator.config.MinOn
+			if !actuator.on {
+				minimum = actuator.config.MinOff
+			}
+			if frame.Time.Sub(actuator.changed) < minimum {
+				continue
+			}
+			log.Infof("Switching %s %s", name, onOff(on))
+		}
+
+		actuator.known, actuator.on, actuator.changed = true, on, frame.Time
+		actuator.switches[on]++
+		engine.push(command{name: name, on: on})
+	}
+}
+
+// Whether all conditions of a rule hold on a frame
+func (rule *rule) evaluate(frame core.Frame, measurement *core.LinkyMeasurement) bool {
+	active := true
+	for i, condition := range rule.config.Conditions {
+		rule.holding[i] = holds(condition, rule.holding[i], frame, measurement)
+		active = active && rule.holding[i]
+	}
+	return active
+}
+
+// Whether a condition holds, a sample has to cross back the hysteresis margin to stop holding
+func holds(condition config.ConditionConfig, holding bool, frame core.Frame, measurement *core.LinkyMeasurement) bool {
+	if condition.Label != "" {
+		value, present := label(frame, condition.Label)
+		if condition.Present != nil {
+			return present == *condition.Present
+		}
+		return present && value == *condition.Equals
+	}
+
+	value, present := sample(measurement, condition.Sample)
+	margin := 0.0
+	if holding {
+		margin = condition.Hysteresis
+	}
+	switch {
+	case condition.Present != nil:
+		return present == *condition.Present
+	case !present:
+		return false
+	case condition.Equals != nil:
+		expected, _ := strconv.ParseFloat(*condition.Equals, 64)
+		return value == expected
+	case condition.Above != nil:
+		return value > *condition.Above-margin
+	default:
+		return value < *condition.Below+margin
+	}
+}
+
+// Value of a data set of a frame, labels are case insensitive
+func label(frame core.Frame, name string) (string, bool) {
+	for _, dataSet := range frame.DataSets {
+		if strings.EqualFold(dataSet.Label, name) {
+			return strings.TrimSpace(dataSet.Value), true
+		}
+	}
+	return "", false
+}
+
+// Value of a sample of a measurement by its key
+func sample(measurement *core.LinkyMeasurement, key string) (float64, bool) {
+	for _, sample := range measurement.Samples {
+		if sample.Key() == key {
+			return sample.Value, true
+		}
+	}
+	return 0, false
+}
+
+// Queue a switch, it is dropped when actuators are late, the mutex must be held
+func (engine *Engine) push(command command) {
+	if engine.closed {
+		return
+	}
+	select {
+	case engine.commands <- command:
+	default:
+		log.Warnf("Actuators are late, switching %s %s dropped", command.name, onOff(command.on))
+		engine.actuators[command.name].known = false
+	}
+}
+
+// Switch an actuator, its state is unknown after a failure so that it is set again on the next frame
+func (engine *Engine) run(command command) {
+	actuator := engine.actuators[command.name]
+	if actuator.actuator == nil {
+		return
+	}
+	if err := actuator.actuator.Set(command.on); err != nil {
+		log.Errorf("Failed to switch %s %s : %s", command.name, onOff(command.on), err)
+		engine.mutex.Lock()
+		actuator.failures++
+		actuator.known = false
+		engine.mutex.Unlock()
+	}
+}
+
+// Rules returns whether each rule was active on the last frame it evaluated, in configuration order
+func (engine *Engine) Rules() []RuleState {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+	var states []RuleState
+	for i, rule := range engine.rules {
+		name := rule.config.Name
+		if name == "" {
+			name = strconv.Itoa(i + 1)
+		}
+		states = append(states, RuleState{Name: name, Actuator: rule.config.Actuator, Active: rule.active})
+	}
+	return states
+}
+
+// Actuators returns the state and switches of each actuator, in configuration order
+func (engine *Engine) Actuators() []ActuatorState {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+	var states []ActuatorState
+	for _, actuatorConfig := range engine.config.Actuators {
+		actuator := engine.actuators[actuatorConfig.Name]
+		states = append(states, ActuatorState{
+			Name:        actuatorConfig.Name,
+			Known:       actuator.known,
+			On:          actuator.on,
+			SwitchesOn:  actuator.switches[true],
+			SwitchesOff: actuator.switches[false],
+			Failures:    actuator.failures,
+		})
+	}
+	return states
+}
+
+// Close waits for queued switches and releases actuators, loads keep their last state
+func (engine *Engine) Close() error {
+	engine.mutex.Lock()
+	if !engine.closed {
+		engine.closed = true
+		close(engine.commands)
+	}
+	engine.mutex.Unlock()
+	<-engine.done
+
+	for name, actuator := range engine.actuators {
+		if actuator.actuator == nil {
+			continue
+		}
+		if err := actuator.actuator.Close(); err != nil {
+			log.Errorf("Failed to close actuator %s : %s", name, err)
+		}
+	}
+	return nil
+}
+
+// Name of a state
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
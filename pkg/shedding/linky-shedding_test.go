@@ -0,0 +1,121 @@
+package shedding
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/internal/tictest"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Actuator recording its switches
+type fakeActuator struct {
+	mutex    sync.Mutex
+	switches []string
+}
+
+func (actuator *fakeActuator) Set(on bool) error {
+	actuator.mutex.Lock()
+	defer actuator.mutex.Unlock()
+	actuator.switches = append(actuator.switches, onOff(on))
+	return nil
+}
+
+func (actuator *fakeActuator) Close() error {
+	return nil
+}
+
+func TestEngineTableDriven(t *testing.T) {
+	// Given
+	start := time.Date(2023, 1, 10, 7, 0, 0, 0, time.UTC)
+	hpjr, present, injection, overload := "HPJR", true, 1500.0, 6000.0
+	var tests = []struct {
+		name     string
+		mode     core.LinkyMode
+		meters   []string   // Meter of each frame, default when not set
+		frames   [][]string // Data sets of each frame
+		minOff   time.Duration
+		def      string
+		rules    []config.RuleConfig
+		expected []string
+	}{
+		{
+			name:     "red day peak hours",
+			mode:     core.Historical,
+			frames:   [][]string{{"PTEC HCJB"}, {"PTEC HPJR"}, {"PTEC HPJR"}, {"PTEC HCJR"}},
+			def:      "on",
+			rules:    []config.RuleConfig{{State: "off", Conditions: []config.ConditionConfig{{Label: "PTEC", Equals: &hpjr}}}},
+			expected: []string{"on", "off", "on"},
+		},
+		{
+			name:     "minimum off time",
+			mode:     core.Historical,
+			frames:   [][]string{{"PTEC HPJR"}, {"PTEC HCJR"}, {"PTEC HCJR"}, {"PTEC HCJR"}, {"PTEC HCJR"}},
+			minOff:   30 * time.Second,
+			def:      "on",
+			rules:    []config.RuleConfig{{State: "off", Conditions: []config.ConditionConfig{{Label: "PTEC", Equals: &hpjr}}}},
+			expected: []string{"off", "on"},
+		},
+		{
+			name:     "overrun signaled",
+			mode:     core.Historical,
+			frames:   [][]string{{"IINST 029"}, {"IINST 031", "ADPS 031"}, {"IINST 029"}},
+			def:      "on",
+			rules:    []config.RuleConfig{{State: "off", Conditions: []config.ConditionConfig{{Label: "ADPS", Present: &present}}}},
+			expected: []string{"on", "off", "on"},
+		},
+		{
+			name:     "injection with hysteresis",
+			mode:     core.Standard,
+			frames:   [][]string{{"SINSTI\t01000"}, {"SINSTI\t01600"}, {"SINSTI\t01400"}, {"SINSTI\t01200"}},
+			def:      "off",
+			rules:    []config.RuleConfig{{State: "on", Conditions: []config.ConditionConfig{{Sample: "apparent_power_exported", Above: &injection, Hysteresis: 200}}}},
+			expected: []string{"off", "on", "off"},
+		},
+		{
+			name:   "rules of two meters",
+			mode:   core.Standard,
+			meters: []string{"consumption", "producer", "consumption", "producer", "consumption", "producer"},
+			frames: [][]string{{"SINSTS\t01000"}, {"SINSTI\t01600"}, {"SINSTS\t01000"}, {"SINSTI\t01600"}, {"SINSTS\t07000"}, {"SINSTI\t01600"}},
+			def:    "off",
+			rules: []config.RuleConfig{
+				{Meter: "consumption", State: "off", Conditions: []config.ConditionConfig{{Sample: "apparent_power_imported", Above: &overload}}},
+				{Meter: "producer", State: "on", Conditions: []config.ConditionConfig{{Sample: "apparent_power_exported", Above: &injection}}},
+			},
+			expected: []string{"off", "on", "off"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range tt.rules {
+				tt.rules[i].Actuator = "load"
+			}
+			sheddingConfig := config.SheddingConfig{
+				Actuators: []config.ActuatorConfig{{Name: "load", Default: tt.def, MinOff: tt.minOff}},
+				Rules:     tt.rules,
+			}
+			fake := &fakeActuator{}
+			engine := NewEngine(sheddingConfig, map[string]Actuator{"load": fake})
+
+			// When
+			for i, dataSets := range tt.frames {
+				frame := tictest.Frame(t, tt.mode, start.Add(time.Duration(i)*10*time.Second), dataSets...)
+				meter := "default"
+				if tt.meters != nil {
+					meter = tt.meters[i]
+				}
+				engine.Add(meter, frame)
+			}
+			engine.Close()
+
+			// Then
+			if !reflect.DeepEqual(fake.switches, tt.expected) {
+				t.Errorf("got %v, want %v", fake.switches, tt.expected)
+			}
+		})
+	}
+}
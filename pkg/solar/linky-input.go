@@ -0,0 +1,76 @@
+package solar
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	log "github.com/sirupsen/logrus"
+	"github.com/syberalexis/linky-exporter/pkg/broker"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+)
+
+// Production object received from an input, as a total energy index or a power
+type Production struct {
+	Energy *float64 `json:"energy_wh"`
+	Power  *float64 `json:"power_w"`
+}
+
+// ParseProduction method to decode a production, as a JSON object or a number of Wh
+func ParseProduction(payload []byte) (Production, error) {
+	var production Production
+	content := strings.TrimSpace(string(payload))
+	if energy, err := strconv.ParseFloat(content, 64); err == nil {
+		production.Energy = &energy
+	} else if err := json.Unmarshal([]byte(content), &production); err != nil {
+		return Production{}, fmt.Errorf("Unable to parse production : %s", err)
+	}
+	if production.Energy == nil && production.Power == nil {
+		return Production{}, fmt.Errorf("Production requires energy_wh or power_w")
+	}
+	if (production.Energy != nil && *production.Energy < 0) || (production.Power != nil && *production.Power < 0) {
+		return Production{}, fmt.Errorf("Production must be positive")
+	}
+	return production, nil
+}
+
+// MqttInput object to receive the production from a MQTT topic, the connection is retried in background
+type MqttInput struct {
+	config config.MqttProductionInput
+	client mqtt.Client
+}
+
+// NewMqttInput method to construct MqttInput, adding each production received to the tracker
+func NewMqttInput(inputConfig config.MqttProductionInput, tracker *Tracker) *MqttInput {
+	input := &MqttInput{config: inputConfig}
+	handler := func(client mqtt.Client, message mqtt.Message) {
+		production, err := ParseProduction(message.Payload())
+		if err != nil {
+			log.Warnf("Invalid production on %s : %s", message.Topic(), err)
+			return
+		}
+		tracker.AddProduction(time.Now(), production.Energy, production.Power)
+	}
+
+	input.client = broker.NewClient(inputConfig.MqttConnection, func(options *mqtt.ClientOptions) {
+		options.SetOnConnectHandler(func(client mqtt.Client) {
+			// Subscriptions are lost with the session, subscribe on each connection
+			client.Subscribe(inputConfig.Topic, inputConfig.Qos, handler)
+		})
+	})
+	return input
+}
+
+// Configured returns whether the input was built with a configuration
+func (input *MqttInput) Configured(inputConfig config.MqttProductionInput) bool {
+	return input.config == inputConfig
+}
+
+// Close disconnects from the broker
+func (input *MqttInput) Close() error {
+	broker.Disconnect(input.client)
+	return nil
+}
@@ -0,0 +1,200 @@
+package solar
+
+import (
+	"sync"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+	"github.com/syberalexis/linky-exporter/pkg/period"
+)
+
+// Longest gap over which production power is integrated, longer ones are ignored
+const maxPowerGap = 5 * time.Minute
+
+// Energies object to hold the energy flows of a window in Wh
+type Energies struct {
+	Imported float64 // Drawn from the grid
+	Exported float64 // Injected into the grid
+	Produced float64 // Produced by the photovoltaic installation
+}
+
+// SelfConsumed returns the production consumed by the installation
+func (energies Energies) SelfConsumed() float64 {
+	if energies.Produced < energies.Exported {
+		return 0
+	}
+	return energies.Produced - energies.Exported
+}
+
+// SelfConsumption returns the share of the production consumed by the installation, false without production
+func (energies Energies) SelfConsumption() (float64, bool) {
+	if energies.Produced <= 0 {
+		return 0, false
+	}
+	return energies.SelfConsumed() / energies.Produced, true
+}
+
+// SelfSufficiency returns the share of the consumption covered by the production, false without consumption
+func (energies Energies) SelfSufficiency() (float64, bool) {
+	consumption := energies.Imported + energies.SelfConsumed()
+	if consumption <= 0 {
+		return 0, false
+	}
+	return energies.SelfConsumed() / consumption, true
+}
+
+// Balance object to hold the energy flows of a period window
+type Balance struct {
+	Period string // day, week, month or year
+	Window string // current or previous
+	Energies
+}
+
+// Windows of a period
+type window struct {
+	start       time.Time
+	current     Energies
+	previous    Energies
+	hasPrevious bool // Whether the previous window was fully followed
+}
+
+// Tracker object to accumulate the energy drawn, injected and produced over calendar periods, since the exporter started
+type Tracker struct {
+	mutex      sync.Mutex
+	config     *config.SolarConfig
+	dayOffset  *time.Duration // Configured start of the energy day, from the grid meter contract when not set
+	contract   string         // Contract of the grid meter
+	windows    map[string]*window
+	imported   *float64 // Last indexes, unknown before the first frame
+	exported   *float64
+	produced   *float64
+	power      float64 // Last production power in W
+	powerTime  time.Time
+	powerKnown bool
+}
+
+// NewTracker method to construct Tracker, disabled until configured
+func NewTracker() *Tracker {
+	return &Tracker{windows: make(map[string]*window)}
+}
+
+// Configure changes the grid meter and production source, indexes are followed again when they change,
+// the energy day starts as for period totals
+func (tracker *Tracker) Configure(solarConfig *config.SolarConfig, dayOffset *time.Duration) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	if solarConfig == nil || tracker.config == nil || solarConfig.GridMeter != tracker.config.GridMeter {
+		tracker.imported, tracker.exported = nil, nil
+	}
+	if solarConfig == nil || tracker.config == nil || solarConfig.Production.Meter != tracker.config.Production.Meter {
+		tracker.produced, tracker.powerKnown = nil, false
+	}
+	tracker.config = solarConfig
+	tracker.dayOffset = dayOffset
+}
+
+// Add a frame, of the grid meter for energy drawn and injected, or of the production meter for energy produced
+func (tracker *Tracker) Add(meter string, frame core.Frame) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	if tracker.config == nil {
+		return
+	}
+	measurement := frame.Measurement()
+
+	switch meter {
+	case tracker.config.GridMeter:
+		tracker.contract = measurement.Contract
+		if imported, ok := measurement.Get(core.ActiveEnergy, core.Imported, 0, ""); ok {
+			tracker.add(frame.Time, Energies{Imported: delta(&tracker.imported, imported)})
+		}
+		if exported, ok := measurement.Get(core.ActiveEnergy, core.Exported, 0, ""); ok {
+			tracker.add(frame.Time, Energies{Exported: delta(&tracker.exported, exported)})
+		}
+	case tracker.config.Production.Meter:
+		if produced, ok := measurement.Get(core.ActiveEnergy, core.Exported, 0, ""); ok {
+			tracker.add(frame.Time, Energies{Produced: delta(&tracker.produced, produced)})
+		}
+		if power, ok := measurement.Get(core.ApparentPower, core.Exported, 0, ""); ok {
+			tracker.power, tracker.powerTime, tracker.powerKnown = power, frame.Time, true
+		}
+	}
+}
+
+// AddProduction adds a production received from an input, as a total energy index in Wh or a power in W integrated over time
+func (tracker *Tracker) AddProduction(at time.Time, energy *float64, power *float64) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	if tracker.config == nil {
+		return
+	}
+
+	switch {
+	case energy != nil:
+		tracker.add(at, Energies{Produced: delta(&tracker.produced, *energy)})
+	case power != nil && tracker.powerKnown && at.After(tracker.powerTime) && at.Sub(tracker.powerTime) <= maxPowerGap:
+		// Power is held since the previous input
+		tracker.add(at, Energies{Produced: tracker.power * at.Sub(tracker.powerTime).Hours()})
+	}
+	if power != nil {
+		tracker.power, tracker.powerTime, tracker.powerKnown = *power, at, true
+	}
+}
+
+// Difference between an index and the last one, which is replaced, 0 for the first index or a lower one from a replaced meter
+func delta(last **float64, value float64) float64 {
+	previous := *last
+	*last = &value
+	if previous == nil || value < *previous {
+		return 0
+	}
+	return value - *previous
+}
+
+// Add energies to the current window of each period, starting new windows when periods end
+func (tracker *Tracker) add(at time.Time, energies Energies) {
+	offset := period.DayOffset(tracker.dayOffset, tracker.contract)
+	for _, name := range period.Periods {
+		start := period.Start(name, at, offset)
+		current, exists := tracker.windows[name]
+		switch {
+		case !exists:
+			current = &window{start: start}
+			tracker.windows[name] = current
+		case start.After(current.start):
+			following := current.start.Equal(period.Start(name, start.Add(-time.Second), offset))
+			*current = window{start: start, previous: current.current, hasPrevious: following}
+		}
+		current.current.Imported += energies.Imported
+		current.current.Exported += energies.Exported
+		current.current.Produced += energies.Produced
+	}
+}
+
+// Balances returns the energy flows of each period, current windows first
+func (tracker *Tracker) Balances() []Balance {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	var balances []Balance
+	for _, name := range period.Periods {
+		current, exists := tracker.windows[name]
+		if !exists {
+			continue
+		}
+		balances = append(balances, Balance{Period: name, Window: "current", Energies: current.current})
+		if current.hasPrevious {
+			balances = append(balances, Balance{Period: name, Window: "previous", Energies: current.previous})
+		}
+	}
+	return balances
+}
+
+// Production returns the last production power in W, false when the source does not provide it
+func (tracker *Tracker) Production() (float64, bool) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	return tracker.power, tracker.powerKnown
+}
@@ -0,0 +1,93 @@
+package solar
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/internal/tictest"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Standard frame of a producer meter at a time
+func producerFrame(t *testing.T, at time.Time, east string, eait string, sinsti string) core.Frame {
+	return tictest.Frame(t, core.Standard, at, "EAST\t"+east, "EAIT\t"+eait, "SINSTI\t"+sinsti)
+}
+
+func TestTrackerSelfConsumption(t *testing.T) {
+	// Given
+	tracker := NewTracker()
+	tracker.Configure(&config.SolarConfig{GridMeter: "grid", Production: config.ProductionConfig{Meter: "pv"}}, nil)
+	day := time.Date(2023, 6, 14, 12, 0, 0, 0, time.Local)
+
+	// When
+	tracker.Add("grid", producerFrame(t, day, "000010000", "000005000", "00000"))
+	tracker.Add("pv", producerFrame(t, day, "000000000", "000020000", "01500"))
+	tracker.Add("grid", producerFrame(t, day.Add(time.Hour), "000010500", "000005800", "00800"))
+	tracker.Add("pv", producerFrame(t, day.Add(time.Hour), "000000000", "000022000", "01800"))
+
+	// Then
+	expected := Energies{Imported: 500, Exported: 800, Produced: 2000}
+	balances := tracker.Balances()
+	if len(balances) != 4 || balances[0].Period != "day" || balances[0].Energies != expected {
+		t.Fatalf("got %+v, want day %+v", balances, expected)
+	}
+	selfConsumption, _ := expected.SelfConsumption()
+	selfSufficiency, _ := expected.SelfSufficiency()
+	if selfConsumption != 0.6 || selfSufficiency != 1200.0/1700 {
+		t.Errorf("got %v %v, want 0.6 %v", selfConsumption, selfSufficiency, 1200.0/1700)
+	}
+	if power, ok := tracker.Production(); !ok || power != 1800 {
+		t.Errorf("got production %v %v, want 1800", power, ok)
+	}
+}
+
+func TestTrackerTempoDayOffset(t *testing.T) {
+	// Given
+	tracker := NewTracker()
+	tracker.Configure(&config.SolarConfig{GridMeter: "grid", Production: config.ProductionConfig{Http: true}}, nil)
+	day := time.Date(2023, 6, 14, 5, 0, 0, 0, time.Local)
+	tempoFrame := func(at time.Time, east string) core.Frame {
+		return tictest.Frame(t, core.Standard, at, "NGTF\tTEMPO", "EAST\t"+east)
+	}
+
+	// When
+	tracker.Add("grid", tempoFrame(day, "000010000"))
+	tracker.Add("grid", tempoFrame(day.Add(30*time.Minute), "000010500"))
+	tracker.Add("grid", tempoFrame(day.Add(2*time.Hour), "000011000"))
+
+	// Then
+	balances := tracker.Balances()
+	if len(balances) < 2 || balances[0].Period != "day" || balances[0].Imported != 500 {
+		t.Fatalf("got %+v, want a day started at 06:00", balances)
+	}
+}
+
+func TestParseProductionTableDriven(t *testing.T) {
+	// Given
+	energy, power := 1234.5, 850.0
+	var tests = []struct {
+		payload  string
+		expected Production
+		ok       bool
+	}{
+		{"1234.5", Production{Energy: &energy}, true},
+		{`{"power_w": 850}`, Production{Power: &power}, true},
+		{`{"energy_wh": 1234.5, "power_w": 850}`, Production{Energy: &energy, Power: &power}, true},
+		{`{"voltage": 230}`, Production{}, false},
+		{"-5", Production{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.payload, func(t *testing.T) {
+			// When
+			production, err := ParseProduction([]byte(tt.payload))
+
+			// Then
+			if (err == nil) != tt.ok || !reflect.DeepEqual(production, tt.expected) {
+				t.Errorf("got %+v %v, want %+v", production, err, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Delay between two deletions of records older than the retention
+const pruneInterval = time.Hour
+
+// Store object to record measurements of meters in an embedded database, one bucket per meter keyed by time
+type Store struct {
+	db     *bolt.DB
+	mutex  sync.Mutex
+	config config.StoreConfig
+	last   map[string]time.Time // Time of the last record of each meter
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// Point object to hold the values of a meter at a time
+type Point struct {
+	Time   time.Time          `json:"time"`
+	Values map[string]float64 `json:"values"` // Sample values by Sample.Key
+}
+
+// Open method to open or create the database of the store and start deleting old records
+func Open(storeConfig config.StoreConfig) (*Store, error) {
+	db, err := bolt.Open(storeConfig.Path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to open store %s : %s", storeConfig.Path, err)
+	}
+
+	store := &Store{
+		db:     db,
+		config: storeConfig,
+		last:   make(map[string]time.Time),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go store.pruneLoop()
+	return store, nil
+}
+
+// Configure changes the retention and interval of the store, the path can not change
+func (store *Store) Configure(storeConfig config.StoreConfig) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.config = storeConfig
+}
+
+// Path returns the database file of the store
+func (store *Store) Path() string {
+	return store.db.Path()
+}
+
+// Record the samples of a frame, unless the meter was recorded less than an interval ago
+func (store *Store) Record(meter string, frame core.Frame) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	last, known := store.last[meter]
+	if !known {
+		last = store.lastRecord(meter)
+	}
+	if !last.IsZero() && frame.Time.Sub(last) < store.config.Interval {
+		return nil
+	}
+
+	values := make(map[string]float64)
+	for _, sample := range frame.Measurement().Samples {
+		values[sample.Key()] = sample.Value
+	}
+	content, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	err = store.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(meter))
+		if err != nil {
+			return err
+		}
+		return bucket.Put(timeKey(frame.Time), content)
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to record frame of %s : %s", meter, err)
+	}
+	store.last[meter] = frame.Time
+	return nil
+}
+
+// Return the time of the last record of a meter, zero when none
+func (store *Store) lastRecord(meter string) time.Time {
+	var last time.Time
+	store.db.View(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket([]byte(meter)); bucket != nil {
+			if key, _ := bucket.Cursor().Last(); key != nil {
+				last = keyTime(key)
+			}
+		}
+		return nil
+	})
+	return last
+}
+
+// Query the points of a meter between from and to included. With a step, points are aggregated at the end of each step :
+// energy counters keep their last value and other values are averaged.
+func (store *Store) Query(meter string, from time.Time, to time.Time, step time.Duration) ([]Point, error) {
+	var points []Point
+	err := store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(meter))
+		if bucket == nil {
+			return nil
+		}
+
+		cursor := bucket.Cursor()
+		for key, content := cursor.Seek(timeKey(from)); key != nil && !keyTime(key).After(to); key, content = cursor.Next() {
+			var values map[string]float64
+			if err := json.Unmarshal(content, &values); err != nil {
+				return fmt.Errorf("Corrupted record of %s at %s : %s", meter, keyTime(key), err)
+			}
+			points = append(points, Point{Time: keyTime(key), Values: values})
+		}
+		return nil
+	})
+	if err != nil || step <= 0 {
+		return points, err
+	}
+	return aggregate(points, from, step), nil
+}
+
+// Aggregate points by step, each step ending at from + n * step
+func aggregate(points []Point, from time.Time, step time.Duration) []Point {
+	var aggregated []Point
+	var counts map[string]int
+	for _, point := range points {
+		end := from.Add((point.Time.Sub(from) + step - 1) / step * step)
+		if len(aggregated) == 0 || !aggregated[len(aggregated)-1].Time.Equal(end) {
+			aggregated = append(aggregated, Point{Time: end, Values: make(map[string]float64)})
+			counts = make(map[string]int)
+		}
+
+		current := aggregated[len(aggregated)-1]
+		for key, value := range point.Values {
+			if isCounter(key) {
+				current.Values[key] = value
+				continue
+			}
+			counts[key]++
+			current.Values[key] += (value - current.Values[key]) / float64(counts[key])
+		}
+	}
+	return aggregated
+}
+
+// Whether a sample key is an energy counter
+func isCounter(key string) bool {
+	return strings.HasPrefix(key, string(core.ActiveEnergy)) || strings.HasPrefix(key, string(core.ReactiveEnergy))
+}
+
+// Delete records older than the retention, every prune interval until Close
+func (store *Store) pruneLoop() {
+	defer close(store.done)
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		if err := store.prune(time.Now()); err != nil {
+			log.Errorf("Failed to delete old records : %s", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-store.stop:
+			return
+		}
+	}
+}
+
+// Delete records older than the retention
+func (store *Store) prune(now time.Time) error {
+	store.mutex.Lock()
+	limit := timeKey(now.Add(-store.config.Retention))
+	store.mutex.Unlock()
+
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			cursor := bucket.Cursor()
+			for key, _ := cursor.First(); key != nil && string(key) < string(limit); key, _ = cursor.First() {
+				if err := cursor.Delete(); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// Close the database
+func (store *Store) Close() error {
+	close(store.stop)
+	<-store.done
+	return store.db.Close()
+}
+
+// Key of a time, big endian nanoseconds sorting records by time
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
+
+// Time of a key
+func keyTime(key []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key)))
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/internal/tictest"
+	"github.com/syberalexis/linky-exporter/pkg/config"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Historical frame with a base index and an apparent power
+func testFrame(t *testing.T, at time.Time, base string, papp string) core.Frame {
+	return tictest.Frame(t, core.Historical, at, "OPTARIF BASE", "BASE "+base, "PAPP "+papp)
+}
+
+// Open a store in a temporary directory, closed at the end of the test
+func testStore(t *testing.T, interval time.Duration) *Store {
+	store, err := Open(config.StoreConfig{Path: filepath.Join(t.TempDir(), "linky.db"), Retention: time.Hour, Interval: interval})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestStoreQueryTableDriven(t *testing.T) {
+	// Given
+	// Recent records, as older records than the retention may be deleted as soon as the store is opened
+	start := time.Now().Truncate(time.Minute)
+	var tests = []struct {
+		name     string
+		interval time.Duration
+		step     time.Duration
+		expected []Point
+	}{
+		{"raw", time.Second, 0, []Point{
+			{start, map[string]float64{"active_energy_imported": 1000, "active_energy_imported_f1": 1000, "apparent_power_imported": 100}},
+			{start.Add(30 * time.Second), map[string]float64{"active_energy_imported": 1010, "active_energy_imported_f1": 1010, "apparent_power_imported": 300}},
+			{start.Add(60 * time.Second), map[string]float64{"active_energy_imported": 1020, "active_energy_imported_f1": 1020, "apparent_power_imported": 200}},
+		}},
+		{"interval", time.Minute, 0, []Point{
+			{start, map[string]float64{"active_energy_imported": 1000, "active_energy_imported_f1": 1000, "apparent_power_imported": 100}},
+			{start.Add(60 * time.Second), map[string]float64{"active_energy_imported": 1020, "active_energy_imported_f1": 1020, "apparent_power_imported": 200}},
+		}},
+		{"step", time.Second, time.Minute, []Point{
+			{start, map[string]float64{"active_energy_imported": 1000, "active_energy_imported_f1": 1000, "apparent_power_imported": 100}},
+			{start.Add(time.Minute), map[string]float64{"active_energy_imported": 1020, "active_energy_imported_f1": 1020, "apparent_power_imported": 250}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := testStore(t, tt.interval)
+			for i, values := range [][2]string{{"000001000", "00100"}, {"000001010", "00300"}, {"000001020", "00200"}} {
+				if err := store.Record("main", testFrame(t, start.Add(time.Duration(i)*30*time.Second), values[0], values[1])); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// When
+			points, err := store.Query("main", start, start.Add(time.Hour), tt.step)
+
+			// Then
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(points) != len(tt.expected) {
+				t.Fatalf("got %+v, want %+v", points, tt.expected)
+			}
+			for i, point := range points {
+				if !point.Time.Equal(tt.expected[i].Time) || len(point.Values) != len(tt.expected[i].Values) {
+					t.Errorf("got %+v, want %+v", point, tt.expected[i])
+				}
+				for key, value := range tt.expected[i].Values {
+					if point.Values[key] != value {
+						t.Errorf("got %s=%v, want %v", key, point.Values[key], value)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	// Given
+	store := testStore(t, time.Second)
+	now := time.Now()
+	store.Record("main", testFrame(t, now.Add(-2*time.Hour), "000001000", "00100"))
+	store.Record("main", testFrame(t, now.Add(-time.Minute), "000001010", "00100"))
+
+	// When
+	err := store.prune(now)
+
+	// Then
+	if err != nil {
+		t.Fatal(err)
+	}
+	points, _ := store.Query("main", now.Add(-24*time.Hour), now, 0)
+	if len(points) != 1 || points[0].Values["active_energy_imported"] != 1010 {
+		t.Errorf("got %+v, want the last record only", points)
+	}
+}
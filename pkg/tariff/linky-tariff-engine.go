@@ -0,0 +1,108 @@
+package tariff
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+// Duration of a year to prorate yearly subscriptions
+const year = 36525 * 24 * time.Hour / 100
+
+// Costs object to hold costs accumulated by a meter since the exporter started
+type Costs struct {
+	Energy       map[string]float64 // Euros by supplier index
+	Subscription float64            // Euros
+}
+
+// State of a meter between two frames
+type meterState struct {
+	last         time.Time
+	indexes      map[string]float64 // Last energy index in Wh by supplier index
+	costs        Costs
+	unsubscribed *Tariff // Last tariff without price for the subscribed power, to warn once
+}
+
+// Engine object to accumulate costs of meters, applying the tariff of the catalogue at each frame time
+type Engine struct {
+	mutex     sync.Mutex
+	catalogue *Catalogue
+	meters    map[string]*meterState
+}
+
+// NewEngine method to construct Engine, without catalogue no cost is computed
+func NewEngine() *Engine {
+	return &Engine{meters: make(map[string]*meterState)}
+}
+
+// SetCatalogue replaces the tariffs, keeping accumulated costs
+func (engine *Engine) SetCatalogue(catalogue *Catalogue) {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+	engine.catalogue = catalogue
+}
+
+// Add the costs of the energy consumed and the subscription elapsed since the previous frame of the meter
+func (engine *Engine) Add(meter string, frame core.Frame) {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+	if engine.catalogue == nil {
+		return
+	}
+
+	state, exists := engine.meters[meter]
+	if !exists {
+		state = &meterState{indexes: make(map[string]float64), costs: Costs{Energy: make(map[string]float64)}}
+		engine.meters[meter] = state
+	}
+	tariff := engine.catalogue.At(frame.Time)
+	measurement := frame.Measurement()
+
+	for _, sample := range measurement.Filter(core.ActiveEnergy) {
+		if sample.Direction != core.Imported || !supplierIndexRegex.MatchString(sample.Index) {
+			continue
+		}
+		previous, known := state.indexes[sample.Index]
+		state.indexes[sample.Index] = sample.Value
+		if tariff == nil || !known {
+			continue
+		}
+		// A lower index comes from a replaced meter, its energy is unknown
+		if sample.Value < previous {
+			log.Warnf("Energy index %s of %s decreased, ignoring", sample.Index, meter)
+			continue
+		}
+		if _, priced := tariff.Prices[sample.Index]; !priced && sample.Value > previous {
+			log.Debugf("No price for index %s of %s", sample.Index, meter)
+		}
+		state.costs.Energy[sample.Index] += (sample.Value - previous) / 1000 * tariff.Prices[sample.Index]
+	}
+
+	if power, ok := measurement.Get(core.ReferencePower, core.NoDirection, 0, ""); ok && tariff != nil && !state.last.IsZero() && frame.Time.After(state.last) {
+		price, priced := tariff.Subscription[power]
+		if !priced && state.unsubscribed != tariff {
+			log.Warnf("No subscription price for %g kVA of %s, ignoring subscription costs", power, meter)
+			state.unsubscribed = tariff
+		}
+		state.costs.Subscription += price * float64(frame.Time.Sub(state.last)) / float64(year)
+	}
+	state.last = frame.Time
+}
+
+// Costs returns the costs accumulated by a meter, false before its first frame
+func (engine *Engine) Costs(meter string) (Costs, bool) {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+
+	state, exists := engine.meters[meter]
+	if !exists {
+		return Costs{}, false
+	}
+	costs := Costs{Energy: make(map[string]float64), Subscription: state.costs.Subscription}
+	for index, cost := range state.costs.Energy {
+		costs.Energy[index] = cost
+	}
+	return costs, true
+}
@@ -0,0 +1,109 @@
+package tariff
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Names of tariff indexes of each option, as F1..F10 supplier indexes of the meter
+var optionIndexes = map[string]map[string]string{
+	"base":   {"BASE": "F1"},
+	"hphc":   {"HC": "F1", "HP": "F2"},
+	"tempo":  {"HCJB": "F1", "HPJB": "F2", "HCJW": "F3", "HPJW": "F4", "HCJR": "F5", "HPJR": "F6"},
+	"ejp":    {"HN": "F1", "PM": "F2"},
+	"zen_we": {"HC": "F1", "HP": "F2"},
+}
+
+var supplierIndexRegex = regexp.MustCompile(`^F([1-9]|10)$`)
+
+// Tariff object describing prices applicable from a date until the next tariff
+type Tariff struct {
+	From         time.Time           `yaml:"from"`         // First day of the tariff, in local time
+	Option       string              `yaml:"option"`       // base, hphc, tempo, ejp or zen_we
+	Subscription map[float64]float64 `yaml:"subscription"` // Euros per year by subscribed power in kVA
+	Prices       map[string]float64  `yaml:"prices"`       // Euros per kWh by index name of the option or F1..F10
+}
+
+// Catalogue object holding tariffs sorted by date
+type Catalogue struct {
+	Tariffs []Tariff `yaml:"tariffs"`
+}
+
+// Load method to read and validate a tariff file
+func Load(path string) (*Catalogue, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read tariff file : %s", err)
+	}
+
+	catalogue := &Catalogue{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(catalogue); err != nil {
+		return nil, fmt.Errorf("Unable to parse tariff file %s : %s", path, err)
+	}
+	if err := catalogue.normalize(); err != nil {
+		return nil, fmt.Errorf("Invalid tariff file %s : %s", path, err)
+	}
+	return catalogue, nil
+}
+
+// Validate tariffs, sort them by date and replace index names by supplier indexes
+func (catalogue *Catalogue) normalize() error {
+	if len(catalogue.Tariffs) == 0 {
+		return fmt.Errorf("No tariff defined")
+	}
+
+	for i := range catalogue.Tariffs {
+		tariff := &catalogue.Tariffs[i]
+		if tariff.From.IsZero() {
+			return fmt.Errorf("Tariff %d has no from date", i+1)
+		}
+		year, month, day := tariff.From.Date()
+		tariff.From = time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+
+		indexes, known := optionIndexes[strings.ToLower(tariff.Option)]
+		if !known {
+			return fmt.Errorf("Unknown option %s of tariff from %s", tariff.Option, tariff.From.Format("2006-01-02"))
+		}
+		prices := make(map[string]float64)
+		for name, price := range tariff.Prices {
+			index, known := indexes[strings.ToUpper(name)]
+			if !known && supplierIndexRegex.MatchString(strings.ToUpper(name)) {
+				index = strings.ToUpper(name)
+			} else if !known {
+				return fmt.Errorf("Unknown index %s for option %s", name, tariff.Option)
+			}
+			if price < 0 {
+				return fmt.Errorf("Price of %s must be positive", name)
+			}
+			prices[index] = price
+		}
+		tariff.Prices = prices
+	}
+
+	sort.Slice(catalogue.Tariffs, func(i, j int) bool { return catalogue.Tariffs[i].From.Before(catalogue.Tariffs[j].From) })
+	for i := 1; i < len(catalogue.Tariffs); i++ {
+		if catalogue.Tariffs[i].From.Equal(catalogue.Tariffs[i-1].From) {
+			return fmt.Errorf("Several tariffs from %s", catalogue.Tariffs[i].From.Format("2006-01-02"))
+		}
+	}
+	return nil
+}
+
+// At returns the tariff applicable at a time, nil before the first tariff
+func (catalogue *Catalogue) At(t time.Time) *Tariff {
+	for i := len(catalogue.Tariffs) - 1; i >= 0; i-- {
+		if !t.Before(catalogue.Tariffs[i].From) {
+			return &catalogue.Tariffs[i]
+		}
+	}
+	return nil
+}
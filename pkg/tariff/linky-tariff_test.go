@@ -0,0 +1,126 @@
+package tariff
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/syberalexis/linky-exporter/internal/tictest"
+	"github.com/syberalexis/linky-exporter/pkg/core"
+)
+
+const catalogueContent = `
+tariffs:
+  - from: 2023-08-01
+    option: hphc
+    subscription:
+      6: 160
+    prices:
+      HC: 0.2
+      HP: 0.3
+  - from: 2023-02-01
+    option: hphc
+    subscription:
+      6: 150
+    prices:
+      F1: 0.1
+      F2: 0.2
+`
+
+// Write a tariff file in a temporary directory
+func writeCatalogue(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "tariffs.yml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Historical HP/HC frame of a 6 kVA meter at a time
+func testFrame(t *testing.T, at time.Time, hchc string, hchp string) core.Frame {
+	return tictest.Frame(t, core.Historical, at, "OPTARIF HC..", "ISOUSC 30", "HCHC "+hchc, "HCHP "+hchp, "IINST 001")
+}
+
+func TestLoadInvalidTableDriven(t *testing.T) {
+	// Given
+	var tests = []struct {
+		name    string
+		content string
+	}{
+		{"empty", "tariffs: []\n"},
+		{"unknown option", "tariffs:\n  - from: 2023-02-01\n    option: night\n"},
+		{"unknown index", "tariffs:\n  - from: 2023-02-01\n    option: base\n    prices:\n      HC: 0.1\n"},
+		{"missing date", "tariffs:\n  - option: base\n"},
+		{"same date", "tariffs:\n  - from: 2023-02-01\n    option: base\n  - from: 2023-02-01\n    option: tempo\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			_, err := Load(writeCatalogue(t, tt.content))
+
+			// Then
+			if err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestEngineTariffChange(t *testing.T) {
+	// Given
+	catalogue, err := Load(writeCatalogue(t, catalogueContent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewEngine()
+	engine.SetCatalogue(catalogue)
+	change := time.Date(2023, 8, 1, 0, 0, 0, 0, time.Local)
+
+	// When
+	engine.Add("main", testFrame(t, change.Add(-2*time.Hour), "000010000", "000020000"))
+	engine.Add("main", testFrame(t, change.Add(-time.Hour), "000011000", "000020000"))
+	engine.Add("main", testFrame(t, change.Add(time.Hour), "000012000", "000022000"))
+	costs, ok := engine.Costs("main")
+
+	// Then
+	if !ok {
+		t.Fatal("no costs")
+	}
+	if math.Abs(costs.Energy["F1"]-(1*0.1+1*0.2)) > 1e-9 || math.Abs(costs.Energy["F2"]-2*0.3) > 1e-9 {
+		t.Errorf("unexpected energy costs %v", costs.Energy)
+	}
+	expected := 150*float64(time.Hour)/float64(year) + 160*float64(2*time.Hour)/float64(year)
+	if math.Abs(costs.Subscription-expected) > 1e-9 {
+		t.Errorf("got subscription %v, want %v", costs.Subscription, expected)
+	}
+}
+
+func TestEngineEjpIndexes(t *testing.T) {
+	// Given
+	catalogue, err := Load(writeCatalogue(t, "tariffs:\n  - from: 2023-02-01\n    option: ejp\n    prices:\n      HN: 0.1\n      PM: 0.5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewEngine()
+	engine.SetCatalogue(catalogue)
+	at := time.Date(2023, 8, 1, 0, 0, 0, 0, time.Local)
+	ejpFrame := func(at time.Time, ejphn string, ejphpm string) core.Frame {
+		return tictest.Frame(t, core.Historical, at, "OPTARIF EJP.", "ISOUSC 30", "EJPHN "+ejphn, "EJPHPM "+ejphpm, "IINST 001")
+	}
+
+	// When
+	engine.Add("main", ejpFrame(at, "000010000", "000020000"))
+	engine.Add("main", ejpFrame(at.Add(time.Hour), "000011000", "000022000"))
+	costs, ok := engine.Costs("main")
+
+	// Then
+	if !ok {
+		t.Fatal("no costs")
+	}
+	if math.Abs(costs.Energy["F1"]-1*0.1) > 1e-9 || math.Abs(costs.Energy["F2"]-2*0.5) > 1e-9 {
+		t.Errorf("unexpected energy costs %v", costs.Energy)
+	}
+}